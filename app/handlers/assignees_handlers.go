@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"api-server/app/middlewares"
+	"api-server/domain/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func abortWithAssigneeError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrTaskDoesNotExist,
+		services.ErrMemberDoesNotExist,
+		services.ErrAssigneeDoesNotExist:
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case services.ErrForbidden:
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func HandleListAssignees(assigneesService *services.AssigneesService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		assignees, err := assigneesService.ListByTaskId(c, workspace.Id, taskId, userData.Id)
+		if err != nil {
+			abortWithAssigneeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, assignees)
+	}
+}
+
+func HandleAssign(assigneesService *services.AssigneesService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+		assigneeId, err := GetIdFromPath(c, "userId")
+		if err != nil {
+			return
+		}
+
+		err = assigneesService.Assign(c, workspace.Id, taskId, assigneeId, userData.Id)
+		if err != nil {
+			abortWithAssigneeError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func HandleUnassign(assigneesService *services.AssigneesService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+		assigneeId, err := GetIdFromPath(c, "userId")
+		if err != nil {
+			return
+		}
+
+		err = assigneesService.Unassign(c, workspace.Id, taskId, assigneeId, userData.Id)
+		if err != nil {
+			abortWithAssigneeError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"api-server/app/middlewares"
+	"api-server/domain/services"
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is the room left in request body limit for multipart
+// boundaries and headers around the uploaded file.
+const multipartOverhead = 1 << 20
+
+func HandleListAttachments(attachmentsService *services.AttachmentsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		attachments, err := attachmentsService.ListByTaskId(c, workspace.Id, taskId, userData.Id)
+		if err == services.ErrTaskDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, attachments)
+	}
+}
+
+func HandleUploadAttachment(attachmentsService *services.AttachmentsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, attachmentsService.MaxSize+multipartOverhead)
+		fileHeader, err := c.FormFile("file")
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAttachmentTooLarge.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+
+		attachment, err := attachmentsService.Upload(c, workspace.Id, taskId, fileHeader.Filename, file, fileHeader.Size, userData.Id)
+		if err == services.ErrAttachmentTooLarge {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrAttachmentTypeNotAllowed {
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrTaskDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, attachment)
+	}
+}
+
+func HandleDownloadAttachment(attachmentsService *services.AttachmentsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+		attachmentId, err := GetIdFromPath(c, "attachmentId")
+		if err != nil {
+			return
+		}
+
+		attachment, content, err := attachmentsService.Open(c, workspace.Id, taskId, attachmentId, userData.Id)
+		if err == services.ErrTaskDoesNotExist || err == services.ErrAttachmentDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer content.Close()
+
+		c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, map[string]string{
+			"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+			"X-Content-Type-Options": "nosniff",
+		})
+	}
+}
+
+func HandleDeleteAttachment(attachmentsService *services.AttachmentsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+		attachmentId, err := GetIdFromPath(c, "attachmentId")
+		if err != nil {
+			return
+		}
+
+		err = attachmentsService.DeleteById(c, workspace.Id, taskId, attachmentId, userData.Id)
+		if err == services.ErrTaskDoesNotExist || err == services.ErrAttachmentDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -63,3 +63,26 @@ func HandleWhoAmI(userService *services.UsersService, jwtAuth *middlewares.JwtHe
 		c.JSON(http.StatusOK, userData)
 	}
 }
+
+func HandleUpdateTimeZone(userService *services.UsersService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+
+		var userTimeZone models.UserTimeZone
+		if err := c.ShouldBindBodyWithJSON(&userTimeZone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userData, err = userService.UpdateTimeZone(c.Request.Context(), userData, userTimeZone.TimeZone)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, userData)
+	}
+}
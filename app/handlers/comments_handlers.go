@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"api-server/app/middlewares"
+	"api-server/domain/models"
+	"api-server/domain/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HandleListComments(commentsService *services.CommentsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		var pageParams models.PageParams
+		if err := c.ShouldBindQuery(&pageParams); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		comments, err := commentsService.ListByTaskId(c, workspace.Id, taskId, pageParams, userData.Id)
+		if err == services.ErrTaskDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, comments)
+	}
+}
+
+func HandleCreateComment(commentsService *services.CommentsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		var commentBody models.CommentBody
+		if err := c.ShouldBindBodyWithJSON(&commentBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		comment, err := commentsService.Create(c, workspace.Id, taskId, commentBody.Body, userData.Id)
+		if err == services.ErrTaskDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, comment)
+	}
+}
+
+func HandleEditComment(commentsService *services.CommentsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+		commentId, err := GetIdFromPath(c, "commentId")
+		if err != nil {
+			return
+		}
+
+		var commentBody models.CommentBody
+		if err := c.ShouldBindBodyWithJSON(&commentBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		comment, err := commentsService.Edit(c, workspace.Id, taskId, commentId, commentBody.Body, userData.Id)
+		if err == services.ErrTaskDoesNotExist || err == services.ErrCommentDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrNotOwner || err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, comment)
+	}
+}
+
+func HandleDeleteComment(commentsService *services.CommentsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+		commentId, err := GetIdFromPath(c, "commentId")
+		if err != nil {
+			return
+		}
+
+		err = commentsService.DeleteById(c, workspace.Id, taskId, commentId, userData.Id)
+		if err == services.ErrTaskDoesNotExist || err == services.ErrCommentDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrNotOwner || err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"api-server/app/middlewares"
+	"api-server/domain/models"
+	"api-server/domain/services"
+	"api-server/utils"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func abortWithCustomFieldError(c *gin.Context, err error) {
+	var validationErr *utils.ValidationError
+	if errors.As(err, &validationErr) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch err {
+	case services.ErrProjectDoesNotExist,
+		services.ErrTaskDoesNotExist,
+		services.ErrFieldDoesNotExist,
+		services.ErrFieldValueDoesNotExist:
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case services.ErrForbidden:
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case services.ErrFieldAlreadyExists:
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func HandleListCustomFields(fieldsService *services.CustomFieldsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		projectId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		fields, err := fieldsService.ListByProjectId(c, workspace.Id, projectId, userData.Id)
+		if err != nil {
+			abortWithCustomFieldError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, fields)
+	}
+}
+
+func HandleCreateCustomField(fieldsService *services.CustomFieldsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		projectId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		var fieldCreate models.CustomFieldCreate
+		if err := c.ShouldBindBodyWithJSON(&fieldCreate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		field, err := fieldsService.Create(c, workspace.Id, projectId, fieldCreate, userData.Id)
+		if err != nil {
+			abortWithCustomFieldError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, field)
+	}
+}
+
+func HandleDeleteCustomField(fieldsService *services.CustomFieldsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		projectId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+		fieldId, err := GetIdFromPath(c, "fieldId")
+		if err != nil {
+			return
+		}
+
+		err = fieldsService.DeleteById(c, workspace.Id, projectId, fieldId, userData.Id)
+		if err != nil {
+			abortWithCustomFieldError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func HandleListFieldValues(fieldsService *services.CustomFieldsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		values, err := fieldsService.ListValues(c, workspace.Id, taskId, userData.Id)
+		if err != nil {
+			abortWithCustomFieldError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, values)
+	}
+}
+
+func HandleSetFieldValue(fieldsService *services.CustomFieldsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+		fieldId, err := GetIdFromPath(c, "fieldId")
+		if err != nil {
+			return
+		}
+
+		var valueUpdate models.FieldValueUpdate
+		if err := c.ShouldBindBodyWithJSON(&valueUpdate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		value, err := fieldsService.SetValue(c, workspace.Id, taskId, fieldId, valueUpdate.Value, userData.Id)
+		if err != nil {
+			abortWithCustomFieldError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, value)
+	}
+}
+
+func HandleDeleteFieldValue(fieldsService *services.CustomFieldsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+		fieldId, err := GetIdFromPath(c, "fieldId")
+		if err != nil {
+			return
+		}
+
+		err = fieldsService.DeleteValue(c, workspace.Id, taskId, fieldId, userData.Id)
+		if err != nil {
+			abortWithCustomFieldError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -3,10 +3,14 @@ package handlers
 import (
 	"api-server/app/middlewares"
 	"api-server/domain/models"
+	"api-server/domain/query"
 	"api-server/domain/services"
-	"encoding/json"
+	"api-server/utils"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -14,19 +18,233 @@ import (
 )
 
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(*http.Request) bool { return true },
+	CheckOrigin:  func(*http.Request) bool { return true },
+	Subprotocols: []string{models.DashboardProtocolV1},
 }
 
-func writeError(wsConn *websocket.Conn, err error) error {
-	return wsConn.WriteMessage(websocket.BinaryMessage, []byte(fmt.Sprint("{\"error\": \"", err.Error(), "\"}")))
+// isFilterError tells whether listing failed because of the filter.
+func isFilterError(err error) bool {
+	var (
+		validationErr *utils.ValidationError
+		syntaxErr     *query.SyntaxError
+	)
+	return err == services.ErrInvalidStatus || errors.As(err, &validationErr) || errors.As(err, &syntaxErr)
 }
 
-func HandleDashboard(tasksService *services.TasksService, jwtCookieAuth *middlewares.JwtCookieAuthenticator) func(*gin.Context) {
+// Dashboard connections are kept alive with pings, a client not answering
+// within dashboardPongWait is disconnected, as is one letting
+// dashboardQueueSize messages pile up.
+const (
+	dashboardWriteWait      = 10 * time.Second
+	dashboardPongWait       = 60 * time.Second
+	dashboardPingPeriod     = dashboardPongWait * 9 / 10
+	dashboardMaxMessageSize = 64 << 10
+	dashboardQueueSize      = 64
+	dashboardMessageTimeout = 10 * time.Second
+)
+
+var errSlowConsumer = errors.New("client doesn't keep up with messages")
+
+type wsMessage struct {
+	messageType int
+	data        []byte
+}
+
+// dashboardSession is a dashboard connection subscribed to tasks matching
+// the last filter the client sent. Messages to the client are queued in out
+// for writeLoop.
+type dashboardSession struct {
+	taskEventMatcher
+	wsConn   *websocket.Conn
+	protocol dashboardProtocol
+	out      chan wsMessage
+}
+
+// send queues the message, failing with errSlowConsumer when the queue is
+// full.
+func (s *dashboardSession) send(message wsMessage) error {
+	select {
+	case s.out <- message:
+		return nil
+	default:
+		return errSlowConsumer
+	}
+}
+
+func (s *dashboardSession) write(messageType string, id string, data any) error {
+	message, err := s.protocol.encode(messageType, id, data)
+	if err != nil {
+		return err
+	}
+	return s.send(message)
+}
+
+func (s *dashboardSession) writeError(id string, code string, err error) error {
+	message, encodeErr := s.protocol.encodeError(id, code, err)
+	if encodeErr != nil {
+		return encodeErr
+	}
+	return s.send(message)
+}
+
+// fail reports the internal error to the client, the connection is closed
+// after it.
+func (s *dashboardSession) fail(id string, err error) error {
+	s.writeError(id, models.DashboardErrInternal, err)
+	return err
+}
+
+// writeLoop writes queued messages and pings the client until the queue is
+// closed. A failed write closes the connection, which stops the reader and
+// so the session.
+func (s *dashboardSession) writeLoop() {
+	ticker := time.NewTicker(dashboardPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case message, ok := <-s.out:
+			if !ok {
+				return
+			}
+			s.wsConn.SetWriteDeadline(time.Now().Add(dashboardWriteWait))
+			if err := s.wsConn.WriteMessage(message.messageType, message.data); err != nil {
+				s.wsConn.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := s.wsConn.WriteControl(websocket.PingMessage, nil, time.Now().Add(dashboardWriteWait)); err != nil {
+				s.wsConn.Close()
+				return
+			}
+		}
+	}
+}
+
+// handleMessage answers the message of the client. Errors are returned only
+// when the connection should be closed.
+func (s *dashboardSession) handleMessage(ctx context.Context, message wsMessage) error {
+	request, err := s.protocol.decode(message)
+	if err != nil {
+		return s.writeError(request.Id, models.DashboardErrBadMessage, err)
+	}
+
+	switch request.Type {
+	case models.DashboardSubscribe:
+		return s.subscribe(ctx, request)
+	case models.DashboardCreateTask:
+		return s.createTask(ctx, request)
+	case models.DashboardUpdateStatus:
+		return s.updateStatus(ctx, request)
+	case models.DashboardEditTask:
+		return s.editTask(ctx, request)
+	case models.DashboardDeleteTask:
+		return s.deleteTask(ctx, request)
+	default:
+		err := fmt.Errorf("unsupported message type %q", request.Type)
+		return s.writeError(request.Id, models.DashboardErrUnsupported, err)
+	}
+}
+
+// subscribe answers the filter of the request with the first page of
+// matching tasks and subscribes to changes of tasks matching the filter.
+func (s *dashboardSession) subscribe(ctx context.Context, request dashboardRequest) error {
+	data := request.Data
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	var tasksFilter models.TasksFilter
+	if err := binding.JSON.BindBody(data, &tasksFilter); err != nil {
+		return s.writeError(request.Id, models.DashboardErrInvalidFilter, err)
+	}
+
+	tasks, err := s.tasksService.ListByUserId(ctx, s.workspaceId, s.userData.Id, s.userData.Location(), tasksFilter)
+	if isFilterError(err) {
+		return s.writeError(request.Id, models.DashboardErrInvalidFilter, err)
+	}
+	if err != nil {
+		return s.fail(request.Id, err)
+	}
+
+	s.subscribeTo(tasksFilter, tasks.Items)
+	return s.write(models.DashboardTasks, request.Id, tasks)
+}
+
+// handleEvent pushes the task event if the task matches the filter or used
+// to match it. Errors are returned only when the connection should be
+// closed.
+func (s *dashboardSession) handleEvent(ctx context.Context, event models.TaskEvent) error {
+	message, err := s.match(ctx, event)
+	if isFilterError(err) {
+		return s.writeError("", models.DashboardErrInvalidFilter, err)
+	}
+	if err != nil {
+		return s.fail("", err)
+	}
+	if message == nil {
+		return nil
+	}
+	return s.write(models.DashboardEvent, "", *message)
+}
+
+// run handles messages and task events until the session ends, returning
+// the close message to send to the client, if any. Each message and event
+// is given dashboardMessageTimeout to be handled.
+func (s *dashboardSession) run(ctx context.Context, messages <-chan wsMessage, events <-chan models.TaskEvent) []byte {
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			messageCtx, cancel := context.WithTimeout(ctx, dashboardMessageTimeout)
+			err = s.handleMessage(messageCtx, message)
+			cancel()
+
+		case event, ok := <-events:
+			if !ok {
+				return websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "missed task events")
+			}
+			eventCtx, cancel := context.WithTimeout(ctx, dashboardMessageTimeout)
+			err = s.handleEvent(eventCtx, event)
+			cancel()
+		}
+
+		if err == errSlowConsumer {
+			return websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error())
+		}
+		if err != nil {
+			return websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "")
+		}
+	}
+}
+
+// HandleDashboard answers task filters sent by the client with the first page
+// of matching tasks, then pushes changes of tasks matching the last filter
+// made through any server instance until the connection closes. Clients
+// negotiating models.DashboardProtocolV1 wrap messages in envelopes and may
+// change tasks through the connection, others send bare filters. Changes
+// reach every subscribed connection, including other ones of the same user,
+// as task events. The connection is closed with CloseGoingAway once the
+// request context is cancelled, as ConnectionTracker does on shutdown.
+func HandleDashboard(
+	tasksService *services.TasksService,
+	taskEvents *services.TaskEventsHub,
+	jwtCookieAuth *middlewares.JwtCookieAuthenticator,
+	workspaces *middlewares.WorkspaceResolver,
+) func(*gin.Context) {
 	return func(c *gin.Context) {
 		userData, err := GetUserFromCtx(c, jwtCookieAuth.AuthCtxKey)
 		if err != nil {
 			return
 		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
 
 		wsConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
@@ -36,43 +254,57 @@ func HandleDashboard(tasksService *services.TasksService, jwtCookieAuth *middlew
 
 		defer wsConn.Close()
 
-		for {
-			mt, reqMessage, err := wsConn.ReadMessage()
-			if err != nil {
-				break
-			}
+		wsConn.SetReadLimit(dashboardMaxMessageSize)
+		wsConn.SetReadDeadline(time.Now().Add(dashboardPongWait))
+		wsConn.SetPongHandler(func(string) error {
+			return wsConn.SetReadDeadline(time.Now().Add(dashboardPongWait))
+		})
 
-			if mt != websocket.BinaryMessage {
-				if err = writeError(wsConn, fmt.Errorf("only binary messages are allowed")); err != nil {
-					break
-				}
-				continue
-			}
+		sub := taskEvents.Subscribe(workspace.Id)
+		defer taskEvents.Unsubscribe(sub)
 
-			var tasksFilter models.TasksFilter
-			if err = binding.JSON.BindBody(reqMessage, &tasksFilter); err != nil {
-				if err = writeError(wsConn, err); err != nil {
-					break
+		// messages are read aside, so are written answers and pushes
+		messages := make(chan wsMessage)
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			defer close(messages)
+			for {
+				mt, data, err := wsConn.ReadMessage()
+				if err != nil {
+					return
+				}
+				select {
+				case messages <- wsMessage{messageType: mt, data: data}:
+				case <-done:
+					return
 				}
-				continue
 			}
+		}()
 
-			tasks, err := tasksService.ListByUserId(c, userData.Id, tasksFilter)
-			if err != nil {
-				writeError(wsConn, err)
-				break
-			}
+		session := &dashboardSession{
+			taskEventMatcher: taskEventMatcher{
+				tasksService: tasksService,
+				userData:     userData,
+				workspaceId:  workspace.Id,
+			},
+			wsConn:   wsConn,
+			protocol: newDashboardProtocol(wsConn.Subprotocol()),
+			out:      make(chan wsMessage, dashboardQueueSize),
+		}
+		written := make(chan struct{})
+		go func() {
+			defer close(written)
+			session.writeLoop()
+		}()
 
-			respMessage, err := json.Marshal(tasks)
-			if err != nil {
-				writeError(wsConn, err)
-				break
-			}
+		closeMessage := session.run(c.Request.Context(), messages, sub.Events)
 
-			err = wsConn.WriteMessage(websocket.BinaryMessage, respMessage)
-			if err != nil {
-				break
-			}
+		// queued messages go out before the close message
+		close(session.out)
+		<-written
+		if closeMessage != nil {
+			wsConn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(dashboardWriteWait))
 		}
 	}
 }
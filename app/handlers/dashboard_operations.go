@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/services"
+	"context"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// taskErrorCode is the nack code of the error of a task operation, following
+// statuses the REST handlers answer with. Empty code is for internal errors.
+func taskErrorCode(err error) string {
+	switch {
+	case err == services.ErrTaskDoesNotExist || err == services.ErrProjectDoesNotExist:
+		return models.DashboardErrNotFound
+	case err == services.ErrForbidden:
+		return models.DashboardErrForbidden
+	case err == services.ErrVersionMismatch:
+		return models.DashboardErrVersionMismatch
+	case err == services.ErrTransitionNotAllowed:
+		return models.DashboardErrConflict
+	case err == services.ErrInvalidStatus:
+		return models.DashboardErrInvalid
+	default:
+		return ""
+	}
+}
+
+// ack answers the task operation of the request with its result, or a nack
+// if it failed. Internal errors close the connection as with subscriptions.
+func (s *dashboardSession) ack(request dashboardRequest, result models.DashboardTaskResult, err error) error {
+	if err != nil {
+		code := taskErrorCode(err)
+		if code == "" {
+			return s.fail(request.Id, err)
+		}
+		return s.nack(request, code, err)
+	}
+	return s.write(models.DashboardAck, request.Id, result)
+}
+
+func (s *dashboardSession) nack(request dashboardRequest, code string, err error) error {
+	data := models.DashboardErrorData{Code: code, Message: err.Error()}
+	return s.write(models.DashboardNack, request.Id, data)
+}
+
+// bindOperation binds the data of the request with the validators of the
+// matching REST body, nacking the request when it's invalid.
+func (s *dashboardSession) bindOperation(request dashboardRequest, obj any) (bool, error) {
+	if err := binding.JSON.BindBody(request.Data, obj); err != nil {
+		return false, s.nack(request, models.DashboardErrInvalid, err)
+	}
+	return true, nil
+}
+
+// createTask does what POST /tasks/ does.
+func (s *dashboardSession) createTask(ctx context.Context, request dashboardRequest) error {
+	var taskCreate models.TaskCreate
+	if ok, err := s.bindOperation(request, &taskCreate); !ok {
+		return err
+	}
+
+	task, err := s.tasksService.Create(ctx, s.workspaceId, taskCreate, s.userData.Id)
+	return s.ack(request, models.DashboardTaskResult{TaskId: task.Id, Task: &task}, err)
+}
+
+// updateStatus does what PATCH /tasks/:id does.
+func (s *dashboardSession) updateStatus(ctx context.Context, request dashboardRequest) error {
+	var update models.DashboardStatusUpdate
+	if ok, err := s.bindOperation(request, &update); !ok {
+		return err
+	}
+
+	task, undoToken, err := s.tasksService.UpdateStatus(ctx, s.workspaceId, update.Id, update.Status, update.Version, s.userData.Id)
+	return s.ack(request, models.DashboardTaskResult{TaskId: update.Id, Task: &task, UndoToken: undoToken}, err)
+}
+
+// editTask does what PUT /tasks/:id does.
+func (s *dashboardSession) editTask(ctx context.Context, request dashboardRequest) error {
+	var edit models.DashboardTaskEdit
+	if ok, err := s.bindOperation(request, &edit); !ok {
+		return err
+	}
+
+	task, err := s.tasksService.Edit(ctx, s.workspaceId, edit.Id, edit.TaskEdit, edit.Version, s.userData.Id)
+	return s.ack(request, models.DashboardTaskResult{TaskId: edit.Id, Task: &task}, err)
+}
+
+// deleteTask does what DELETE /tasks/:id does.
+func (s *dashboardSession) deleteTask(ctx context.Context, request dashboardRequest) error {
+	var del models.DashboardTaskDelete
+	if ok, err := s.bindOperation(request, &del); !ok {
+		return err
+	}
+
+	undoToken, err := s.tasksService.DeleteById(ctx, s.workspaceId, del.Id, del.Version, s.userData.Id)
+	return s.ack(request, models.DashboardTaskResult{TaskId: del.Id, UndoToken: undoToken}, err)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"api-server/domain/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+)
+
+var errNotJSON = errors.New("message is not a JSON object")
+
+// dashboardRequest is a decoded message of the client.
+type dashboardRequest struct {
+	Type string
+	Id   string
+	Data json.RawMessage
+}
+
+// dashboardProtocol translates dashboard messages from and to the frames of
+// a protocol version.
+type dashboardProtocol interface {
+	// decode returns the request of the message, along with whatever of it
+	// could be decoded if it fails.
+	decode(message wsMessage) (dashboardRequest, error)
+	encode(messageType string, id string, data any) (wsMessage, error)
+	encodeError(id string, code string, err error) (wsMessage, error)
+}
+
+func newDashboardProtocol(subprotocol string) dashboardProtocol {
+	if subprotocol == models.DashboardProtocolV1 {
+		return dashboardProtocolV1{}
+	}
+	return legacyDashboardProtocol{}
+}
+
+// legacyDashboardProtocol takes every message for a bare filter and answers
+// with bare pages and events in binary frames.
+type legacyDashboardProtocol struct{}
+
+func (legacyDashboardProtocol) decode(message wsMessage) (dashboardRequest, error) {
+	return dashboardRequest{Type: models.DashboardSubscribe, Data: message.data}, nil
+}
+
+func (legacyDashboardProtocol) encode(_ string, _ string, data any) (wsMessage, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return wsMessage{}, err
+	}
+	return wsMessage{messageType: websocket.BinaryMessage, data: encoded}, nil
+}
+
+func (p legacyDashboardProtocol) encodeError(_ string, _ string, err error) (wsMessage, error) {
+	return p.encode(models.DashboardError, "", map[string]string{"error": err.Error()})
+}
+
+// dashboardProtocolV1 speaks in DashboardMessage envelopes in text frames,
+// taking them in either text or binary frames.
+type dashboardProtocolV1 struct{}
+
+func (dashboardProtocolV1) decode(message wsMessage) (dashboardRequest, error) {
+	var envelope models.DashboardMessage
+	if err := json.Unmarshal(message.data, &envelope); err != nil {
+		return dashboardRequest{}, errNotJSON
+	}
+	request := dashboardRequest{Type: envelope.Type, Id: envelope.Id, Data: envelope.Data}
+	if envelope.Version != models.DashboardVersion {
+		return request, fmt.Errorf("unsupported protocol version %d", envelope.Version)
+	}
+	if envelope.Type == "" {
+		return request, errors.New("message type is missing")
+	}
+	return request, nil
+}
+
+func (dashboardProtocolV1) encode(messageType string, id string, data any) (wsMessage, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return wsMessage{}, err
+	}
+	envelope := models.DashboardMessage{Version: models.DashboardVersion, Type: messageType, Id: id, Data: encoded}
+	encoded, err = json.Marshal(envelope)
+	if err != nil {
+		return wsMessage{}, err
+	}
+	return wsMessage{messageType: websocket.TextMessage, data: encoded}, nil
+}
+
+func (p dashboardProtocolV1) encodeError(id string, code string, err error) (wsMessage, error) {
+	return p.encode(models.DashboardError, id, models.DashboardErrorData{Code: code, Message: err.Error()})
+}
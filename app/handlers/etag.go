@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"api-server/domain/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrPreconditionFailed = errors.New("If-Match doesn't match the current version")
+
+// taskETag is the entity tag of the task version.
+func taskETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+func SetTaskETag(c *gin.Context, task models.TaskData) {
+	c.Header("ETag", taskETag(task.Version))
+}
+
+// GetIfMatchVersion returns the task version the If-Match header requires,
+// nil if there is no header or it matches any version. Only a single strong
+// entity tag can match a version, requests with anything else fail.
+func GetIfMatchVersion(c *gin.Context) (*int, error) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return nil, nil
+	}
+
+	version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(ifMatch, `"`), `"`))
+	if err != nil || taskETag(version) != ifMatch {
+		c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": ErrPreconditionFailed.Error()})
+		return nil, ErrPreconditionFailed
+	}
+	return &version, nil
+}
+
+// NoneMatches tells whether the If-None-Match header of the request doesn't
+// match the task, weak entity tags match as well.
+func NoneMatches(c *gin.Context, task models.TaskData) bool {
+	ifNoneMatch := strings.TrimSpace(c.GetHeader("If-None-Match"))
+	if ifNoneMatch == "" {
+		return true
+	}
+	if ifNoneMatch == "*" {
+		return false
+	}
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == taskETag(task.Version) {
+			return false
+		}
+	}
+	return true
+}
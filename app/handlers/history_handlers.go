@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"api-server/app/middlewares"
+	"api-server/domain/models"
+	"api-server/domain/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HandleTaskHistory(historyService *services.HistoryService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		var pageParams models.PageParams
+		if err := c.ShouldBindQuery(&pageParams); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		history, err := historyService.ListByTaskId(c, workspace.Id, taskId, pageParams, userData.Id)
+		if err == services.ErrTaskDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, history)
+	}
+}
+
+func HandleActivity(historyService *services.HistoryService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+
+		var params models.ActivityParams
+		if err := c.ShouldBindQuery(&params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		activity, err := historyService.ListActivity(c, workspace.Id, params, userData.Id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, activity)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"api-server/app/middlewares"
+	"api-server/domain/models"
+	"api-server/domain/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShareTargetFunc builds share target from the resource id in URL path.
+type ShareTargetFunc func(id int) models.ShareTarget
+
+func abortWithSharingError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrTaskDoesNotExist,
+		services.ErrProjectDoesNotExist,
+		services.ErrShareDoesNotExist,
+		services.ErrInvitationDoesNotExist:
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case services.ErrForbidden:
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case services.ErrCannotInviteSelf, services.ErrNotWorkspaceMember:
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func HandleListShares(sharingService *services.SharingService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, target ShareTargetFunc) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		id, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		shares, err := sharingService.ListShares(c, workspace.Id, target(id), userData.Id)
+		if err != nil {
+			abortWithSharingError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, shares)
+	}
+}
+
+func HandleCreateShare(sharingService *services.SharingService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, target ShareTargetFunc) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		id, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		var shareCreate models.ShareCreate
+		if err := c.ShouldBindBodyWithJSON(&shareCreate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		invitation, err := sharingService.Invite(c, workspace.Id, target(id), shareCreate, userData)
+		if err != nil {
+			abortWithSharingError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, invitation)
+	}
+}
+
+func HandleRevokeShare(sharingService *services.SharingService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, target ShareTargetFunc) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		id, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+		sharedUserId, err := GetIdFromPath(c, "userId")
+		if err != nil {
+			return
+		}
+
+		err = sharingService.Revoke(c, workspace.Id, target(id), sharedUserId, userData.Id)
+		if err != nil {
+			abortWithSharingError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func HandleListInvitations(sharingService *services.SharingService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+
+		invitations, err := sharingService.ListInvitations(c, userData)
+		if err != nil {
+			abortWithSharingError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, invitations)
+	}
+}
+
+func HandleAcceptInvitation(sharingService *services.SharingService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		invitationId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		err = sharingService.AcceptInvitation(c, invitationId, userData)
+		if err != nil {
+			abortWithSharingError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func HandleDeclineInvitation(sharingService *services.SharingService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		invitationId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		err = sharingService.DeclineInvitation(c, invitationId, userData)
+		if err != nil {
+			abortWithSharingError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"api-server/app/middlewares"
+	"api-server/domain/models"
+	"api-server/domain/services"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	lastEventIdHeader = "Last-Event-ID"
+	// taskEventsKeepAlive is how often idle streams get a comment, so that
+	// proxies don't take them for dead.
+	taskEventsKeepAlive = 30 * time.Second
+)
+
+// taskEventMatcher picks task events of interest to a subscriber of tasks
+// matching a filter. Matched holds ids of tasks the subscriber was told
+// match the filter, to tell it when they stop matching.
+type taskEventMatcher struct {
+	tasksService *services.TasksService
+	userData     models.UserData
+	workspaceId  int
+	filter       *models.TasksFilter
+	matched      map[int]bool
+}
+
+// subscribeTo replaces the filter, tasks are the ones the subscriber was
+// told match it.
+func (m *taskEventMatcher) subscribeTo(filter models.TasksFilter, tasks []models.TaskListItem) {
+	m.filter = &filter
+	m.matched = make(map[int]bool, len(tasks))
+	for _, task := range tasks {
+		m.matched[task.Id] = true
+	}
+}
+
+// match returns the message to push for the event, nil if the task neither
+// matches the filter nor used to match it. The subscription ends when the
+// filter turns out to be invalid, e.g. its status was removed from
+// workflows, which is told apart by isFilterError.
+func (m *taskEventMatcher) match(ctx context.Context, event models.TaskEvent) (*models.TaskEventMessage, error) {
+	if m.filter == nil {
+		return nil, nil
+	}
+
+	var task *models.TaskListItem
+	if event.Type != models.TaskEventDeleted {
+		tasks, err := m.tasksService.FilterTasks(ctx, m.workspaceId, m.userData.Id, m.userData.Location(), *m.filter, []int{event.TaskId})
+		if isFilterError(err) {
+			m.filter = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(tasks) > 0 {
+			task = &tasks[0]
+		}
+	}
+
+	message := models.TaskEventMessage{Event: event.Type, TaskId: event.TaskId, Task: task}
+	if task == nil {
+		if !m.matched[event.TaskId] {
+			return nil, nil
+		}
+		delete(m.matched, event.TaskId)
+		message.Event = models.TaskEventDeleted
+	} else {
+		m.matched[event.TaskId] = true
+	}
+	return &message, nil
+}
+
+// writeServerSentEvent writes the event to the stream and flushes it. Events
+// without an id don't move the position the client resumes from.
+func writeServerSentEvent(c *gin.Context, id int64, event string, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id > 0 {
+		if _, err := fmt.Fprintf(c.Writer, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, encoded); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// HandleTaskEvents streams the task events the dashboard pushes as
+// Server-Sent Events, for clients which can't open WebSockets. Tasks are
+// filtered by the query as with HandleListTasks. The stream starts with
+// a tasks event carrying the first page of matching tasks, followed by
+// created, updated and deleted events carrying a TaskEventMessage. Event ids
+// are numbered per workspace, clients reconnecting to the workspace with the
+// Last-Event-ID header get the events they missed replayed from the log
+// instead, or the first page again when they are too far behind. An invalid
+// filter ends the stream with an error event, the stream is also ended once
+// the request context is cancelled.
+func HandleTaskEvents(
+	tasksService *services.TasksService,
+	taskEvents *services.TaskEventsHub,
+	jwtAuth *middlewares.JwtHeaderOrCookieAuthenticator,
+	workspaces *middlewares.WorkspaceResolver,
+) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+
+		var tasksFilter models.TasksFilter
+		if err := c.ShouldBindQuery(&tasksFilter); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var lastEventId *int64
+		if headerValue := c.GetHeader(lastEventIdHeader); headerValue != "" {
+			id, err := strconv.ParseInt(headerValue, 10, 64)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID header"})
+				return
+			}
+			lastEventId = &id
+		}
+
+		ctx := c.Request.Context()
+
+		// subscribed first, so events logged while catching up aren't missed
+		sub := taskEvents.Subscribe(workspace.Id)
+		defer taskEvents.Unsubscribe(sub)
+
+		lastId, err := taskEvents.LastId(ctx, workspace.Id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		tasks, err := tasksService.ListByUserId(ctx, workspace.Id, userData.Id, userData.Location(), tasksFilter)
+		if isFilterError(err) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		var (
+			missed     []models.TaskEvent
+			visibleIds []int
+		)
+		resumed := false
+		if lastEventId != nil {
+			missed, err = taskEvents.Since(ctx, workspace.Id, *lastEventId)
+			if err != nil && err != services.ErrTaskEventsMissed {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			resumed = err == nil
+		}
+		if resumed && len(missed) > 0 {
+			missedIds := make([]int, len(missed))
+			for i, event := range missed {
+				missedIds[i] = event.TaskId
+			}
+			visibleIds, err = tasksService.VisibleTaskIds(ctx, workspace.Id, userData.Id, missedIds)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		matcher := taskEventMatcher{tasksService: tasksService, userData: userData, workspaceId: workspace.Id}
+		matcher.subscribeTo(tasksFilter, tasks.Items)
+
+		// push writes the event if it's of interest, reporting whether the
+		// stream goes on
+		push := func(event models.TaskEvent) bool {
+			message, err := matcher.match(ctx, event)
+			if isFilterError(err) {
+				errorData := models.DashboardErrorData{Code: models.DashboardErrInvalidFilter, Message: err.Error()}
+				writeServerSentEvent(c, 0, models.DashboardError, errorData)
+				return false
+			}
+			// the client reconnects and catches up from the log
+			if err != nil {
+				return false
+			}
+			if message == nil {
+				return true
+			}
+			return writeServerSentEvent(c, event.Id, message.Event, *message) == nil
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		// keeps nginx from buffering the stream
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		// replayed events may come through the subscription too
+		replayed := make(map[int64]bool, len(missed))
+		if resumed {
+			// the client may have known tasks changed while it was away,
+			// those which no longer match are pushed as deleted. Tasks the
+			// user can't see were never pushed to it in the first place.
+			for _, taskId := range visibleIds {
+				matcher.matched[taskId] = true
+			}
+			for _, event := range missed {
+				replayed[event.Id] = true
+				if !push(event) {
+					return
+				}
+			}
+			c.Writer.Flush()
+		} else if err := writeServerSentEvent(c, lastId, models.DashboardTasks, tasks); err != nil {
+			return
+		}
+
+		keepAlive := time.NewTicker(taskEventsKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+					return
+				}
+				c.Writer.Flush()
+
+			case event, ok := <-sub.Events:
+				// fell behind, the client reconnects and catches up from the log
+				if !ok {
+					return
+				}
+				if replayed[event.Id] {
+					continue
+				}
+				if !push(event) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -3,19 +3,26 @@ package handlers
 import (
 	"api-server/app/middlewares"
 	"api-server/domain/models"
+	"api-server/domain/query"
 	"api-server/domain/services"
+	"api-server/utils"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
-func HandleListTasks(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+func HandleListTasks(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
 	return func(c *gin.Context) {
 		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
 		if err != nil {
 			return
 		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
 
 		var tasksFilter models.TasksFilter
 		if err := c.ShouldBindQuery(&tasksFilter); err != nil {
@@ -23,21 +30,34 @@ func HandleListTasks(tasksService *services.TasksService, jwtAuth *middlewares.J
 			return
 		}
 
-		tasks, err := tasksService.ListByUserId(c, userData.Id, tasksFilter)
+		tasks, err := tasksService.ListByUserId(c, workspace.Id, userData.Id, userData.Location(), tasksFilter)
+		var (
+			validationErr *utils.ValidationError
+			syntaxErr     *query.SyntaxError
+		)
+		if err == services.ErrInvalidStatus || errors.As(err, &validationErr) || errors.As(err, &syntaxErr) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		SetPageLinks(c, tasks.Next, tasks.Prev)
 		c.JSON(http.StatusOK, tasks)
 	}
 }
 
-func HandleCreateTask(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+func HandleCreateTask(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
 	return func(c *gin.Context) {
 		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
 		if err != nil {
 			return
 		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
 
 		var taskCreate models.TaskCreate
 		if err := c.ShouldBindBodyWithJSON(&taskCreate); err != nil {
@@ -45,21 +65,72 @@ func HandleCreateTask(tasksService *services.TasksService, jwtAuth *middlewares.
 			return
 		}
 
-		task, err := tasksService.Create(c, taskCreate, userData.Id)
+		task, err := tasksService.Create(c, workspace.Id, taskCreate, userData.Id)
+		if err == services.ErrProjectDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		SetTaskETag(c, task)
 		c.JSON(http.StatusOK, task)
 	}
 }
 
-func HandleDeleteTask(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+func HandleGetTask(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
 	return func(c *gin.Context) {
 		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
 		if err != nil {
 			return
 		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		task, err := tasksService.GetById(c, workspace.Id, taskId, userData.Id)
+		if err == services.ErrTaskDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		SetTaskETag(c, task)
+		if !NoneMatches(c, task) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.JSON(http.StatusOK, task)
+	}
+}
+
+func HandleDeleteTask(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
 		taskIdParam := c.Param("id")
 		taskId, err := strconv.Atoi(taskIdParam)
 		if err != nil {
@@ -67,12 +138,21 @@ func HandleDeleteTask(tasksService *services.TasksService, jwtAuth *middlewares.
 			return
 		}
 
-		err = tasksService.DeleteById(c, taskId, userData.Id)
+		version, err := GetIfMatchVersion(c)
+		if err != nil {
+			return
+		}
+
+		undoToken, err := tasksService.DeleteById(c, workspace.Id, taskId, version, userData.Id)
 		if err == services.ErrTaskDoesNotExist {
 			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		if err == services.ErrNotOwner {
+		if err == services.ErrVersionMismatch {
+			c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
@@ -80,16 +160,21 @@ func HandleDeleteTask(tasksService *services.TasksService, jwtAuth *middlewares.
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		SetUndoToken(c, undoToken)
 		c.Status(http.StatusNoContent)
 	}
 }
 
-func HandleUpdateTask(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+func HandleUpdateTask(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
 	return func(c *gin.Context) {
 		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
 		if err != nil {
 			return
 		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
 
 		taskIdParam := c.Param("id")
 		taskId, err := strconv.Atoi(taskIdParam)
@@ -104,12 +189,29 @@ func HandleUpdateTask(tasksService *services.TasksService, jwtAuth *middlewares.
 			return
 		}
 
-		updatedTask, err := tasksService.UpdateStatus(c, taskId, taskStatus.Status, userData.Id)
+		version, err := GetIfMatchVersion(c)
+		if err != nil {
+			return
+		}
+
+		updatedTask, undoToken, err := tasksService.UpdateStatus(c, workspace.Id, taskId, taskStatus.Status, version, userData.Id)
 		if err == services.ErrTaskDoesNotExist {
 			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		if err == services.ErrNotOwner {
+		if err == services.ErrVersionMismatch {
+			c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrInvalidStatus {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrTransitionNotAllowed {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
@@ -117,6 +219,99 @@ func HandleUpdateTask(tasksService *services.TasksService, jwtAuth *middlewares.
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		SetTaskETag(c, updatedTask)
+		SetUndoToken(c, undoToken)
 		c.JSON(http.StatusOK, updatedTask)
 	}
 }
+
+func HandleEditTask(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		var taskEdit models.TaskEdit
+		if err := c.ShouldBindBodyWithJSON(&taskEdit); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		version, err := GetIfMatchVersion(c)
+		if err != nil {
+			return
+		}
+
+		task, err := tasksService.Edit(c, workspace.Id, taskId, taskEdit, version, userData.Id)
+		if err == services.ErrTaskDoesNotExist || err == services.ErrProjectDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrVersionMismatch {
+			c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		SetTaskETag(c, task)
+		c.JSON(http.StatusOK, task)
+	}
+}
+
+func HandleBulkTasks(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+
+		var bulkRequest models.BulkTasksRequest
+		if err := c.ShouldBindBodyWithJSON(&bulkRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := tasksService.Bulk(c, workspace.Id, userData.Id, userData.Location(), bulkRequest)
+		var (
+			validationErr *utils.ValidationError
+			syntaxErr     *query.SyntaxError
+		)
+		if err == services.ErrInvalidStatus || err == services.ErrTooManyTasks || errors.As(err, &validationErr) || errors.As(err, &syntaxErr) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrProjectDoesNotExist {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrForbidden {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		SetUndoToken(c, result.UndoToken)
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"api-server/app/middlewares"
+	"api-server/domain/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func abortWithTrashError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrTaskDoesNotExist:
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case services.ErrForbidden:
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func HandleListTrash(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+
+		tasks, err := tasksService.ListTrash(c, workspace.Id, userData.Id)
+		if err != nil {
+			abortWithTrashError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, tasks)
+	}
+}
+
+func HandleRestoreTask(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		task, err := tasksService.RestoreById(c, workspace.Id, taskId, userData.Id)
+		if err != nil {
+			abortWithTrashError(c, err)
+			return
+		}
+		SetTaskETag(c, task)
+		c.JSON(http.StatusOK, task)
+	}
+}
+
+func HandlePurgeTask(tasksService *services.TasksService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		taskId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		if err := tasksService.PurgeById(c, workspace.Id, taskId, userData.Id); err != nil {
+			abortWithTrashError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
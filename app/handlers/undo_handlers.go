@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"api-server/app/middlewares"
+	"api-server/domain/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetUndoToken hands out the token undoing the operation of the request, if
+// there is anything to undo.
+func SetUndoToken(c *gin.Context, token string) {
+	if token != "" {
+		c.Header("Undo-Token", token)
+	}
+}
+
+func abortWithUndoError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrUndoDoesNotExist:
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case services.ErrUndoExpired:
+		c.AbortWithStatusJSON(http.StatusGone, gin.H{"error": err.Error()})
+	case services.ErrUndoConflict:
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case services.ErrForbidden:
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func HandleListUndo(undoService *services.UndoService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+
+		entries, err := undoService.ListByUserId(c, workspace.Id, userData.Id)
+		if err != nil {
+			abortWithUndoError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, entries)
+	}
+}
+
+func HandleUndo(undoService *services.UndoService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+
+		tasks, err := undoService.Undo(c, workspace.Id, c.Param("token"), userData.Id)
+		if err != nil {
+			abortWithUndoError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, tasks)
+	}
+}
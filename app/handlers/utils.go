@@ -3,12 +3,18 @@ package handlers
 import (
 	"api-server/domain/models"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-var ErrGetUserFromCtx = errors.New("failed to get user from context")
+var (
+	ErrGetUserFromCtx      = errors.New("failed to get user from context")
+	ErrGetWorkspaceFromCtx = errors.New("failed to get workspace from context")
+)
 
 func GetUserFromCtx(c *gin.Context, ctxKey string) (models.UserData, error) {
 	userDataI, ok := c.Get(ctxKey)
@@ -23,4 +29,49 @@ func GetUserFromCtx(c *gin.Context, ctxKey string) (models.UserData, error) {
 		return models.UserData{}, ErrGetUserFromCtx
 	}
 	return userData, nil
-}
\ No newline at end of file
+}
+
+func GetWorkspaceFromCtx(c *gin.Context, ctxKey string) (models.UserWorkspace, error) {
+	workspaceI, ok := c.Get(ctxKey)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "workspace is not provided by middleware"})
+		return models.UserWorkspace{}, ErrGetWorkspaceFromCtx
+	}
+
+	workspace, ok := workspaceI.(models.UserWorkspace)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "wrong workspace type provided by middleware"})
+		return models.UserWorkspace{}, ErrGetWorkspaceFromCtx
+	}
+	return workspace, nil
+}
+
+func GetIdFromPath(c *gin.Context, param string) (int, error) {
+	id, err := strconv.Atoi(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID in URL path"})
+		return 0, err
+	}
+	return id, nil
+}
+
+// SetPageLinks sets the Link header pointing to neighbouring pages of the
+// current request, which differ from it by the cursor query parameter only.
+func SetPageLinks(c *gin.Context, next *string, prev *string) {
+	var links []string
+	addLink := func(rel string, cursor *string) {
+		if cursor == nil {
+			return
+		}
+		pageUrl := *c.Request.URL
+		query := pageUrl.Query()
+		query.Set("cursor", *cursor)
+		pageUrl.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, pageUrl.RequestURI(), rel))
+	}
+	addLink("next", next)
+	addLink("prev", prev)
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"api-server/app/middlewares"
+	"api-server/domain/models"
+	"api-server/domain/query"
+	"api-server/domain/services"
+	"api-server/utils"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func abortWithViewError(c *gin.Context, err error) {
+	var (
+		validationErr *utils.ValidationError
+		syntaxErr     *query.SyntaxError
+	)
+	if errors.As(err, &validationErr) || errors.As(err, &syntaxErr) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch err {
+	case services.ErrViewDoesNotExist:
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case services.ErrNotOwner:
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case services.ErrInvalidStatus:
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func HandleListViews(viewsService *services.ViewsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+
+		views, err := viewsService.ListByUserId(c, workspace.Id, userData.Id)
+		if err != nil {
+			abortWithViewError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, views)
+	}
+}
+
+func HandleCreateView(viewsService *services.ViewsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+
+		var viewCreate models.ViewCreate
+		if err := c.ShouldBindBodyWithJSON(&viewCreate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		view, err := viewsService.Create(c, workspace.Id, viewCreate, userData.Id)
+		if err != nil {
+			abortWithViewError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, view)
+	}
+}
+
+func HandleGetView(viewsService *services.ViewsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		viewId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		view, err := viewsService.GetById(c, workspace.Id, viewId, userData.Id)
+		if err != nil {
+			abortWithViewError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, view)
+	}
+}
+
+func HandleUpdateView(viewsService *services.ViewsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		viewId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		var viewUpdate models.ViewCreate
+		if err := c.ShouldBindBodyWithJSON(&viewUpdate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		view, err := viewsService.Update(c, workspace.Id, viewId, viewUpdate, userData.Id)
+		if err != nil {
+			abortWithViewError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, view)
+	}
+}
+
+func HandleDeleteView(viewsService *services.ViewsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		viewId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		if err := viewsService.DeleteById(c, workspace.Id, viewId, userData.Id); err != nil {
+			abortWithViewError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func HandleListViewTasks(viewsService *services.ViewsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		viewId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		var params models.ViewTasksParams
+		if err := c.ShouldBindQuery(&params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		groups, err := viewsService.ListTasks(c, workspace.Id, viewId, userData.Id, userData.Location(), params)
+		if err != nil {
+			abortWithViewError(c, err)
+			return
+		}
+		SetPageLinks(c, groups.Next, groups.Prev)
+		c.JSON(http.StatusOK, groups)
+	}
+}
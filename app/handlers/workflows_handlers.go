@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"api-server/app/middlewares"
+	"api-server/domain/models"
+	"api-server/domain/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func abortWithWorkflowError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrProjectDoesNotExist:
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case services.ErrForbidden:
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case services.ErrDuplicateStatus,
+		services.ErrInvalidTransition:
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case services.ErrStatusInUse:
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func HandleGetWorkflow(workflowsService *services.WorkflowsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		projectId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		workflow, err := workflowsService.GetByProjectId(c, workspace.Id, projectId, userData.Id)
+		if err != nil {
+			abortWithWorkflowError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, workflow)
+	}
+}
+
+func HandleUpdateWorkflow(workflowsService *services.WorkflowsService, jwtAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspace, err := GetWorkspaceFromCtx(c, workspaces.WorkspaceCtxKey)
+		if err != nil {
+			return
+		}
+		projectId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		var workflowUpdate models.WorkflowUpdate
+		if err := c.ShouldBindBodyWithJSON(&workflowUpdate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		workflow, err := workflowsService.Update(c, workspace.Id, projectId, workflowUpdate, userData.Id)
+		if err != nil {
+			abortWithWorkflowError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, workflow)
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"api-server/app/middlewares"
+	"api-server/domain/models"
+	"api-server/domain/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func abortWithWorkspaceError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrWorkspaceDoesNotExist,
+		services.ErrMemberDoesNotExist,
+		services.ErrInvitationDoesNotExist:
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case services.ErrForbidden:
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case services.ErrCannotInviteSelf,
+		services.ErrAlreadyMember,
+		services.ErrCannotRemoveOwner:
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func HandleListWorkspaces(workspacesService *services.WorkspacesService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+
+		workspaces, err := workspacesService.ListByUserId(c, userData.Id)
+		if err != nil {
+			abortWithWorkspaceError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, workspaces)
+	}
+}
+
+func HandleCreateWorkspace(workspacesService *services.WorkspacesService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+
+		var workspaceCreate models.WorkspaceCreate
+		if err := c.ShouldBindBodyWithJSON(&workspaceCreate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		workspace, err := workspacesService.Create(c, workspaceCreate, userData.Id)
+		if err != nil {
+			abortWithWorkspaceError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, workspace)
+	}
+}
+
+func HandleListWorkspaceMembers(workspacesService *services.WorkspacesService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspaceId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		members, err := workspacesService.ListMembers(c, workspaceId, userData.Id)
+		if err != nil {
+			abortWithWorkspaceError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, members)
+	}
+}
+
+func HandleRemoveWorkspaceMember(workspacesService *services.WorkspacesService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspaceId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+		memberId, err := GetIdFromPath(c, "userId")
+		if err != nil {
+			return
+		}
+
+		err = workspacesService.RemoveMember(c, workspaceId, memberId, userData.Id)
+		if err != nil {
+			abortWithWorkspaceError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func HandleInviteToWorkspace(workspacesService *services.WorkspacesService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		workspaceId, err := GetIdFromPath(c, "id")
+		if err != nil {
+			return
+		}
+
+		var invite models.WorkspaceInvite
+		if err := c.ShouldBindBodyWithJSON(&invite); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		invitation, err := workspacesService.Invite(c, workspaceId, invite, userData)
+		if err != nil {
+			abortWithWorkspaceError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, invitation)
+	}
+}
+
+func HandleListWorkspaceInvitations(workspacesService *services.WorkspacesService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+
+		invitations, err := workspacesService.ListInvitations(c, userData)
+		if err != nil {
+			abortWithWorkspaceError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, invitations)
+	}
+}
+
+func HandleAcceptWorkspaceInvitation(workspacesService *services.WorkspacesService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		invitationId, err := GetIdFromPath(c, "invitationId")
+		if err != nil {
+			return
+		}
+
+		err = workspacesService.AcceptInvitation(c, invitationId, userData)
+		if err != nil {
+			abortWithWorkspaceError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func HandleDeclineWorkspaceInvitation(workspacesService *services.WorkspacesService, jwtAuth *middlewares.JwtHeaderAuthenticator) func(*gin.Context) {
+	return func(c *gin.Context) {
+		userData, err := GetUserFromCtx(c, jwtAuth.AuthCtxKey)
+		if err != nil {
+			return
+		}
+		invitationId, err := GetIdFromPath(c, "invitationId")
+		if err != nil {
+			return
+		}
+
+		err = workspacesService.DeclineInvitation(c, invitationId, userData)
+		if err != nil {
+			abortWithWorkspaceError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
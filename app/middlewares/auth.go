@@ -1,25 +1,40 @@
 package middlewares
 
 import (
+	"api-server/domain/models"
 	"api-server/domain/repos"
 	"api-server/domain/services"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+var ErrUnauthorized = errors.New("request carries no valid token")
+
 type JwtHeaderAuthenticator struct {
 	AuthHeader       string
 	AuthHeaderPrefix string
 	AuthCtxKey       string
-	Handler          gin.HandlerFunc
+	// Authenticate returns the user the request is made by, ErrUnauthorized
+	// if it carries no valid token.
+	Authenticate func(c *gin.Context) (models.UserData, error)
+	Handler      gin.HandlerFunc
 }
 
 type JwtCookieAuthenticator struct {
 	AuthCookieKey string
 	AuthCtxKey    string
-	Handler       gin.HandlerFunc
+	// Authenticate returns the user the request is made by, ErrUnauthorized
+	// if it carries no valid token.
+	Authenticate func(c *gin.Context) (models.UserData, error)
+	Handler      gin.HandlerFunc
+}
+
+type JwtHeaderOrCookieAuthenticator struct {
+	AuthCtxKey string
+	Handler    gin.HandlerFunc
 }
 
 func NewJwtHeaderAuthenticator(tp *services.JwtTokenProvider, usersRepo *repos.UsersRepo) *JwtHeaderAuthenticator {
@@ -28,32 +43,37 @@ func NewJwtHeaderAuthenticator(tp *services.JwtTokenProvider, usersRepo *repos.U
 		authHeaderPrefix = "Bearer"
 		authCtxKey       = "User"
 	)
+	authenticate := func(c *gin.Context) (models.UserData, error) {
+		headerValue := c.Request.Header.Get(authHeader)
+		if headerValue == "" {
+			return models.UserData{}, ErrUnauthorized
+		}
+
+		headerParts := strings.Split(headerValue, " ")
+		if len(headerParts) != 2 || headerParts[0] != authHeaderPrefix {
+			return models.UserData{}, ErrUnauthorized
+		}
+
+		tokenString := headerParts[1]
+		email, err := tp.ParseEmail(tokenString)
+		if err != nil {
+			return models.UserData{}, ErrUnauthorized
+		}
+
+		userData, err := usersRepo.GetByEmail(c, email)
+		if err == repos.ErrNotFound {
+			return models.UserData{}, ErrUnauthorized
+		}
+		return userData, err
+	}
 	return &JwtHeaderAuthenticator{
 		AuthHeader:       authHeader,
 		AuthHeaderPrefix: authHeaderPrefix,
 		AuthCtxKey:       authCtxKey,
+		Authenticate:     authenticate,
 		Handler: func(c *gin.Context) {
-			headerValue := c.Request.Header.Get(authHeader)
-			if headerValue == "" {
-				c.AbortWithStatus(http.StatusUnauthorized)
-				return
-			}
-
-			headerParts := strings.Split(headerValue, " ")
-			if len(headerParts) != 2 && headerParts[0] != authHeaderPrefix {
-				c.AbortWithStatus(http.StatusUnauthorized)
-				return
-			}
-
-			tokenString := headerParts[1]
-			email, err := tp.ParseEmail(tokenString)
-			if err != nil {
-				c.AbortWithStatus(http.StatusUnauthorized)
-				return
-			}
-
-			userData, err := usersRepo.GetByEmail(c, email)
-			if err == repos.ErrNotFound {
+			userData, err := authenticate(c)
+			if err == ErrUnauthorized {
 				c.AbortWithStatus(http.StatusUnauthorized)
 				return
 			}
@@ -73,24 +93,58 @@ func NewJwtCookieAuthenticator(tp *services.JwtTokenProvider, usersRepo *repos.U
 		authCookieKey = "auth_token"
 		authCtxKey    = "User"
 	)
+	authenticate := func(c *gin.Context) (models.UserData, error) {
+		tokenString, err := c.Cookie(authCookieKey)
+		if tokenString == "" || err != nil {
+			return models.UserData{}, ErrUnauthorized
+		}
+
+		email, err := tp.ParseEmail(tokenString)
+		if err != nil {
+			return models.UserData{}, ErrUnauthorized
+		}
+
+		userData, err := usersRepo.GetByEmail(c, email)
+		if err == repos.ErrNotFound {
+			return models.UserData{}, ErrUnauthorized
+		}
+		return userData, err
+	}
 	return &JwtCookieAuthenticator{
 		AuthCookieKey: authCookieKey,
 		AuthCtxKey:    authCtxKey,
+		Authenticate:  authenticate,
 		Handler: func(c *gin.Context) {
-			tokenString, err := c.Cookie(authCookieKey)
-			if tokenString == "" || err != nil {
+			userData, err := authenticate(c)
+			if err == ErrUnauthorized {
 				c.AbortWithStatus(http.StatusUnauthorized)
 				return
 			}
-
-			email, err := tp.ParseEmail(tokenString)
 			if err != nil {
-				c.AbortWithStatus(http.StatusUnauthorized)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
 
-			userData, err := usersRepo.GetByEmail(c, email)
-			if err == repos.ErrNotFound {
+			c.Set(authCtxKey, userData)
+			c.Next()
+		},
+	}
+}
+
+// NewJwtHeaderOrCookieAuthenticator accepts requests authenticated by either
+// of the authenticators, the header taking precedence, for endpoints used by
+// both API clients and browsers. The user is stored under the key of the
+// header authenticator.
+func NewJwtHeaderOrCookieAuthenticator(header *JwtHeaderAuthenticator, cookie *JwtCookieAuthenticator) *JwtHeaderOrCookieAuthenticator {
+	authCtxKey := header.AuthCtxKey
+	return &JwtHeaderOrCookieAuthenticator{
+		AuthCtxKey: authCtxKey,
+		Handler: func(c *gin.Context) {
+			userData, err := header.Authenticate(c)
+			if err == ErrUnauthorized {
+				userData, err = cookie.Authenticate(c)
+			}
+			if err == ErrUnauthorized {
 				c.AbortWithStatus(http.StatusUnauthorized)
 				return
 			}
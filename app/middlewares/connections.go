@@ -0,0 +1,109 @@
+package middlewares
+
+import (
+	"api-server/domain/models"
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxConnectionsPerUser is how many tracked connections a user may
+// keep open at once.
+const DefaultMaxConnectionsPerUser = 5
+
+type ConnectionTracker struct {
+	MaxPerUser int
+	Handler    gin.HandlerFunc
+
+	mu       sync.Mutex
+	open     map[int]int
+	wg       sync.WaitGroup
+	closing  context.Context
+	shutdown context.CancelFunc
+}
+
+// NewConnectionTracker keeps track of long-lived connections, such as
+// WebSockets, rejecting the ones over MaxPerUser open connections of the user.
+// The request context of tracked connections is cancelled on Shutdown, the
+// handlers are expected to close their connections then. Must run after one
+// of the authenticators storing the user under authCtxKey.
+func NewConnectionTracker(authCtxKey string) *ConnectionTracker {
+	closing, shutdown := context.WithCancel(context.Background())
+	t := &ConnectionTracker{
+		MaxPerUser: DefaultMaxConnectionsPerUser,
+		open:       make(map[int]int),
+		closing:    closing,
+		shutdown:   shutdown,
+	}
+	t.Handler = func(c *gin.Context) {
+		userDataI, _ := c.Get(authCtxKey)
+		userData, ok := userDataI.(models.UserData)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "wrong user type provided by middleware"})
+			return
+		}
+
+		if closing.Err() != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is shutting down"})
+			return
+		}
+		if !t.acquire(userData.Id) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many open connections"})
+			return
+		}
+		defer t.release(userData.Id)
+
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		stop := context.AfterFunc(closing, cancel)
+		defer stop()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+	return t
+}
+
+func (t *ConnectionTracker) acquire(userId int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// checked under the lock again, so Shutdown doesn't miss connections
+	if t.closing.Err() != nil || t.open[userId] >= t.MaxPerUser {
+		return false
+	}
+	t.open[userId]++
+	t.wg.Add(1)
+	return true
+}
+
+func (t *ConnectionTracker) release(userId int) {
+	t.mu.Lock()
+	t.open[userId]--
+	if t.open[userId] == 0 {
+		delete(t.open, userId)
+	}
+	t.mu.Unlock()
+	t.wg.Done()
+}
+
+// Shutdown cancels contexts of tracked connections, rejects new ones, and
+// waits until the open ones are closed or ctx is done.
+func (t *ConnectionTracker) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	t.shutdown()
+	t.mu.Unlock()
+
+	closed := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
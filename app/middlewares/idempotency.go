@@ -0,0 +1,180 @@
+package middlewares
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/services"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize is 1 MiB, bodies are buffered in full to be hashed
+	maxIdempotentBodySize int64 = 1 << 20
+)
+
+// replayedHeaders are response headers stored along with the body, the rest
+// is recomputed or irrelevant to a replay.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+type IdempotencyGuard struct {
+	KeyHeader      string
+	ReplayedHeader string
+	Handler        gin.HandlerFunc
+}
+
+// responseRecorder passes the response through while keeping a copy of the
+// body.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func requestHash(c *gin.Context, workspaceHeader string, body []byte) string {
+	hash := sha256.New()
+	for _, part := range []string{c.Request.Method, c.Request.URL.RequestURI(), c.Request.Header.Get(workspaceHeader)} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// NewIdempotencyGuard replays responses to mutating requests retried with the
+// same Idempotency-Key header. Requests without the header are passed through
+// untouched, as are requests which fail authentication, leaving it to the
+// authenticator of the route to reject them. Multipart uploads carrying the
+// header are rejected rather than buffered. Must be registered on the route
+// groups it guards, so that requests matching no route don't take up keys;
+// responses with server errors are not stored so that retries get another
+// chance.
+func NewIdempotencyGuard(
+	service *services.IdempotencyService,
+	jwtAuth *JwtHeaderAuthenticator,
+	workspaces *WorkspaceResolver,
+) *IdempotencyGuard {
+	const (
+		keyHeader      = "Idempotency-Key"
+		replayedHeader = "Idempotent-Replayed"
+	)
+	return &IdempotencyGuard{
+		KeyHeader:      keyHeader,
+		ReplayedHeader: replayedHeader,
+		Handler: func(c *gin.Context) {
+			key := c.Request.Header.Get(keyHeader)
+			switch c.Request.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				key = ""
+			}
+			if key == "" {
+				c.Next()
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency key is too long"})
+				return
+			}
+			if c.ContentType() == binding.MIMEMultipartPOSTForm {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency key is not supported for multipart requests"})
+				return
+			}
+
+			userData, err := jwtAuth.Authenticate(c)
+			if err == ErrUnauthorized {
+				c.Next()
+				return
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize)
+			body, err := io.ReadAll(c.Request.Body)
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large for an idempotent request"})
+				return
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+			stored, err := service.Begin(c, userData.Id, key, requestHash(c, workspaces.WorkspaceHeader, body))
+			if err == services.ErrIdempotencyKeyReused || err == services.ErrIdempotencyKeyInProgress {
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if stored != nil {
+				for name, value := range stored.Headers {
+					c.Header(name, value)
+				}
+				c.Header(replayedHeader, "true")
+				c.Status(stored.Status)
+				c.Writer.Write(stored.Body)
+				c.Abort()
+				return
+			}
+
+			// the key must be completed or released even if the client is gone
+			// or the handler panics
+			ctx := context.WithoutCancel(c.Request.Context())
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := service.Release(ctx, userData.Id, key); err != nil {
+					log.WithFields(log.Fields{"err": err}).Error("Failed to release idempotency key")
+				}
+			}()
+
+			recorder := &responseRecorder{ResponseWriter: c.Writer}
+			c.Writer = recorder
+			c.Next()
+			c.Writer = recorder.ResponseWriter
+
+			status := recorder.Status()
+			if status >= http.StatusInternalServerError {
+				return
+			}
+			response := models.IdempotentResponse{Status: status, Headers: make(map[string]string), Body: recorder.body.Bytes()}
+			for _, name := range replayedHeaders {
+				if value := recorder.Header().Get(name); value != "" {
+					response.Headers[name] = value
+				}
+			}
+			if err := service.Complete(ctx, userData.Id, key, response); err != nil {
+				log.WithFields(log.Fields{"err": err}).Error("Failed to store idempotent response")
+				return
+			}
+			completed = true
+		},
+	}
+}
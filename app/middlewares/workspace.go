@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WorkspaceResolver struct {
+	WorkspaceHeader string
+	WorkspaceCtxKey string
+	Handler         gin.HandlerFunc
+}
+
+// NewWorkspaceResolver selects the workspace the request operates in. The
+// workspace is taken from the X-Workspace-Id header, falling back to the
+// personal workspace of the user. Must run after one of the authenticators
+// storing the user under authCtxKey.
+func NewWorkspaceResolver(workspacesRepo *repos.WorkspacesRepo, authCtxKey string) *WorkspaceResolver {
+	const (
+		workspaceHeader = "X-Workspace-Id"
+		workspaceCtxKey = "Workspace"
+	)
+	return &WorkspaceResolver{
+		WorkspaceHeader: workspaceHeader,
+		WorkspaceCtxKey: workspaceCtxKey,
+		Handler: func(c *gin.Context) {
+			userDataI, _ := c.Get(authCtxKey)
+			userData, ok := userDataI.(models.UserData)
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "wrong user type provided by middleware"})
+				return
+			}
+
+			var (
+				workspace models.UserWorkspace
+				err       error
+			)
+			headerValue := c.Request.Header.Get(workspaceHeader)
+			if headerValue == "" {
+				workspace, err = workspacesRepo.GetPersonal(c, userData.Id)
+			} else {
+				workspaceId, convErr := strconv.Atoi(headerValue)
+				if convErr != nil {
+					c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace ID in header"})
+					return
+				}
+				workspace, err = workspacesRepo.GetMembership(c, workspaceId, userData.Id)
+			}
+
+			if err == repos.ErrNotFound {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "user is not a member of this workspace"})
+				return
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			c.Set(workspaceCtxKey, workspace)
+			c.Next()
+		},
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"api-server/app/handlers"
 	"api-server/app/middlewares"
+	"api-server/domain/models"
 	"api-server/domain/services"
 	"net/http"
 	"time"
@@ -42,22 +43,140 @@ func SetupDefaultRouter() *gin.Engine {
 	return r
 }
 
-func RegisterAuthRoutes(r *gin.Engine, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, usersService *services.UsersService) {
+func RegisterAuthRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, usersService *services.UsersService) {
 	g := r.Group("/auth")
 	g.POST("/register", handlers.HandleRegistration(usersService))
 	g.POST("/login", handlers.HandleLogin(usersService))
 	g.GET("/whoami", jwtHeaderAuth.Handler, handlers.HandleWhoAmI(usersService, jwtHeaderAuth))
+	g.PUT("/timezone", jwtHeaderAuth.Handler, handlers.HandleUpdateTimeZone(usersService, jwtHeaderAuth))
 }
 
-func RegisterTasksRoutes(r *gin.Engine, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, tasksService *services.TasksService) {
+func RegisterTasksRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, tasksService *services.TasksService) {
 	g := r.Group("/tasks")
-	g.GET("/", jwtHeaderAuth.Handler, handlers.HandleListTasks(tasksService, jwtHeaderAuth))
-	g.POST("/", jwtHeaderAuth.Handler, handlers.HandleCreateTask(tasksService, jwtHeaderAuth))
+	g.GET("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListTasks(tasksService, jwtHeaderAuth, workspaces))
+	g.POST("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleCreateTask(tasksService, jwtHeaderAuth, workspaces))
+	g.POST("/bulk", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleBulkTasks(tasksService, jwtHeaderAuth, workspaces))
 
-	g.DELETE("/:id", jwtHeaderAuth.Handler, handlers.HandleDeleteTask(tasksService, jwtHeaderAuth))
-	g.PATCH("/:id", jwtHeaderAuth.Handler, handlers.HandleUpdateTask(tasksService, jwtHeaderAuth))
+	g.GET("/:id", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleGetTask(tasksService, jwtHeaderAuth, workspaces))
+	g.DELETE("/:id", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleDeleteTask(tasksService, jwtHeaderAuth, workspaces))
+	g.PATCH("/:id", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleUpdateTask(tasksService, jwtHeaderAuth, workspaces))
+	g.PUT("/:id", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleEditTask(tasksService, jwtHeaderAuth, workspaces))
+
+	g.GET("/trash/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListTrash(tasksService, jwtHeaderAuth, workspaces))
+	g.POST("/trash/:id/restore", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleRestoreTask(tasksService, jwtHeaderAuth, workspaces))
+	g.DELETE("/trash/:id", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandlePurgeTask(tasksService, jwtHeaderAuth, workspaces))
+}
+
+func RegisterUndoRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, undoService *services.UndoService) {
+	g := r.Group("/undo")
+	g.GET("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListUndo(undoService, jwtHeaderAuth, workspaces))
+	g.POST("/:token", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleUndo(undoService, jwtHeaderAuth, workspaces))
+}
+
+func RegisterDashboardRoute(r *gin.Engine, jwtCookieAuth *middlewares.JwtCookieAuthenticator, workspaces *middlewares.WorkspaceResolver, connections *middlewares.ConnectionTracker, tasksService *services.TasksService, taskEvents *services.TaskEventsHub) {
+	r.GET("/dashboard/", jwtCookieAuth.Handler, connections.Handler, workspaces.Handler, handlers.HandleDashboard(tasksService, taskEvents, jwtCookieAuth, workspaces))
+}
+
+func RegisterTaskEventsRoute(r *gin.Engine, jwtAuth *middlewares.JwtHeaderOrCookieAuthenticator, workspaces *middlewares.WorkspaceResolver, connections *middlewares.ConnectionTracker, tasksService *services.TasksService, taskEvents *services.TaskEventsHub) {
+	r.GET("/tasks/events", jwtAuth.Handler, connections.Handler, workspaces.Handler, handlers.HandleTaskEvents(tasksService, taskEvents, jwtAuth, workspaces))
+}
+
+func RegisterRemindersRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, remindersService *services.RemindersService) {
+	g := r.Group("/tasks/:id/reminders")
+	g.GET("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListReminders(remindersService, jwtHeaderAuth, workspaces))
+	g.POST("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleCreateReminder(remindersService, jwtHeaderAuth, workspaces))
+	g.DELETE("/:reminderId", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleDeleteReminder(remindersService, jwtHeaderAuth, workspaces))
+}
+
+func RegisterCommentsRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, commentsService *services.CommentsService) {
+	g := r.Group("/tasks/:id/comments")
+	g.GET("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListComments(commentsService, jwtHeaderAuth, workspaces))
+	g.POST("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleCreateComment(commentsService, jwtHeaderAuth, workspaces))
+	g.PATCH("/:commentId", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleEditComment(commentsService, jwtHeaderAuth, workspaces))
+	g.DELETE("/:commentId", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleDeleteComment(commentsService, jwtHeaderAuth, workspaces))
+}
+
+func RegisterAttachmentsRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, attachmentsService *services.AttachmentsService) {
+	g := r.Group("/tasks/:id/attachments")
+	g.GET("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListAttachments(attachmentsService, jwtHeaderAuth, workspaces))
+	g.POST("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleUploadAttachment(attachmentsService, jwtHeaderAuth, workspaces))
+	g.GET("/:attachmentId", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleDownloadAttachment(attachmentsService, jwtHeaderAuth, workspaces))
+	g.DELETE("/:attachmentId", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleDeleteAttachment(attachmentsService, jwtHeaderAuth, workspaces))
+}
+
+func RegisterHistoryRoutes(r *gin.Engine, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, historyService *services.HistoryService) {
+	r.GET("/tasks/:id/history", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleTaskHistory(historyService, jwtHeaderAuth, workspaces))
+	r.GET("/activity", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleActivity(historyService, jwtHeaderAuth, workspaces))
 }
 
-func RegisterDashboardRoute(r *gin.Engine, jwtCookieAuth *middlewares.JwtCookieAuthenticator, tasksService *services.TasksService) {
-	r.GET("/dashboard/", jwtCookieAuth.Handler, handlers.HandleDashboard(tasksService, jwtCookieAuth))
+func RegisterWorkflowsRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, workflowsService *services.WorkflowsService) {
+	g := r.Group("/projects/:id/workflow")
+	g.GET("", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleGetWorkflow(workflowsService, jwtHeaderAuth, workspaces))
+	g.PUT("", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleUpdateWorkflow(workflowsService, jwtHeaderAuth, workspaces))
+}
+
+func RegisterCustomFieldsRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, fieldsService *services.CustomFieldsService) {
+	g := r.Group("/projects/:id/fields")
+	g.GET("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListCustomFields(fieldsService, jwtHeaderAuth, workspaces))
+	g.POST("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleCreateCustomField(fieldsService, jwtHeaderAuth, workspaces))
+	g.DELETE("/:fieldId", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleDeleteCustomField(fieldsService, jwtHeaderAuth, workspaces))
+
+	g = r.Group("/tasks/:id/fields")
+	g.GET("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListFieldValues(fieldsService, jwtHeaderAuth, workspaces))
+	g.PUT("/:fieldId", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleSetFieldValue(fieldsService, jwtHeaderAuth, workspaces))
+	g.DELETE("/:fieldId", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleDeleteFieldValue(fieldsService, jwtHeaderAuth, workspaces))
+}
+
+func RegisterAssigneesRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, assigneesService *services.AssigneesService) {
+	g := r.Group("/tasks/:id/assignees")
+	g.GET("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListAssignees(assigneesService, jwtHeaderAuth, workspaces))
+	g.PUT("/:userId", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleAssign(assigneesService, jwtHeaderAuth, workspaces))
+	g.DELETE("/:userId", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleUnassign(assigneesService, jwtHeaderAuth, workspaces))
+}
+
+func RegisterProjectsRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, projectsService *services.ProjectsService) {
+	g := r.Group("/projects")
+	g.GET("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListProjects(projectsService, jwtHeaderAuth, workspaces))
+	g.POST("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleCreateProject(projectsService, jwtHeaderAuth, workspaces))
+	g.GET("/:id", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleGetProject(projectsService, jwtHeaderAuth, workspaces))
+}
+
+func RegisterViewsRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, viewsService *services.ViewsService) {
+	g := r.Group("/views")
+	g.GET("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListViews(viewsService, jwtHeaderAuth, workspaces))
+	g.POST("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleCreateView(viewsService, jwtHeaderAuth, workspaces))
+	g.GET("/:id", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleGetView(viewsService, jwtHeaderAuth, workspaces))
+	g.PUT("/:id", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleUpdateView(viewsService, jwtHeaderAuth, workspaces))
+	g.DELETE("/:id", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleDeleteView(viewsService, jwtHeaderAuth, workspaces))
+	g.GET("/:id/tasks", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListViewTasks(viewsService, jwtHeaderAuth, workspaces))
+}
+
+func RegisterSharingRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspaces *middlewares.WorkspaceResolver, sharingService *services.SharingService) {
+	for path, target := range map[string]handlers.ShareTargetFunc{
+		"/tasks/:id/shares":    models.TaskShareTarget,
+		"/projects/:id/shares": models.ProjectShareTarget,
+	} {
+		g := r.Group(path)
+		g.GET("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleListShares(sharingService, jwtHeaderAuth, workspaces, target))
+		g.POST("/", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleCreateShare(sharingService, jwtHeaderAuth, workspaces, target))
+		g.DELETE("/:userId", jwtHeaderAuth.Handler, workspaces.Handler, handlers.HandleRevokeShare(sharingService, jwtHeaderAuth, workspaces, target))
+	}
+
+	g := r.Group("/invitations")
+	g.GET("/", jwtHeaderAuth.Handler, handlers.HandleListInvitations(sharingService, jwtHeaderAuth))
+	g.POST("/:id/accept", jwtHeaderAuth.Handler, handlers.HandleAcceptInvitation(sharingService, jwtHeaderAuth))
+	g.DELETE("/:id", jwtHeaderAuth.Handler, handlers.HandleDeclineInvitation(sharingService, jwtHeaderAuth))
+}
+
+func RegisterWorkspacesRoutes(r gin.IRouter, jwtHeaderAuth *middlewares.JwtHeaderAuthenticator, workspacesService *services.WorkspacesService) {
+	g := r.Group("/workspaces")
+	g.GET("/", jwtHeaderAuth.Handler, handlers.HandleListWorkspaces(workspacesService, jwtHeaderAuth))
+	g.POST("/", jwtHeaderAuth.Handler, handlers.HandleCreateWorkspace(workspacesService, jwtHeaderAuth))
+	g.GET("/:id/members/", jwtHeaderAuth.Handler, handlers.HandleListWorkspaceMembers(workspacesService, jwtHeaderAuth))
+	g.DELETE("/:id/members/:userId", jwtHeaderAuth.Handler, handlers.HandleRemoveWorkspaceMember(workspacesService, jwtHeaderAuth))
+	g.POST("/:id/invitations/", jwtHeaderAuth.Handler, handlers.HandleInviteToWorkspace(workspacesService, jwtHeaderAuth))
+
+	g.GET("/invitations/", jwtHeaderAuth.Handler, handlers.HandleListWorkspaceInvitations(workspacesService, jwtHeaderAuth))
+	g.POST("/invitations/:invitationId/accept", jwtHeaderAuth.Handler, handlers.HandleAcceptWorkspaceInvitation(workspacesService, jwtHeaderAuth))
+	g.DELETE("/invitations/:invitationId", jwtHeaderAuth.Handler, handlers.HandleDeclineWorkspaceInvitation(workspacesService, jwtHeaderAuth))
 }
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type AssigneeData struct {
+	UserId     int       `json:"user_id"`
+	Email      string    `json:"email"`
+	AssignedBy int       `json:"assigned_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
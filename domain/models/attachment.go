@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type AttachmentData struct {
+	Id          int       `json:"id"`
+	TaskId      int       `json:"task_id"`
+	UserId      int       `json:"uploaded_by"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	BlobKey     string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,59 @@
+package models
+
+const MaxBulkTasks = 500
+
+// Actions of bulk task operations.
+const (
+	BulkSetStatus  = "set_status"
+	BulkDelete     = "delete"
+	BulkMove       = "move"
+	BulkEditLabels = "edit_labels"
+)
+
+// Outcomes of bulk operations for single tasks.
+const (
+	BulkResultOk        = "ok"
+	BulkResultNotFound  = "not_found"
+	BulkResultForbidden = "forbidden"
+	BulkResultInvalid   = "invalid"
+	BulkResultConflict  = "conflict"
+)
+
+// BulkTasksRequest applies a single action to tasks with given ids or to
+// all tasks matching the filter. Move takes tasks out of projects when
+// ProjectId is null.
+type BulkTasksRequest struct {
+	Ids          []int        `json:"ids" binding:"required_without=Filter,excluded_with=Filter,max=500,unique"`
+	Filter       *TasksFilter `json:"filter" binding:"required_without=Ids"`
+	Action       string       `json:"action" binding:"required,oneof=set_status delete move edit_labels"`
+	Status       *string      `json:"status" binding:"required_if=Action set_status"`
+	ProjectId    *int         `json:"project_id"`
+	AddLabels    []string     `json:"add_labels" binding:"omitempty,unique,dive,required"`
+	RemoveLabels []string     `json:"remove_labels" binding:"omitempty,unique,dive,required"`
+	DryRun       bool         `json:"dry_run"`
+}
+
+type BulkTaskResult struct {
+	Id     int    `json:"id"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BulkTasksResult struct {
+	DryRun    bool             `json:"dry_run"`
+	Results   []BulkTaskResult `json:"results"`
+	UndoToken string           `json:"undo_token,omitempty"`
+}
+
+// TaskChange is the change of a single task made by a bulk operation,
+// nil fields are left as they are. BaseVersion is the version of the task
+// the change was made against.
+type TaskChange struct {
+	Id          int
+	BaseVersion int
+	Delete      bool
+	Status      *string
+	Labels      []string
+	Move        bool
+	ProjectId   *int
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type CommentBody struct {
+	Body string `json:"body" binding:"required,notBlank"`
+}
+
+type CommentData struct {
+	Id          int        `json:"id"`
+	TaskId      int        `json:"task_id"`
+	AuthorId    int        `json:"author_id"`
+	AuthorEmail string     `json:"author_email"`
+	Body        string     `json:"body"`
+	CreatedAt   time.Time  `json:"created_at"`
+	EditedAt    *time.Time `json:"edited_at"`
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"api-server/utils"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	FieldTypeText        = "text"
+	FieldTypeNumber      = "number"
+	FieldTypeDate        = "date"
+	FieldTypeSelect      = "select"
+	FieldTypeMultiSelect = "multi_select"
+	FieldTypeCheckbox    = "checkbox"
+)
+
+type CustomFieldCreate struct {
+	Name    string   `json:"name" binding:"required"`
+	Type    string   `json:"type" binding:"required,customFieldType"`
+	Options []string `json:"options" binding:"omitempty,unique,dive,required"`
+}
+
+type CustomFieldData struct {
+	Id        int       `json:"id"`
+	ProjectId int       `json:"project_id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Options   []string  `json:"options"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FieldValueUpdate struct {
+	Value any `json:"value"`
+}
+
+type FieldValueData struct {
+	FieldId int    `json:"field_id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Value   any    `json:"value"`
+}
+
+// FieldCondition matches tasks having the custom field set to Value, or
+// containing it for multi select fields.
+type FieldCondition struct {
+	Field CustomFieldData
+	Value any
+}
+
+// TaskOrder is a resolved sort key, Field is set for custom field keys.
+type TaskOrder struct {
+	Key   string
+	Field *CustomFieldData
+	Desc  bool
+}
+
+// FieldsQuery is the custom fields part of TasksFilter resolved against
+// definitions of the fields, along with the whole sort order of the filter
+// as custom fields may appear anywhere in it.
+type FieldsQuery struct {
+	Conditions []FieldCondition
+	Order      []TaskOrder
+}
+
+func (f CustomFieldData) invalid(tag string) error {
+	return &utils.ValidationError{Namespace: "CustomField." + f.Name, Field: f.Name, Tag: tag}
+}
+
+func (f CustomFieldData) option(value any) (string, error) {
+	option, ok := value.(string)
+	if !ok {
+		return "", f.invalid("string")
+	}
+	if !slices.Contains(f.Options, option) {
+		return "", f.invalid("oneof")
+	}
+	return option, nil
+}
+
+// NormalizeValue checks value decoded from JSON matches the type of the field
+// and returns it in the form it is stored in.
+func (f CustomFieldData) NormalizeValue(value any) (any, error) {
+	if value == nil {
+		return nil, f.invalid("required")
+	}
+
+	switch f.Type {
+	case FieldTypeText:
+		text, ok := value.(string)
+		if !ok {
+			return nil, f.invalid("string")
+		}
+		return text, nil
+	case FieldTypeNumber:
+		number, ok := value.(float64)
+		if !ok {
+			return nil, f.invalid("number")
+		}
+		return number, nil
+	case FieldTypeDate:
+		date, ok := value.(string)
+		if !ok {
+			return nil, f.invalid("dayFormat")
+		}
+		if _, err := time.Parse(utils.DayDateFmt, date); err != nil {
+			return nil, f.invalid("dayFormat")
+		}
+		return date, nil
+	case FieldTypeSelect:
+		return f.option(value)
+	case FieldTypeMultiSelect:
+		values, ok := value.([]any)
+		if !ok {
+			return nil, f.invalid("array")
+		}
+		options := make([]string, 0, len(values))
+		for _, v := range values {
+			option, err := f.option(v)
+			if err != nil {
+				return nil, err
+			}
+			if slices.Contains(options, option) {
+				return nil, f.invalid("unique")
+			}
+			options = append(options, option)
+		}
+		return options, nil
+	case FieldTypeCheckbox:
+		checked, ok := value.(bool)
+		if !ok {
+			return nil, f.invalid("boolean")
+		}
+		return checked, nil
+	}
+	return nil, fmt.Errorf("unknown custom field type %s", f.Type)
+}
+
+// ParseCondition parses a filter value given as a query string. Multi select
+// fields are matched by a single option.
+func (f CustomFieldData) ParseCondition(value string) (FieldCondition, error) {
+	var (
+		parsed any
+		err    error
+	)
+	switch f.Type {
+	case FieldTypeNumber:
+		var number float64
+		number, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return FieldCondition{}, f.invalid("number")
+		}
+		parsed = number
+	case FieldTypeCheckbox:
+		var checked bool
+		checked, err = strconv.ParseBool(value)
+		if err != nil {
+			return FieldCondition{}, f.invalid("boolean")
+		}
+		parsed = checked
+	case FieldTypeMultiSelect:
+		parsed, err = f.NormalizeValue([]any{value})
+	default:
+		parsed, err = f.NormalizeValue(value)
+	}
+	if err != nil {
+		return FieldCondition{}, err
+	}
+	return FieldCondition{Field: f, Value: parsed}, nil
+}
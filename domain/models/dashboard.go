@@ -0,0 +1,92 @@
+package models
+
+import "encoding/json"
+
+// DashboardProtocolV1 is the WebSocket subprotocol of the dashboard speaking
+// in DashboardMessage envelopes. Clients which don't negotiate it get the
+// legacy protocol of bare filters answered with bare pages.
+const (
+	DashboardProtocolV1 = "tasks.v1"
+	DashboardVersion    = 1
+)
+
+// Types of dashboard messages.
+const (
+	// DashboardSubscribe carries a TasksFilter, it's answered with the first
+	// page of matching tasks and subscribes to their changes.
+	DashboardSubscribe = "subscribe"
+	// DashboardTasks carries the page of tasks answering a subscription.
+	DashboardTasks = "tasks"
+	// DashboardEvent carries a TaskEventMessage pushed to the subscriber.
+	DashboardEvent = "event"
+	// DashboardError carries a DashboardErrorData.
+	DashboardError = "error"
+
+	// Task operations, each of them is answered with an ack carrying
+	// a DashboardTaskResult or a nack carrying a DashboardErrorData.
+	DashboardCreateTask   = "create_task"
+	DashboardUpdateStatus = "update_status"
+	DashboardEditTask     = "edit_task"
+	DashboardDeleteTask   = "delete_task"
+	DashboardAck          = "ack"
+	DashboardNack         = "nack"
+)
+
+// Codes of dashboard errors.
+const (
+	DashboardErrBadMessage    = "bad_message"
+	DashboardErrInvalidFilter = "invalid_filter"
+	DashboardErrUnsupported   = "unsupported"
+	DashboardErrInternal      = "internal"
+	DashboardErrInvalid       = "invalid"
+	DashboardErrNotFound      = "not_found"
+	DashboardErrForbidden     = "forbidden"
+	DashboardErrConflict      = "conflict"
+	// DashboardErrVersionMismatch is for operations on tasks changed since
+	// the version they were given.
+	DashboardErrVersionMismatch = "version_mismatch"
+)
+
+// DashboardMessage is the envelope of dashboard messages. Id correlates
+// requests of the client with answers and errors, pushed events go without.
+type DashboardMessage struct {
+	Version int             `json:"v"`
+	Type    string          `json:"type"`
+	Id      string          `json:"id,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+type DashboardErrorData struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// DashboardStatusUpdate moves the task to the status, only if it's still in
+// Version when it's set.
+type DashboardStatusUpdate struct {
+	Id int `json:"id" binding:"required"`
+	TaskStatus
+	Version *int `json:"version"`
+}
+
+// DashboardTaskEdit replaces editable fields of the task, only if it's still
+// in Version when it's set.
+type DashboardTaskEdit struct {
+	Id int `json:"id" binding:"required"`
+	TaskEdit
+	Version *int `json:"version"`
+}
+
+// DashboardTaskDelete moves the task to the trash, only if it's still in
+// Version when it's set.
+type DashboardTaskDelete struct {
+	Id      int  `json:"id" binding:"required"`
+	Version *int `json:"version"`
+}
+
+// DashboardTaskResult acks a task operation, Task is missing for deletes.
+type DashboardTaskResult struct {
+	TaskId    int       `json:"task_id"`
+	Task      *TaskData `json:"task,omitempty"`
+	UndoToken string    `json:"undo_token,omitempty"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"reflect"
+	"time"
+)
+
+// Actions recorded in the task history.
+const (
+	HistoryCreated  = "created"
+	HistoryUpdated  = "updated"
+	HistoryDeleted  = "deleted"
+	HistoryRestored = "restored"
+	HistoryPurged   = "purged"
+)
+
+// HistoryEntry records a single change of a task. Updates record one entry
+// per changed field, other actions record none.
+type HistoryEntry struct {
+	Id        int64     `json:"id"`
+	TaskId    int       `json:"task_id"`
+	UserId    *int      `json:"user_id"`
+	Action    string    `json:"action"`
+	Field     *string   `json:"field"`
+	OldValue  any       `json:"old_value"`
+	NewValue  any       `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ActivityParams struct {
+	PageParams
+	UserId *int `form:"user_id" binding:"omitempty,min=1"`
+}
+
+// TaskFieldChange is a field of a task which changed from Old to New.
+type TaskFieldChange struct {
+	Field string
+	Old   any
+	New   any
+}
+
+// TaskFieldChanges lists fields which differ between the old and new
+// version of the task.
+func TaskFieldChanges(old TaskData, new TaskData) []TaskFieldChange {
+	fields := []TaskFieldChange{
+		{"name", old.Name, new.Name},
+		{"description", old.Description, new.Description},
+		{"due_date", old.DueDate, new.DueDate},
+		{"status", old.Status, new.Status},
+		{"priority", old.Priority, new.Priority},
+		{"labels", old.Labels, new.Labels},
+		{"project_id", old.ProjectId, new.ProjectId},
+	}
+
+	var changes []TaskFieldChange
+	for _, field := range fields {
+		if !reflect.DeepEqual(field.Old, field.New) {
+			changes = append(changes, field)
+		}
+	}
+	return changes
+}
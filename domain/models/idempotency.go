@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// IdempotencyKey is a key sent by a user with a mutating request along with
+// the response to replay to retries of the request, Status is missing while
+// the request is in progress.
+type IdempotencyKey struct {
+	UserId      int
+	Key         string
+	RequestHash string
+	Status      *int
+	Headers     map[string]string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+type IdempotentResponse struct {
+	Status  int
+	Headers map[string]string
+	Body    []byte
+}
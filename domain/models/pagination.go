@@ -0,0 +1,66 @@
+package models
+
+const DefaultPerPage = 20
+
+type PageParams struct {
+	Page    int `form:"page" json:"page" binding:"omitempty,min=1"`
+	PerPage int `form:"per_page" json:"per_page" binding:"omitempty,min=1,max=100"`
+}
+
+func (p PageParams) Limit() int {
+	if p.PerPage == 0 {
+		return DefaultPerPage
+	}
+	return p.PerPage
+}
+
+func (p PageParams) Offset() int {
+	if p.Page == 0 {
+		return 0
+	}
+	return (p.Page - 1) * p.Limit()
+}
+
+type Page[T any] struct {
+	Items   []T `json:"items"`
+	Total   int `json:"total"`
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+}
+
+func NewPage[T any](items []T, total int, params PageParams) Page[T] {
+	page := params.Page
+	if page == 0 {
+		page = 1
+	}
+	return Page[T]{Items: items, Total: total, Page: page, PerPage: params.Limit()}
+}
+
+const DefaultTasksLimit = 50
+
+// CursorPage is a page of keyset pagination, Next and Prev are cursors of
+// the adjacent pages if there are any.
+type CursorPage[T any] struct {
+	Items []T     `json:"items"`
+	Next  *string `json:"next"`
+	Prev  *string `json:"prev"`
+}
+
+// TasksCursor points right after (or right before when Backward is set)
+// the task with given sort key. Sort is the sort filter the key was taken
+// with, a cursor is only valid for the same sorting.
+type TasksCursor struct {
+	Key      []*string `json:"k"`
+	Sort     string    `json:"s"`
+	Backward bool      `json:"b,omitempty"`
+}
+
+// TasksSlice is a page of tasks together with sort keys of its first and
+// last task.
+type TasksSlice struct {
+	Items     []TaskListItem
+	FirstKey  []*string
+	LastKey   []*string
+	HasBefore bool
+	HasAfter  bool
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type ProjectCreate struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type ProjectData struct {
+	Id          int       `json:"id"`
+	Name        string    `json:"name"`
+	CreatedAt   time.Time `json:"created_at"`
+	UserId      int       `json:"owner_id"`
+	WorkspaceId int       `json:"workspace_id"`
+}
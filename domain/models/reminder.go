@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type ReminderCreate struct {
+	RemindAt      *time.Time `json:"remind_at" binding:"required_without=OffsetMinutes,excluded_with=OffsetMinutes"`
+	OffsetMinutes *int       `json:"offset_minutes" binding:"omitempty,min=0"`
+}
+
+type ReminderData struct {
+	Id            int        `json:"id"`
+	TaskId        int        `json:"task_id"`
+	RemindAt      time.Time  `json:"remind_at"`
+	OffsetMinutes *int       `json:"offset_minutes"`
+	SentAt        *time.Time `json:"sent_at"`
+	// FailedAt is set once the scheduler gives up delivering the reminder
+	FailedAt  *time.Time `json:"failed_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// DueReminder is a reminder claimed by the scheduler together with
+// the task data needed to notify its owner.
+type DueReminder struct {
+	Id       int
+	TaskId   int
+	RemindAt time.Time
+	TaskName string
+	DueDate  *time.Time
+	UserId   int
+	// Attempts counts deliveries of the reminder, this one included
+	Attempts int
+}
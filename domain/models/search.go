@@ -0,0 +1,97 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TextSearch is the q filter parsed into to_tsquery expressions. Words match
+// as they are, `word*` by prefix, `"quoted words"` as a phrase and any of
+// them prefixed with "-" excludes tasks containing it.
+type TextSearch struct {
+	// Match is the query tasks must match, empty if there are no such terms
+	Match string
+	// Exclude is the query tasks must not match
+	Exclude string
+	// Fuzzy is the text of matched terms for trigram similarity
+	Fuzzy string
+}
+
+type searchToken struct {
+	Text    string
+	Exclude bool
+	Prefix  bool
+}
+
+func tokenizeSearch(q string) []searchToken {
+	var tokens []searchToken
+	runes := []rune(q)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		var token searchToken
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			token.Exclude = true
+			i++
+		}
+
+		start := i
+		if runes[i] == '"' {
+			start++
+			for i = start; i < len(runes) && runes[i] != '"'; i++ {
+			}
+			token.Text = string(runes[start:i])
+			i++
+		} else {
+			for ; i < len(runes) && !unicode.IsSpace(runes[i]); i++ {
+			}
+			token.Text = string(runes[start:i])
+			if strings.HasSuffix(token.Text, "*") {
+				token.Text = strings.TrimRight(token.Text, "*")
+				token.Prefix = true
+			}
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// searchLexemes splits text into words safe to put into to_tsquery.
+func searchLexemes(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func ParseTextSearch(q string) TextSearch {
+	var match, exclude, fuzzy []string
+	for _, token := range tokenizeSearch(q) {
+		lexemes := searchLexemes(token.Text)
+		if len(lexemes) == 0 {
+			continue
+		}
+		if token.Prefix {
+			lexemes[len(lexemes)-1] += ":*"
+		}
+
+		// words joined by punctuation are matched as a phrase as well
+		expr := strings.Join(lexemes, " <-> ")
+		if len(lexemes) > 1 {
+			expr = "(" + expr + ")"
+		}
+		if token.Exclude {
+			exclude = append(exclude, expr)
+		} else {
+			match = append(match, expr)
+			fuzzy = append(fuzzy, token.Text)
+		}
+	}
+	return TextSearch{
+		Match:   strings.Join(match, " & "),
+		Exclude: strings.Join(exclude, " | "),
+		Fuzzy:   strings.Join(fuzzy, " "),
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"api-server/utils"
+	"slices"
+	"time"
+)
+
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+// RoleAtLeast reports whether role grants at least the required privileges.
+// Empty role means no access at all.
+func RoleAtLeast(role string, required string) bool {
+	if role == "" {
+		return false
+	}
+	return slices.Index(utils.ValidShareRoles, role) >= slices.Index(utils.ValidShareRoles, required)
+}
+
+// ShareTarget identifies the resource access is granted to, exactly one of
+// the fields is set.
+type ShareTarget struct {
+	TaskId    *int
+	ProjectId *int
+}
+
+// TaskAccess is the role a user has on a task through project ownership or
+// shares, see TaskRole of the authorizer.
+type TaskAccess struct {
+	TaskId   int
+	Role     string
+	Assigned bool
+}
+
+type ShareCreate struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,shareRole"`
+}
+
+type ShareData struct {
+	UserId    int       `json:"user_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type InvitationData struct {
+	Id         int        `json:"id"`
+	Email      string     `json:"email"`
+	TaskId     *int       `json:"task_id"`
+	ProjectId  *int       `json:"project_id"`
+	Role       string     `json:"role"`
+	InvitedBy  int        `json:"invited_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+}
+
+type SharesList struct {
+	Members     []ShareData      `json:"members"`
+	Invitations []InvitationData `json:"invitations"`
+}
+
+func TaskShareTarget(taskId int) ShareTarget {
+	return ShareTarget{TaskId: &taskId}
+}
+
+func ProjectShareTarget(projectId int) ShareTarget {
+	return ShareTarget{ProjectId: &projectId}
+}
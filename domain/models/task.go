@@ -2,37 +2,204 @@ package models
 
 import (
 	"api-server/utils"
+	"encoding/json"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type TaskStatus struct {
-	Status string `json:"status" binding:"required,taskStatus"`
+	Status string `json:"status" binding:"required"`
 }
 
 type TaskCreate struct {
-	Name    string     `json:"name" binding:"required"`
-	DueDate *time.Time `json:"due_date"`
+	Name        string     `json:"name" binding:"required"`
+	Description *string    `json:"description"`
+	DueDate     *time.Time `json:"due_date"`
+	Priority    *string    `json:"priority" binding:"omitempty,taskPriority"`
+	ProjectId   *int       `json:"project_id"`
+}
+
+// TaskEdit replaces editable fields of the task. Status changes follow the
+// workflow and go through TaskStatus instead. The task stays in its project
+// unless ProjectId moves it or RemoveProject takes it out of the project.
+type TaskEdit struct {
+	Name          string     `json:"name" binding:"required,notBlank"`
+	Description   *string    `json:"description"`
+	DueDate       *time.Time `json:"due_date"`
+	Priority      *string    `json:"priority" binding:"omitempty,taskPriority"`
+	ProjectId     *int       `json:"project_id"`
+	RemoveProject bool       `json:"remove_project" binding:"excluded_with=ProjectId"`
 }
 
 type TaskData struct {
-	Id        int        `json:"id"`
-	Name      string     `json:"name"`
-	DueDate   *time.Time `json:"due_date"`
-	Status    string     `json:"status"`
-	CreatedAt time.Time  `json:"created_at"`
-	UserId    int        `json:"-"`
+	Id          int        `json:"id"`
+	Name        string     `json:"name"`
+	Description *string    `json:"description"`
+	DueDate     *time.Time `json:"due_date"`
+	Status      string     `json:"status"`
+	Priority    *string    `json:"priority"`
+	Labels      []string   `json:"labels"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UserId      int        `json:"-"`
+	ProjectId   *int       `json:"project_id"`
+	WorkspaceId int        `json:"workspace_id"`
+	Version     int        `json:"version"`
+}
+
+// TaskListItem is a task as returned by task listings.
+type TaskListItem struct {
+	TaskData
+	CommentsCount int            `json:"comments_count"`
+	AssigneeIds   []int          `json:"assignee_ids"`
+	Fields        map[string]any `json:"fields"`
+	// Snippet highlights matches of the q filter
+	Snippet *string `json:"snippet,omitempty"`
 }
 
 type TasksFilter struct {
-	Query      *string `form:"q" json:"q"`
-	DueDateStr *string `form:"due_date" json:"dues_date" binding:"omitempty,dayFormat"`
-	Status     *string `form:"status" json:"status" binding:"omitempty,taskStatus"`
+	Query *string `form:"q" json:"q"`
+	// Expr is a query in the search language of the query package
+	Expr *string `form:"query" json:"query"`
+	// Due dates are days or relative days resolved in the user's time zone
+	DueDateStr *string `form:"due_date" json:"due_date" binding:"omitempty,day"`
+	DueBefore  *string `form:"due_before" json:"due_before" binding:"omitempty,day"`
+	DueAfter   *string `form:"due_after" json:"due_after" binding:"omitempty,day"`
+	HasDueDate *bool   `form:"has_due_date" json:"has_due_date"`
+	Overdue    *bool   `form:"overdue" json:"overdue"`
+	Status     *string `form:"status" json:"status"`
+	ProjectId  *int    `form:"project_id" json:"project_id"`
+	Assignee   *string `form:"assignee" json:"assignee" binding:"omitempty,oneof=me|numeric"`
+	// Fields maps custom field ids to the values tasks must have
+	Fields map[string]string `form:"fields" json:"fields" binding:"omitempty,dive,keys,numeric,endkeys"`
+	Sort   *string           `form:"sort" json:"sort"`
+	Limit  int               `form:"limit" json:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor *string           `form:"cursor" json:"cursor"`
+}
+
+// UnmarshalJSON also takes the due date filter under dues_date, the key
+// legacy dashboard clients still send it under.
+func (tf *TasksFilter) UnmarshalJSON(data []byte) error {
+	// the conversion drops the method, which would recurse otherwise
+	type tasksFilter TasksFilter
+	var filter struct {
+		tasksFilter
+		DuesDate *string `json:"dues_date"`
+	}
+	if err := json.Unmarshal(data, &filter); err != nil {
+		return err
+	}
+	if filter.DueDateStr == nil {
+		filter.DueDateStr = filter.DuesDate
+	}
+	*tf = TasksFilter(filter.tasksFilter)
+	return nil
+}
+
+func (tf TasksFilter) PageLimit() int {
+	if tf.Limit == 0 {
+		return DefaultTasksLimit
+	}
+	return tf.Limit
+}
+
+// SortString identifies the order tasks are listed in, which is relevance
+// to the q filter unless sorting is given.
+func (tf TasksFilter) SortString() string {
+	if tf.Sort != nil && *tf.Sort != "" {
+		return *tf.Sort
+	}
+	if tf.Query != nil && *tf.Query != "" {
+		return "relevance:" + *tf.Query
+	}
+	return ""
 }
 
-func (tf TasksFilter) DueDate() *time.Time {
-	if tf.DueDateStr == nil {
+func (tf TasksFilter) TextSearch() TextSearch {
+	if tf.Query == nil {
+		return TextSearch{}
+	}
+	return ParseTextSearch(*tf.Query)
+}
+
+// Sort keys of task attributes, custom fields are sorted by "fields.<id>".
+const (
+	SortDueDate   = "due_date"
+	SortCreatedAt = "created_at"
+	SortName      = "name"
+	SortStatus    = "status"
+	SortPriority  = "priority"
+)
+
+var TaskSortKeys = []string{SortDueDate, SortCreatedAt, SortName, SortStatus, SortPriority}
+
+// SortKey is a single key of the comma separated sort filter. Keys prefixed
+// with "-" sort in descending order.
+type SortKey struct {
+	Key  string
+	Desc bool
+}
+
+func (tf TasksFilter) SortKeys() []SortKey {
+	if tf.Sort == nil || *tf.Sort == "" {
 		return nil
 	}
-	date, _ := time.Parse(utils.DayDateFmt, *tf.DueDateStr)
-	return &date
+	keys := strings.Split(*tf.Sort, ",")
+	sortKeys := make([]SortKey, len(keys))
+	for i, key := range keys {
+		key = strings.TrimSpace(key)
+		sortKeys[i] = SortKey{Key: strings.TrimPrefix(key, "-"), Desc: strings.HasPrefix(key, "-")}
+	}
+	return sortKeys
+}
+
+// DueRange combines due date filters into a half-open interval [from, to)
+// with days resolved in the location of now. Nil bounds are open.
+func (tf TasksFilter) DueRange(now time.Time) (from *time.Time, to *time.Time) {
+	if tf.DueDateStr != nil {
+		dayFrom, dayTo, _ := utils.DayRange(*tf.DueDateStr, now)
+		from, to = &dayFrom, &dayTo
+	}
+	if tf.DueBefore != nil {
+		before, _, _ := utils.DayRange(*tf.DueBefore, now)
+		if to == nil || before.Before(*to) {
+			to = &before
+		}
+	}
+	if tf.DueAfter != nil {
+		_, after, _ := utils.DayRange(*tf.DueAfter, now)
+		if from == nil || after.After(*from) {
+			from = &after
+		}
+	}
+	return from, to
+}
+
+// AssigneeId resolves the assignee filter, where "me" stands for the
+// requesting user.
+func (tf TasksFilter) AssigneeId(reqUserId int) *int {
+	if tf.Assignee == nil {
+		return nil
+	}
+	if *tf.Assignee == "me" {
+		return &reqUserId
+	}
+	assigneeId, _ := strconv.Atoi(*tf.Assignee)
+	return &assigneeId
+}
+
+// TrashedTask is a deleted task waiting in the trash to be restored or
+// purged for good.
+type TrashedTask struct {
+	TaskData
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy *int      `json:"deleted_by"`
+	PurgeAt   time.Time `json:"purge_at" db:"-"`
+}
+
+// PurgedTasks sums up tasks deleted permanently, their attachments are left
+// to be removed from storage.
+type PurgedTasks struct {
+	Count    int
+	BlobKeys []string
 }
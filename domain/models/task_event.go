@@ -0,0 +1,26 @@
+package models
+
+// Types of task events.
+const (
+	TaskEventCreated = "created"
+	TaskEventUpdated = "updated"
+	TaskEventDeleted = "deleted"
+)
+
+// TaskEvent announces a change of a task made through any server instance.
+// Ids grow with every event, in the order of changes.
+type TaskEvent struct {
+	Id          int64  `json:"id"`
+	Type        string `json:"type"`
+	TaskId      int    `json:"task_id"`
+	WorkspaceId int    `json:"workspace_id"`
+}
+
+// TaskEventMessage is a task event pushed to a subscriber, along with the
+// task for tasks matching the filter of the subscription. Tasks which stop
+// matching the filter are pushed as deleted.
+type TaskEventMessage struct {
+	Event  string        `json:"event"`
+	TaskId int           `json:"task_id"`
+	Task   *TaskListItem `json:"task,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// UndoStep reverses the change an undoable operation made to a single task.
+// Version is the version the change left the task in, the step only applies
+// to the task in that version. Nil fields were left as they were.
+type UndoStep struct {
+	TaskId    int      `json:"task_id"`
+	Version   int      `json:"version"`
+	Restore   bool     `json:"restore,omitempty"`
+	Status    *string  `json:"status,omitempty"`
+	Labels    []string `json:"labels"`
+	Move      bool     `json:"move,omitempty"`
+	ProjectId *int     `json:"project_id"`
+}
+
+// UndoEntry is an undoable operation on the undo stack of a user. Action is
+// one of the bulk actions, operations on single tasks record the matching
+// one.
+type UndoEntry struct {
+	Token       string     `json:"token"`
+	UserId      int        `json:"-"`
+	WorkspaceId int        `json:"-"`
+	Action      string     `json:"action"`
+	Steps       []UndoStep `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	TaskIds     []int      `json:"task_ids" db:"-"`
+}
+
+// AppliedChange is the change a bulk operation made to a single task, along
+// with the task before the change and the version the change left it in.
+type AppliedChange struct {
+	TaskChange
+	Old     TaskData
+	Version int
+}
@@ -12,9 +12,23 @@ type UserLogin struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type UserTimeZone struct {
+	TimeZone string `json:"time_zone" binding:"required,timezone"`
+}
+
 type UserData struct {
 	Id           int       `json:"id"`
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"`
+	TimeZone     string    `json:"time_zone"`
 	CreatedAt    time.Time `json:"created_at"`
 }
+
+// Location is the time zone relative dates of the user resolve in.
+func (u UserData) Location() *time.Location {
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Group keys of saved views, tasks can also be grouped by a custom field
+// with "fields.<id>".
+const (
+	GroupByStatus   = "status"
+	GroupByPriority = "priority"
+)
+
+// ViewCreate defines a saved view. Filter is validated with the same rules
+// as the query of task listings, except that it never holds a cursor.
+type ViewCreate struct {
+	Name    string      `json:"name" binding:"required"`
+	Filter  TasksFilter `json:"filter"`
+	GroupBy *string     `json:"group_by" binding:"omitempty,groupBy"`
+	Shared  bool        `json:"shared"`
+}
+
+type ViewData struct {
+	Id          int         `json:"id"`
+	Name        string      `json:"name"`
+	Filter      TasksFilter `json:"filter"`
+	GroupBy     *string     `json:"group_by"`
+	Shared      bool        `json:"shared"`
+	UserId      int         `json:"owner_id"`
+	WorkspaceId int         `json:"workspace_id"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// ViewTasksParams pages through the tasks of a view, Limit overrides the
+// limit of the view's filter.
+type ViewTasksParams struct {
+	Limit  int     `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor *string `form:"cursor"`
+}
+
+// TaskGroup is a run of tasks sharing the same value of the group key of
+// a view. Key is null for tasks without the value, and for views which
+// aren't grouped.
+type TaskGroup struct {
+	Key   any            `json:"key"`
+	Tasks []TaskListItem `json:"tasks"`
+}
+
+// TasksFilter is the filter tasks of the view are listed with. Grouping
+// sorts by the group key first, so tasks of a group come one after another.
+func (v ViewData) TasksFilter(params ViewTasksParams) TasksFilter {
+	filter := v.Filter
+	if params.Limit != 0 {
+		filter.Limit = params.Limit
+	}
+	filter.Cursor = params.Cursor
+
+	if v.GroupBy == nil {
+		return filter
+	}
+	sortKeys := filter.SortKeys()
+	if len(sortKeys) > 0 && sortKeys[0].Key == *v.GroupBy {
+		return filter
+	}
+	sort := *v.GroupBy
+	if filter.Sort != nil && *filter.Sort != "" {
+		sort += "," + *filter.Sort
+	}
+	filter.Sort = &sort
+	return filter
+}
+
+// GroupKey is the value of the view's group key of the task.
+func (v ViewData) GroupKey(task TaskListItem) any {
+	if v.GroupBy == nil {
+		return nil
+	}
+	switch *v.GroupBy {
+	case GroupByStatus:
+		return task.Status
+	case GroupByPriority:
+		if task.Priority == nil {
+			return nil
+		}
+		return *task.Priority
+	}
+	return task.Fields[strings.TrimPrefix(*v.GroupBy, "fields.")]
+}
@@ -0,0 +1,65 @@
+package models
+
+const (
+	StatusCategoryTodo      = "todo"
+	StatusCategoryActive    = "active"
+	StatusCategoryDone      = "done"
+	StatusCategoryCancelled = "cancelled"
+)
+
+type StatusCreate struct {
+	Name     string `json:"name" binding:"required"`
+	Category string `json:"category" binding:"required,statusCategory"`
+}
+
+type TransitionData struct {
+	From string `json:"from" binding:"required"`
+	To   string `json:"to" binding:"required"`
+}
+
+// WorkflowUpdate replaces the workflow of a project. Statuses are ordered as
+// given, omitted transitions allow moving between any two statuses.
+type WorkflowUpdate struct {
+	Statuses    []StatusCreate    `json:"statuses" binding:"required,min=1,dive"`
+	Transitions *[]TransitionData `json:"transitions" binding:"omitempty,dive"`
+}
+
+type StatusData struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Position int    `json:"position"`
+}
+
+// WorkflowData is the ordered set of statuses tasks of a project can be in
+// together with the allowed transitions between them. ProjectId is nil for
+// the default workflow.
+type WorkflowData struct {
+	ProjectId   *int             `json:"project_id"`
+	Statuses    []StatusData     `json:"statuses"`
+	Transitions []TransitionData `json:"transitions"`
+}
+
+func (w WorkflowData) HasStatus(name string) bool {
+	for _, status := range w.Statuses {
+		if status.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// InitialStatus returns the status new tasks start in, which is the first
+// status of the workflow.
+func (w WorkflowData) InitialStatus() string {
+	return w.Statuses[0].Name
+}
+
+func (w WorkflowData) CanTransition(from string, to string) bool {
+	for _, transition := range w.Transitions {
+		if transition.From == from && transition.To == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"api-server/utils"
+	"slices"
+	"time"
+)
+
+const (
+	WorkspaceRoleMember = "member"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleOwner  = "owner"
+)
+
+// WorkspaceRoleAtLeast reports whether workspace role grants at least the
+// required privileges.
+func WorkspaceRoleAtLeast(role string, required string) bool {
+	return slices.Index(utils.ValidWorkspaceRoles, role) >= slices.Index(utils.ValidWorkspaceRoles, required)
+}
+
+type WorkspaceCreate struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type WorkspaceData struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	Personal  bool      `json:"personal"`
+	CreatedAt time.Time `json:"created_at"`
+	UserId    int       `json:"owner_id"`
+}
+
+// UserWorkspace is a workspace together with the role of the user in it.
+type UserWorkspace struct {
+	WorkspaceData
+	Role string `json:"role"`
+}
+
+type WorkspaceMemberData struct {
+	UserId    int       `json:"user_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WorkspaceInvite struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=member admin"`
+}
+
+type WorkspaceInvitationData struct {
+	Id          int        `json:"id"`
+	WorkspaceId int        `json:"workspace_id"`
+	Email       string     `json:"email"`
+	Role        string     `json:"role"`
+	InvitedBy   int        `json:"invited_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	AcceptedAt  *time.Time `json:"accepted_at"`
+}
@@ -0,0 +1,73 @@
+// Package query parses the task search language, e.g.
+//
+//	status:"In progress" due<2026-11-01 label:urgent -label:blocked "release notes"
+//
+// Terms are joined by AND unless separated by OR, can be negated with "-" or
+// NOT and grouped with parentheses. Terms without a field are searched in the
+// text of tasks.
+package query
+
+import "fmt"
+
+const (
+	FieldStatus   = "status"
+	FieldLabel    = "label"
+	FieldProject  = "project"
+	FieldAssignee = "assignee"
+	FieldPriority = "priority"
+	FieldDue      = "due"
+	FieldCreated  = "created"
+	FieldIs       = "is"
+	FieldHas      = "has"
+)
+
+const (
+	OpEq  = ":"
+	OpLt  = "<"
+	OpLte = "<="
+	OpGt  = ">"
+	OpGte = ">="
+)
+
+// Node is a node of the parsed query.
+type Node interface {
+	node()
+}
+
+type And struct {
+	Nodes []Node
+}
+
+type Or struct {
+	Nodes []Node
+}
+
+type Not struct {
+	Node Node
+}
+
+// Term compares a field with a value, or searches Value in the text of tasks
+// when Field is empty. Phrase is set for quoted text.
+type Term struct {
+	Field  string
+	Op     string
+	Value  string
+	Phrase bool
+	Pos    int
+}
+
+func (And) node()  {}
+func (Or) node()   {}
+func (Not) node()  {}
+func (Term) node() {}
+
+// SyntaxError is an error in the query at Pos, the 1-based position of the
+// offending character.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query: %s at position %d", e.Msg, e.Pos)
+}
@@ -0,0 +1,84 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenMinus
+	tokenEOF
+)
+
+type token struct {
+	Kind tokenKind
+	Text string
+	Pos  int
+	// Adjacent is set when the token directly follows the previous one
+	Adjacent bool
+}
+
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(`()":<>=`, r)
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	adjacent := false
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		tok := token{Pos: start + 1, Adjacent: adjacent}
+		adjacent = true
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			adjacent = false
+			continue
+		case r == '(':
+			tok.Kind, tok.Text = tokenLParen, "("
+			i++
+		case r == ')':
+			tok.Kind, tok.Text = tokenRParen, ")"
+			i++
+		case r == '"':
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				i++
+			}
+			if i == len(runes) {
+				return nil, &SyntaxError{Pos: start + 1, Msg: "unterminated quoted string"}
+			}
+			tok.Kind, tok.Text = tokenString, string(runes[start+1:i])
+			i++
+		case r == ':' || r == '<' || r == '>' || r == '=':
+			i++
+			if (r == '<' || r == '>') && i < len(runes) && runes[i] == '=' {
+				i++
+			}
+			tok.Kind, tok.Text = tokenOp, string(runes[start:i])
+			if tok.Text == "=" {
+				return nil, &SyntaxError{Pos: start + 1, Msg: `unexpected "=", use ":" to compare`}
+			}
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && (!tok.Adjacent || tokens[len(tokens)-1].Kind == tokenLParen):
+			tok.Kind, tok.Text = tokenMinus, "-"
+			i++
+		default:
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			tok.Kind, tok.Text = tokenWord, string(runes[start:i])
+		}
+		tokens = append(tokens, tok)
+	}
+	return append(tokens, token{Kind: tokenEOF, Pos: len(runes) + 1}), nil
+}
@@ -0,0 +1,233 @@
+package query
+
+import (
+	"api-server/domain/models"
+	"api-server/utils"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ValidIs  = []string{"overdue"}
+	ValidHas = []string{"due", "description", "labels", "assignee", "project"}
+)
+
+var fieldOps = map[string][]string{
+	FieldStatus:   {OpEq},
+	FieldLabel:    {OpEq},
+	FieldProject:  {OpEq},
+	FieldAssignee: {OpEq},
+	FieldPriority: {OpEq, OpLt, OpLte, OpGt, OpGte},
+	FieldDue:      {OpEq, OpLt, OpLte, OpGt, OpGte},
+	FieldCreated:  {OpEq, OpLt, OpLte, OpGt, OpGte},
+	FieldIs:       {OpEq},
+	FieldHas:      {OpEq},
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses the query into its AST, which is nil for an empty query.
+func Parse(input string) (Node, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().Kind == tokenEOF {
+		return nil, nil
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.Kind != tokenEOF {
+		return nil, &SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf("unexpected %q", tok.Text)}
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.Kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func isKeyword(tok token, keyword string) bool {
+	return tok.Kind == tokenWord && tok.Text == keyword
+}
+
+func (p *parser) parseOr() (Node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{first}
+	for isKeyword(p.peek(), "OR") {
+		p.next()
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return first, nil
+	}
+	return Or{Nodes: nodes}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	var nodes []Node
+	for {
+		tok := p.peek()
+		if tok.Kind == tokenEOF || tok.Kind == tokenRParen || isKeyword(tok, "OR") {
+			break
+		}
+		if isKeyword(tok, "AND") {
+			p.next()
+			if len(nodes) == 0 {
+				return nil, &SyntaxError{Pos: tok.Pos, Msg: `expected a term before "AND"`}
+			}
+			continue
+		}
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 0 {
+		tok := p.peek()
+		if tok.Kind == tokenEOF {
+			return nil, &SyntaxError{Pos: tok.Pos, Msg: "expected a term at the end of the query"}
+		}
+		return nil, &SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf("expected a term before %q", tok.Text)}
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return And{Nodes: nodes}, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if tok := p.peek(); tok.Kind == tokenMinus || isKeyword(tok, "NOT") {
+		p.next()
+		if next := p.peek(); next.Kind == tokenEOF || next.Kind == tokenRParen || isKeyword(next, "OR") {
+			return nil, &SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf("expected a term after %q", tok.Text)}
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Node: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.next()
+	switch tok.Kind {
+	case tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().Kind != tokenRParen {
+			return nil, &SyntaxError{Pos: tok.Pos, Msg: `missing closing ")"`}
+		}
+		p.next()
+		return node, nil
+	case tokenString:
+		return textTerm(tok, true)
+	case tokenWord:
+		if op := p.peek(); op.Kind == tokenOp && op.Adjacent {
+			p.next()
+			return p.parseField(tok, op)
+		}
+		return textTerm(tok, false)
+	}
+	return nil, &SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf("unexpected %q", tok.Text)}
+}
+
+func textTerm(tok token, phrase bool) (Node, error) {
+	if models.ParseTextSearch(tok.Text).Match == "" {
+		return nil, &SyntaxError{Pos: tok.Pos, Msg: "nothing to search for"}
+	}
+	return Term{Value: tok.Text, Phrase: phrase, Pos: tok.Pos}, nil
+}
+
+func (p *parser) parseField(field token, op token) (Node, error) {
+	name := strings.ToLower(field.Text)
+	ops, ok := fieldOps[name]
+	if !ok {
+		return nil, &SyntaxError{Pos: field.Pos, Msg: fmt.Sprintf("unknown field %q", field.Text)}
+	}
+	if !slices.Contains(ops, op.Text) {
+		return nil, &SyntaxError{Pos: op.Pos, Msg: fmt.Sprintf("operator %q is not supported by field %q", op.Text, name)}
+	}
+
+	value := p.peek()
+	if (value.Kind != tokenWord && value.Kind != tokenString) || !value.Adjacent {
+		return nil, &SyntaxError{Pos: op.Pos + len([]rune(op.Text)), Msg: fmt.Sprintf("expected a value after %q", field.Text+op.Text)}
+	}
+	p.next()
+
+	term := Term{Field: name, Op: op.Text, Value: value.Text, Pos: field.Pos}
+	if err := validateValue(term, value.Pos); err != nil {
+		return nil, err
+	}
+	return term, nil
+}
+
+func validateValue(term Term, pos int) error {
+	invalid := func(what string) error {
+		return &SyntaxError{Pos: pos, Msg: fmt.Sprintf("invalid %s %q", what, term.Value)}
+	}
+
+	switch term.Field {
+	case FieldStatus, FieldLabel:
+		if term.Value == "" {
+			return invalid(term.Field)
+		}
+	case FieldProject:
+		if _, err := strconv.Atoi(term.Value); err != nil {
+			return invalid("project id")
+		}
+	case FieldAssignee:
+		if _, err := strconv.Atoi(term.Value); err != nil && term.Value != "me" {
+			return invalid("assignee, expected \"me\" or user id")
+		}
+	case FieldPriority:
+		if !slices.Contains(utils.ValidTaskPriorities, term.Value) {
+			return invalid("priority")
+		}
+	case FieldDue, FieldCreated:
+		if _, _, ok := utils.DayRange(term.Value, time.Now()); !ok {
+			return invalid("day")
+		}
+	case FieldIs:
+		if !slices.Contains(ValidIs, term.Value) {
+			return invalid(`value of "is"`)
+		}
+	case FieldHas:
+		if !slices.Contains(ValidHas, term.Value) {
+			return invalid(`value of "has"`)
+		}
+	}
+	return nil
+}
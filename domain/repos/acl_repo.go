@@ -0,0 +1,289 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+func shareTargetEq(target models.ShareTarget, prefix string) sq.Eq {
+	if target.TaskId != nil {
+		return sq.Eq{prefix + "task_id": *target.TaskId}
+	}
+	return sq.Eq{prefix + "project_id": *target.ProjectId}
+}
+
+type ACLRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewACLRepo(conn *pgxpool.Pool) *ACLRepo {
+	return &ACLRepo{Conn: conn}
+}
+
+// GetTaskRole returns the highest role the user has on the task through
+// project ownership or shares. Assignees are at least viewers of the task.
+// Empty string means no access.
+func (repo *ACLRepo) GetTaskRole(ctx context.Context, task models.TaskData, userId int) (string, error) {
+	roles := utils.PgxSB.
+		Select("role").
+		From("acl_entries").
+		Where(sq.Eq{"user_id": userId, "task_id": task.Id}).
+		Suffix("UNION ALL SELECT 'viewer'::share_role FROM task_assignees WHERE user_id = ? AND task_id = ?", userId, task.Id)
+	if task.ProjectId != nil {
+		roles = roles.
+			Suffix("UNION ALL SELECT role FROM acl_entries WHERE user_id = ? AND project_id = ?", userId, *task.ProjectId).
+			Suffix("UNION ALL SELECT 'owner'::share_role FROM projects WHERE user_id = ? AND id = ?", userId, *task.ProjectId)
+	}
+
+	query, args := utils.PgxSB.
+		Select("coalesce(max(role)::text, '')").
+		FromSelect(roles, "r").
+		MustSql()
+
+	startTime := time.Now()
+	role, err := pgxutil.SelectValue[string](ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return "", fmt.Errorf("db: failed to query role of user %d on task %d: %w", userId, task.Id, err)
+	}
+	return role, nil
+}
+
+// GetTaskAccess is GetTaskRole for many tasks at once, it also tells
+// whether the user is assigned to each of them. Tasks without any access
+// are missing from the result.
+func (repo *ACLRepo) GetTaskAccess(ctx context.Context, taskIds []int, userId int) (map[int]models.TaskAccess, error) {
+	query, args := utils.PgxSB.
+		Select("t.id").
+		Column(sq.Expr(`coalesce((SELECT max(r.role)::text FROM (
+	SELECT role FROM acl_entries WHERE user_id = ? AND task_id = t.id
+	UNION ALL SELECT 'viewer'::share_role FROM task_assignees WHERE user_id = ? AND task_id = t.id
+	UNION ALL SELECT role FROM acl_entries WHERE user_id = ? AND project_id = t.project_id
+	UNION ALL SELECT 'owner'::share_role FROM projects WHERE user_id = ? AND id = t.project_id
+) r), '')`, userId, userId, userId, userId)).
+		Column(sq.Expr("EXISTS (SELECT 1 FROM task_assignees WHERE user_id = ? AND task_id = t.id)", userId)).
+		From("tasks t").
+		Where("t.id = ANY(?)", taskIds).
+		MustSql()
+
+	startTime := time.Now()
+	rows, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.TaskAccess])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query roles of user %d on tasks: %w", userId, err)
+	}
+
+	access := make(map[int]models.TaskAccess, len(rows))
+	for _, row := range rows {
+		if row.Role != "" {
+			access[row.TaskId] = row
+		}
+	}
+	return access, nil
+}
+
+// GetProjectRole returns the role the user has on the project through a share.
+// Empty string means no access.
+func (repo *ACLRepo) GetProjectRole(ctx context.Context, projectId int, userId int) (string, error) {
+	query, args := utils.PgxSB.
+		Select("role::text").
+		From("acl_entries").
+		Where(sq.Eq{"user_id": userId, "project_id": projectId}).
+		MustSql()
+
+	startTime := time.Now()
+	role, err := pgxutil.SelectValue[string](ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("db: failed to query role of user %d on project %d: %w", userId, projectId, err)
+	}
+	return role, nil
+}
+
+func (repo *ACLRepo) ListShares(ctx context.Context, target models.ShareTarget) ([]models.ShareData, error) {
+	query, args := utils.PgxSB.
+		Select("a.user_id", "u.email", "a.role", "a.created_at").
+		From("acl_entries a").
+		Join("users u ON u.id = a.user_id").
+		Where(shareTargetEq(target, "a.")).
+		OrderBy("a.id").
+		MustSql()
+
+	startTime := time.Now()
+	shares, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.ShareData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query shares: %w", err)
+	}
+	return shares, nil
+}
+
+func (repo *ACLRepo) DeleteShare(ctx context.Context, target models.ShareTarget, userId int) error {
+	query, args := utils.PgxSB.
+		Delete("acl_entries").
+		Where(shareTargetEq(target, "")).
+		Where(sq.Eq{"user_id": userId}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := pgxutil.ExecRow(ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("db: failed to delete share of user %d: %w", userId, err)
+	}
+	return nil
+}
+
+const invitationReturnedFields = "RETURNING id, email, task_id, project_id, role, invited_by, created_at, accepted_at"
+
+// CreateInvitation creates a pending invitation or, when the email already
+// has one for the target, updates its role.
+func (repo *ACLRepo) CreateInvitation(ctx context.Context, target models.ShareTarget, email string, role string, invitedBy int) (models.InvitationData, error) {
+	conflictTarget := "(email, task_id) WHERE accepted_at IS NULL AND task_id IS NOT NULL"
+	if target.ProjectId != nil {
+		conflictTarget = "(email, project_id) WHERE accepted_at IS NULL AND project_id IS NOT NULL"
+	}
+	query, args := utils.PgxSB.
+		Insert("share_invitations").Columns("email", "task_id", "project_id", "role", "invited_by").
+		Values(email, target.TaskId, target.ProjectId, role, invitedBy).
+		Suffix("ON CONFLICT " + conflictTarget + " DO UPDATE SET role = EXCLUDED.role, invited_by = EXCLUDED.invited_by").
+		Suffix(invitationReturnedFields).
+		MustSql()
+
+	startTime := time.Now()
+	invitation, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.InvitationData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.InvitationData{}, fmt.Errorf("db: failed to create invitation: %w", err)
+	}
+	return invitation, nil
+}
+
+func selectInvitations() sq.SelectBuilder {
+	return utils.PgxSB.
+		Select("id", "email", "task_id", "project_id", "role", "invited_by", "created_at", "accepted_at").
+		From("share_invitations")
+}
+
+func (repo *ACLRepo) ListPendingInvitations(ctx context.Context, target *models.ShareTarget, email *string) ([]models.InvitationData, error) {
+	qBuilder := selectInvitations().
+		Where(sq.Eq{"accepted_at": nil}).
+		OrderBy("id")
+	if target != nil {
+		qBuilder = qBuilder.Where(shareTargetEq(*target, ""))
+	}
+	if email != nil {
+		qBuilder = qBuilder.Where(sq.Eq{"email": *email})
+	}
+	query, args := qBuilder.MustSql()
+
+	startTime := time.Now()
+	invitations, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.InvitationData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+func (repo *ACLRepo) GetPendingInvitation(ctx context.Context, id int) (models.InvitationData, error) {
+	query, args := selectInvitations().
+		Where(sq.Eq{"id": id, "accepted_at": nil}).
+		MustSql()
+
+	startTime := time.Now()
+	invitation, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.InvitationData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.InvitationData{}, ErrNotFound
+	}
+	if err != nil {
+		return models.InvitationData{}, fmt.Errorf("db: failed to query invitation with ID %d: %w", id, err)
+	}
+	return invitation, nil
+}
+
+func (repo *ACLRepo) DeleteInvitation(ctx context.Context, id int) error {
+	query, args := utils.PgxSB.
+		Delete("share_invitations").
+		Where(sq.Eq{"id": id, "accepted_at": nil}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := pgxutil.ExecRow(ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("db: failed to delete invitation with ID %d: %w", id, err)
+	}
+	return nil
+}
+
+// AcceptInvitation marks the invitation accepted and grants its role to the
+// user in one transaction. An existing share of the user is overwritten.
+func (repo *ACLRepo) AcceptInvitation(ctx context.Context, invitation models.InvitationData, userId int) error {
+	return pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) error {
+		query, args := utils.PgxSB.
+			Update("share_invitations").
+			Set("accepted_at", sq.Expr("CURRENT_TIMESTAMP")).
+			Where(sq.Eq{"id": invitation.Id, "accepted_at": nil}).
+			MustSql()
+
+		startTime := time.Now()
+		_, err := pgxutil.ExecRow(ctx, tx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("db: failed to accept invitation with ID %d: %w", invitation.Id, err)
+		}
+
+		conflictTarget := "(user_id, task_id)"
+		if invitation.ProjectId != nil {
+			conflictTarget = "(user_id, project_id)"
+		}
+		query, args = utils.PgxSB.
+			Insert("acl_entries").Columns("user_id", "task_id", "project_id", "role").
+			Values(userId, invitation.TaskId, invitation.ProjectId, invitation.Role).
+			Suffix("ON CONFLICT " + conflictTarget + " DO UPDATE SET role = EXCLUDED.role").
+			MustSql()
+
+		startTime = time.Now()
+		_, err = tx.Exec(ctx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to grant access of invitation %d: %w", invitation.Id, err)
+		}
+		return nil
+	})
+}
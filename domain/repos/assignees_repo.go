@@ -0,0 +1,100 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+type AssigneesRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewAssigneesRepo(conn *pgxpool.Pool) *AssigneesRepo {
+	return &AssigneesRepo{Conn: conn}
+}
+
+// Assign adds the user to assignees of the task, assigning an already
+// assigned user is a no-op.
+func (repo *AssigneesRepo) Assign(ctx context.Context, taskId int, userId int, assignedBy int) error {
+	query, args := utils.PgxSB.
+		Insert("task_assignees").Columns("task_id", "user_id", "assigned_by").
+		Values(taskId, userId, assignedBy).
+		Suffix("ON CONFLICT (task_id, user_id) DO NOTHING").
+		MustSql()
+
+	startTime := time.Now()
+	_, err := repo.Conn.Exec(ctx, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return fmt.Errorf("db: failed to assign user %d to task %d: %w", userId, taskId, err)
+	}
+	return nil
+}
+
+func (repo *AssigneesRepo) Unassign(ctx context.Context, taskId int, userId int) error {
+	query, args := utils.PgxSB.
+		Delete("task_assignees").
+		Where(sq.Eq{"task_id": taskId, "user_id": userId}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := pgxutil.ExecRow(ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("db: failed to unassign user %d from task %d: %w", userId, taskId, err)
+	}
+	return nil
+}
+
+func (repo *AssigneesRepo) ListByTaskId(ctx context.Context, taskId int) ([]models.AssigneeData, error) {
+	query, args := utils.PgxSB.
+		Select("a.user_id", "u.email", "a.assigned_by", "a.created_at").
+		From("task_assignees a").
+		Join("users u ON u.id = a.user_id").
+		Where(sq.Eq{"a.task_id": taskId}).
+		OrderBy("a.created_at", "a.user_id").
+		MustSql()
+
+	startTime := time.Now()
+	assignees, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.AssigneeData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query assignees of task %d: %w", taskId, err)
+	}
+	return assignees, nil
+}
+
+func (repo *AssigneesRepo) IsAssigned(ctx context.Context, taskId int, userId int) (bool, error) {
+	query, args := utils.PgxSB.
+		Select("1").
+		Prefix("SELECT EXISTS (").
+		From("task_assignees").
+		Where(sq.Eq{"task_id": taskId, "user_id": userId}).
+		Suffix(")").
+		MustSql()
+
+	startTime := time.Now()
+	isAssigned, err := pgxutil.SelectValue[bool](ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return false, fmt.Errorf("db: failed to query assignment of user %d to task %d: %w", userId, taskId, err)
+	}
+	return isAssigned, nil
+}
@@ -0,0 +1,98 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+type AttachmentsRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewAttachmentsRepo(conn *pgxpool.Pool) *AttachmentsRepo {
+	return &AttachmentsRepo{Conn: conn}
+}
+
+func (repo *AttachmentsRepo) Create(ctx context.Context, attachment models.AttachmentData) (models.AttachmentData, error) {
+	query, args := utils.PgxSB.
+		Insert("task_attachments").Columns("task_id", "user_id", "file_name", "content_type", "size", "blob_key").
+		Values(attachment.TaskId, attachment.UserId, attachment.FileName, attachment.ContentType, attachment.Size, attachment.BlobKey).
+		Suffix("RETURNING id, task_id, user_id, file_name, content_type, size, blob_key, created_at").
+		MustSql()
+
+	startTime := time.Now()
+	created, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.AttachmentData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.AttachmentData{}, fmt.Errorf("db: failed to create attachment: %w", err)
+	}
+	return created, nil
+}
+
+func (repo *AttachmentsRepo) ListByTaskId(ctx context.Context, taskId int) ([]models.AttachmentData, error) {
+	query, args := utils.PgxSB.
+		Select("id", "task_id", "user_id", "file_name", "content_type", "size", "blob_key", "created_at").
+		From("task_attachments").
+		Where(sq.Eq{"task_id": taskId}).
+		OrderBy("created_at", "id").
+		MustSql()
+
+	startTime := time.Now()
+	attachments, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.AttachmentData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query attachments of task %d: %w", taskId, err)
+	}
+	return attachments, nil
+}
+
+func (repo *AttachmentsRepo) GetById(ctx context.Context, taskId int, id int) (models.AttachmentData, error) {
+	query, args := utils.PgxSB.
+		Select("id", "task_id", "user_id", "file_name", "content_type", "size", "blob_key", "created_at").
+		From("task_attachments").
+		Where(sq.Eq{"id": id, "task_id": taskId}).
+		MustSql()
+
+	startTime := time.Now()
+	attachment, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.AttachmentData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.AttachmentData{}, ErrNotFound
+	}
+	if err != nil {
+		return models.AttachmentData{}, fmt.Errorf("db: failed to query attachment with ID %d: %w", id, err)
+	}
+	return attachment, nil
+}
+
+func (repo *AttachmentsRepo) DeleteById(ctx context.Context, id int) error {
+	query, args := utils.PgxSB.
+		Delete("task_attachments").
+		Where(sq.Eq{"id": id}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := pgxutil.ExecRow(ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("db: failed to delete attachment with ID %d: %w", id, err)
+	}
+	return nil
+}
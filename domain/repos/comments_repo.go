@@ -0,0 +1,140 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+type CommentsRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewCommentsRepo(conn *pgxpool.Pool) *CommentsRepo {
+	return &CommentsRepo{Conn: conn}
+}
+
+func selectComments() sq.SelectBuilder {
+	return utils.PgxSB.
+		Select("c.id", "c.task_id", "c.user_id", "u.email", "c.body", "c.created_at", "c.edited_at").
+		From("task_comments c").
+		Join("users u ON u.id = c.user_id").
+		Where(sq.Eq{"c.deleted_at": nil})
+}
+
+func (repo *CommentsRepo) ListByTaskId(ctx context.Context, taskId int, limit int, offset int) ([]models.CommentData, int, error) {
+	query, args := selectComments().
+		Where(sq.Eq{"c.task_id": taskId}).
+		OrderBy("c.created_at", "c.id").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		MustSql()
+
+	startTime := time.Now()
+	comments, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.CommentData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("db: failed to query comments of task %d: %w", taskId, err)
+	}
+
+	query, args = utils.PgxSB.
+		Select("count(*)").
+		From("task_comments").
+		Where(sq.Eq{"task_id": taskId, "deleted_at": nil}).
+		MustSql()
+
+	startTime = time.Now()
+	total, err := pgxutil.SelectValue[int](ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("db: failed to count comments of task %d: %w", taskId, err)
+	}
+	return comments, total, nil
+}
+
+func (repo *CommentsRepo) GetById(ctx context.Context, id int) (models.CommentData, error) {
+	query, args := selectComments().
+		Where(sq.Eq{"c.id": id}).
+		MustSql()
+
+	startTime := time.Now()
+	comment, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.CommentData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.CommentData{}, ErrNotFound
+	}
+	if err != nil {
+		return models.CommentData{}, fmt.Errorf("db: failed to query comment with ID %d: %w", id, err)
+	}
+	return comment, nil
+}
+
+func (repo *CommentsRepo) Create(ctx context.Context, taskId int, userId int, body string) (models.CommentData, error) {
+	query, args := utils.PgxSB.
+		Insert("task_comments").Columns("task_id", "user_id", "body").
+		Values(taskId, userId, body).
+		Suffix("RETURNING id").
+		MustSql()
+
+	startTime := time.Now()
+	id, err := pgxutil.SelectValue[int](ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.CommentData{}, fmt.Errorf("db: failed to create comment: %w", err)
+	}
+	return repo.GetById(ctx, id)
+}
+
+func (repo *CommentsRepo) UpdateBody(ctx context.Context, id int, body string) (models.CommentData, error) {
+	query, args := utils.PgxSB.
+		Update("task_comments").
+		Set("body", body).
+		Set("edited_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.Eq{"id": id, "deleted_at": nil}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := pgxutil.ExecRow(ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.CommentData{}, ErrNotFound
+	}
+	if err != nil {
+		return models.CommentData{}, fmt.Errorf("db: failed to update comment with ID %d: %w", id, err)
+	}
+	return repo.GetById(ctx, id)
+}
+
+func (repo *CommentsRepo) SoftDeleteById(ctx context.Context, id int) error {
+	query, args := utils.PgxSB.
+		Update("task_comments").
+		Set("deleted_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.Eq{"id": id, "deleted_at": nil}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := pgxutil.ExecRow(ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("db: failed to delete comment with ID %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+var customFieldColumns = []string{"id", "project_id", "name", "type", "options", "created_at"}
+
+type CustomFieldsRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewCustomFieldsRepo(conn *pgxpool.Pool) *CustomFieldsRepo {
+	return &CustomFieldsRepo{Conn: conn}
+}
+
+// jsonValue encodes value for a jsonb parameter, pgx passes strings through
+// as raw JSON otherwise.
+func jsonValue(value any) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+func (repo *CustomFieldsRepo) Create(ctx context.Context, projectId int, field models.CustomFieldCreate) (models.CustomFieldData, error) {
+	options := field.Options
+	if options == nil {
+		options = []string{}
+	}
+	query, args := utils.PgxSB.
+		Insert("custom_fields").Columns("project_id", "name", "type", "options").
+		Values(projectId, field.Name, field.Type, options).
+		Suffix("RETURNING id, project_id, name, type, options, created_at").
+		MustSql()
+
+	startTime := time.Now()
+	created, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.CustomFieldData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.CustomFieldData{}, fmt.Errorf("db: failed to create custom field: %w", err)
+	}
+	return created, nil
+}
+
+func (repo *CustomFieldsRepo) ListByProjectId(ctx context.Context, projectId int) ([]models.CustomFieldData, error) {
+	query, args := utils.PgxSB.
+		Select(customFieldColumns...).
+		From("custom_fields").
+		Where(sq.Eq{"project_id": projectId}).
+		OrderBy("id").
+		MustSql()
+
+	startTime := time.Now()
+	fields, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.CustomFieldData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query custom fields of project %d: %w", projectId, err)
+	}
+	return fields, nil
+}
+
+// GetByIds returns custom fields with given ids defined in projects of the workspace.
+func (repo *CustomFieldsRepo) GetByIds(ctx context.Context, workspaceId int, ids []int) ([]models.CustomFieldData, error) {
+	query, args := utils.PgxSB.
+		Select(customFieldColumns...).
+		From("custom_fields").
+		Where(sq.Eq{"id": ids}).
+		Where("project_id IN (SELECT id FROM projects WHERE workspace_id = ?)", workspaceId).
+		MustSql()
+
+	startTime := time.Now()
+	fields, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.CustomFieldData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query custom fields by ids: %w", err)
+	}
+	return fields, nil
+}
+
+func (repo *CustomFieldsRepo) GetById(ctx context.Context, projectId int, id int) (models.CustomFieldData, error) {
+	query, args := utils.PgxSB.
+		Select(customFieldColumns...).
+		From("custom_fields").
+		Where(sq.Eq{"id": id, "project_id": projectId}).
+		MustSql()
+
+	startTime := time.Now()
+	field, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.CustomFieldData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.CustomFieldData{}, ErrNotFound
+	}
+	if err != nil {
+		return models.CustomFieldData{}, fmt.Errorf("db: failed to query custom field with ID %d: %w", id, err)
+	}
+	return field, nil
+}
+
+func (repo *CustomFieldsRepo) DeleteById(ctx context.Context, projectId int, id int) error {
+	query, args := utils.PgxSB.
+		Delete("custom_fields").
+		Where(sq.Eq{"id": id, "project_id": projectId}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := pgxutil.ExecRow(ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("db: failed to delete custom field with ID %d: %w", id, err)
+	}
+	return nil
+}
+
+func (repo *CustomFieldsRepo) ListValues(ctx context.Context, taskId int) ([]models.FieldValueData, error) {
+	query, args := utils.PgxSB.
+		Select("f.id", "f.name", "f.type", "v.value").
+		From("task_field_values v").
+		Join("custom_fields f ON f.id = v.field_id").
+		Where(sq.Eq{"v.task_id": taskId}).
+		OrderBy("f.id").
+		MustSql()
+
+	startTime := time.Now()
+	values, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.FieldValueData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query custom field values of task %d: %w", taskId, err)
+	}
+	return values, nil
+}
+
+// fieldHistoryKey is the field of history entries of custom field values.
+func fieldHistoryKey(fieldId int) string {
+	return fmt.Sprintf("fields.%d", fieldId)
+}
+
+// lockValue selects the value of the field of the task for update in tx,
+// nil if the task has none.
+func lockValue(ctx context.Context, tx pgx.Tx, taskId int, fieldId int) (any, error) {
+	query, args := utils.PgxSB.
+		Select("value").
+		From("task_field_values").
+		Where(sq.Eq{"task_id": taskId, "field_id": fieldId}).
+		Suffix("FOR UPDATE").
+		MustSql()
+
+	startTime := time.Now()
+	value, err := pgxutil.SelectRow(ctx, tx, query, args, pgx.RowTo[any])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query custom field %d of task %d: %w", fieldId, taskId, err)
+	}
+	return value, nil
+}
+
+// SetValue sets the value of the field of the task the user edits, the
+// change is recorded in the task history.
+func (repo *CustomFieldsRepo) SetValue(ctx context.Context, workspaceId int, taskId int, fieldId int, value any, userId int) error {
+	query, args := utils.PgxSB.
+		Insert("task_field_values").Columns("task_id", "field_id", "value").
+		Values(taskId, fieldId, jsonValue(value)).
+		Suffix("ON CONFLICT (task_id, field_id) DO UPDATE SET value = EXCLUDED.value").
+		MustSql()
+
+	return pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) error {
+		old, err := lockValue(ctx, tx, taskId, fieldId)
+		if err != nil {
+			return err
+		}
+
+		startTime := time.Now()
+		_, err = tx.Exec(ctx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to set custom field %d of task %d: %w", fieldId, taskId, err)
+		}
+
+		field := fieldHistoryKey(fieldId)
+		return insertHistory(ctx, tx, workspaceId, []models.HistoryEntry{{
+			TaskId:   taskId,
+			UserId:   &userId,
+			Action:   models.HistoryUpdated,
+			Field:    &field,
+			OldValue: old,
+			NewValue: value,
+		}})
+	})
+}
+
+func (repo *CustomFieldsRepo) DeleteValue(ctx context.Context, workspaceId int, taskId int, fieldId int, userId int) error {
+	query, args := utils.PgxSB.
+		Delete("task_field_values").
+		Where(sq.Eq{"task_id": taskId, "field_id": fieldId}).
+		Suffix("RETURNING value").
+		MustSql()
+
+	return pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) error {
+		startTime := time.Now()
+		old, err := pgxutil.SelectRow(ctx, tx, query, args, pgx.RowTo[any])
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("db: failed to delete custom field %d of task %d: %w", fieldId, taskId, err)
+		}
+
+		field := fieldHistoryKey(fieldId)
+		return insertHistory(ctx, tx, workspaceId, []models.HistoryEntry{{
+			TaskId:   taskId,
+			UserId:   &userId,
+			Action:   models.HistoryUpdated,
+			Field:    &field,
+			OldValue: old,
+		}})
+	})
+}
+
+// fieldValueExpr returns the stored value of the field cast to its type so
+// it can be compared and sorted.
+func fieldValueExpr(field models.CustomFieldData) string {
+	return fmt.Sprintf("(v.value #>> '{}')::%s", fieldValueType(field))
+}
+
+func fieldSortTerm(field models.CustomFieldData) sortTerm {
+	return sortTerm{
+		Expr: fmt.Sprintf("(SELECT %s FROM task_field_values v WHERE v.task_id = tasks.id AND v.field_id = ?)", fieldValueExpr(field)),
+		Args: []any{field.Id},
+		Type: fieldValueType(field),
+	}
+}
+
+func fieldValueType(field models.CustomFieldData) string {
+	switch field.Type {
+	case models.FieldTypeNumber:
+		return "numeric"
+	case models.FieldTypeDate:
+		return "date"
+	case models.FieldTypeCheckbox:
+		return "boolean"
+	}
+	return "text"
+}
@@ -4,4 +4,10 @@ import (
 	"errors"
 )
 
-var ErrNotFound = errors.New("object not found")
+var (
+	ErrNotFound    = errors.New("object not found")
+	ErrStatusInUse = errors.New("status is still used by tasks")
+	// ErrVersionMismatch is returned by conditional updates of objects which
+	// changed since the expected version.
+	ErrVersionMismatch = errors.New("object version mismatch")
+)
@@ -0,0 +1,157 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+var historyColumns = []string{"id", "task_id", "user_id", "action", "field", "old_value", "new_value", "created_at"}
+
+// HistoryRepo reads the task history. Entries are only ever appended, by
+// repos changing tasks in the transaction of the change.
+type HistoryRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewHistoryRepo(conn *pgxpool.Pool) *HistoryRepo {
+	return &HistoryRepo{Conn: conn}
+}
+
+// actionEntry is a history entry of an action which doesn't change any
+// particular field.
+func actionEntry(taskId int, actorId *int, action string) models.HistoryEntry {
+	return models.HistoryEntry{TaskId: taskId, UserId: actorId, Action: action}
+}
+
+// updateEntries are history entries of fields the update of the task changed.
+func updateEntries(old models.TaskData, new models.TaskData, actorId *int) []models.HistoryEntry {
+	var entries []models.HistoryEntry
+	for _, change := range models.TaskFieldChanges(old, new) {
+		entries = append(entries, models.HistoryEntry{
+			TaskId:   new.Id,
+			UserId:   actorId,
+			Action:   models.HistoryUpdated,
+			Field:    &change.Field,
+			OldValue: change.Old,
+			NewValue: change.New,
+		})
+	}
+	return entries
+}
+
+// historyValue encodes a value of a history entry, entries without a value
+// store NULL.
+func historyValue(value any) any {
+	if value == nil {
+		return nil
+	}
+	return jsonValue(value)
+}
+
+// insertHistory appends entries to the history of tasks of the workspace.
+func insertHistory(ctx context.Context, tx pgx.Tx, workspaceId int, entries []models.HistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	insert := utils.PgxSB.
+		Insert("task_history").
+		Columns("task_id", "workspace_id", "user_id", "action", "field", "old_value", "new_value")
+	for _, entry := range entries {
+		insert = insert.Values(entry.TaskId, workspaceId, entry.UserId, entry.Action, entry.Field, historyValue(entry.OldValue), historyValue(entry.NewValue))
+	}
+	query, args := insert.MustSql()
+
+	startTime := time.Now()
+	_, err := tx.Exec(ctx, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return fmt.Errorf("db: failed to record task history: %w", err)
+	}
+	return nil
+}
+
+func (repo *HistoryRepo) ListByTaskId(ctx context.Context, taskId int, limit int, offset int) ([]models.HistoryEntry, int, error) {
+	query, args := utils.PgxSB.
+		Select(historyColumns...).
+		From("task_history").
+		Where(sq.Eq{"task_id": taskId}).
+		OrderBy("id").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		MustSql()
+
+	startTime := time.Now()
+	entries, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.HistoryEntry])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("db: failed to query history of task %d: %w", taskId, err)
+	}
+
+	query, args = utils.PgxSB.
+		Select("count(*)").
+		From("task_history").
+		Where(sq.Eq{"task_id": taskId}).
+		MustSql()
+
+	startTime = time.Now()
+	total, err := pgxutil.SelectValue[int](ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("db: failed to count history of task %d: %w", taskId, err)
+	}
+	return entries, total, nil
+}
+
+// ListByUserId returns changes the user made in the workspace, the latest
+// first. Only changes of tasks visible to the viewer are listed, including
+// tasks in the trash.
+func (repo *HistoryRepo) ListByUserId(ctx context.Context, workspaceId int, userId int, viewerId int, limit int, offset int) ([]models.HistoryEntry, int, error) {
+	conditions := sq.And{
+		sq.Eq{"workspace_id": workspaceId, "user_id": userId},
+		sq.Expr("task_id IN (SELECT id FROM tasks WHERE ?)", visibleTasks(viewerId)),
+	}
+	query, args := utils.PgxSB.
+		Select(historyColumns...).
+		From("task_history").
+		Where(conditions).
+		OrderBy("id DESC").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		MustSql()
+
+	startTime := time.Now()
+	entries, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.HistoryEntry])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("db: failed to query activity of user %d: %w", userId, err)
+	}
+
+	query, args = utils.PgxSB.
+		Select("count(*)").
+		From("task_history").
+		Where(conditions).
+		MustSql()
+
+	startTime = time.Now()
+	total, err := pgxutil.SelectValue[int](ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("db: failed to count activity of user %d: %w", userId, err)
+	}
+	return entries, total, nil
+}
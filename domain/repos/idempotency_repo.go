@@ -0,0 +1,138 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+var idempotencyKeyColumns = []string{"user_id", "key", "request_hash", "status", "headers", "body", "created_at", "expires_at"}
+
+type IdempotencyRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewIdempotencyRepo(conn *pgxpool.Pool) *IdempotencyRepo {
+	return &IdempotencyRepo{Conn: conn}
+}
+
+// Acquire claims the key of the user for the request with the given hash,
+// taking over keys expired at the given time. The second result reports
+// whether the key was claimed, otherwise the key as claimed by an earlier
+// request is returned.
+func (repo *IdempotencyRepo) Acquire(
+	ctx context.Context,
+	userId int,
+	key string,
+	requestHash string,
+	now time.Time,
+	expiresAt time.Time,
+) (models.IdempotencyKey, bool, error) {
+	query, args := utils.PgxSB.
+		Insert("idempotency_keys").Columns("user_id", "key", "request_hash", "expires_at").
+		Values(userId, key, requestHash, expiresAt).
+		Suffix(`ON CONFLICT (user_id, key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash, status = NULL, headers = NULL, body = NULL,
+				created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= ?`, now).
+		Suffix("RETURNING " + strings.Join(idempotencyKeyColumns, ", ")).
+		MustSql()
+
+	startTime := time.Now()
+	acquired, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.IdempotencyKey])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err == nil {
+		return acquired, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return models.IdempotencyKey{}, false, fmt.Errorf("db: failed to acquire idempotency key: %w", err)
+	}
+
+	existing, err := repo.Get(ctx, userId, key)
+	return existing, false, err
+}
+
+func (repo *IdempotencyRepo) Get(ctx context.Context, userId int, key string) (models.IdempotencyKey, error) {
+	query, args := utils.PgxSB.
+		Select(idempotencyKeyColumns...).
+		From("idempotency_keys").
+		Where(sq.Eq{"user_id": userId, "key": key}).
+		MustSql()
+
+	startTime := time.Now()
+	existing, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.IdempotencyKey])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.IdempotencyKey{}, ErrNotFound
+	}
+	if err != nil {
+		return models.IdempotencyKey{}, fmt.Errorf("db: failed to query idempotency key: %w", err)
+	}
+	return existing, nil
+}
+
+// SaveResponse stores the response to the request which claimed the key.
+func (repo *IdempotencyRepo) SaveResponse(ctx context.Context, userId int, key string, response models.IdempotentResponse) error {
+	query, args := utils.PgxSB.
+		Update("idempotency_keys").
+		Set("status", response.Status).
+		Set("headers", response.Headers).
+		Set("body", response.Body).
+		Where(sq.Eq{"user_id": userId, "key": key}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := repo.Conn.Exec(ctx, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return fmt.Errorf("db: failed to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+func (repo *IdempotencyRepo) Delete(ctx context.Context, userId int, key string) error {
+	query, args := utils.PgxSB.
+		Delete("idempotency_keys").
+		Where(sq.Eq{"user_id": userId, "key": key}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := repo.Conn.Exec(ctx, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return fmt.Errorf("db: failed to delete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired deletes keys expired at the given time and returns how many
+// of them there were.
+func (repo *IdempotencyRepo) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	query, args := utils.PgxSB.
+		Delete("idempotency_keys").
+		Where(sq.LtOrEq{"expires_at": now}).
+		MustSql()
+
+	startTime := time.Now()
+	tag, err := repo.Conn.Exec(ctx, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return 0, fmt.Errorf("db: failed to delete expired idempotency keys: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,92 @@
+package repos
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// sortTerm is a single expression of a keyset paginated ORDER BY. Values of
+// terms travel in cursors as text and are cast back to Type for comparisons.
+// Nulls sort after all values in the forward direction.
+type sortTerm struct {
+	Expr string
+	Args []any
+	Type string
+	Desc bool
+}
+
+func (t sortTerm) compare(op string, value string) sq.Sqlizer {
+	return sq.Expr(fmt.Sprintf("%s %s (?::text)::%s", t.Expr, op, t.Type), append(slices.Clone(t.Args), value)...)
+}
+
+func (t sortTerm) isNull() sq.Sqlizer {
+	return sq.Expr(t.Expr+" IS NULL", t.Args...)
+}
+
+// orderByTerms orders by the terms, reversed when paging backward.
+func orderByTerms(qBuilder sq.SelectBuilder, terms []sortTerm, backward bool) sq.SelectBuilder {
+	for _, t := range terms {
+		direction, nulls := "ASC", "NULLS LAST"
+		if t.Desc != backward {
+			direction = "DESC"
+		}
+		if backward {
+			nulls = "NULLS FIRST"
+		}
+		qBuilder = qBuilder.OrderByClause(fmt.Sprintf("%s %s %s", t.Expr, direction, nulls), t.Args...)
+	}
+	return qBuilder
+}
+
+// sortKeyColumn selects values of the terms as a text array to be put into cursors.
+func sortKeyColumn(terms []sortTerm) sq.Sqlizer {
+	exprs := make([]string, len(terms))
+	var args []any
+	for i, t := range terms {
+		exprs[i] = fmt.Sprintf("(%s)::text", t.Expr)
+		args = append(args, t.Args...)
+	}
+	return sq.Expr("ARRAY["+strings.Join(exprs, ", ")+"]::text[]", args...)
+}
+
+// keysetCondition matches rows sorting after the key, or before it when
+// paging backward.
+func keysetCondition(terms []sortTerm, key []*string, backward bool) sq.Sqlizer {
+	condition := sq.Or{}
+	for i, t := range terms {
+		termCondition := sq.And{}
+		for j := 0; j < i; j++ {
+			if key[j] == nil {
+				termCondition = append(termCondition, terms[j].isNull())
+			} else {
+				termCondition = append(termCondition, terms[j].compare("=", *key[j]))
+			}
+		}
+		termCondition = append(termCondition, termBeyond(t, key[i], backward))
+		condition = append(condition, termCondition)
+	}
+	return condition
+}
+
+// termBeyond matches values of the term strictly past value in the paging direction.
+func termBeyond(t sortTerm, value *string, backward bool) sq.Sqlizer {
+	if value == nil {
+		// nothing follows nulls, every value precedes them
+		if backward {
+			return sq.Expr(t.Expr+" IS NOT NULL", t.Args...)
+		}
+		return sq.Expr("FALSE")
+	}
+
+	op := ">"
+	if t.Desc != backward {
+		op = "<"
+	}
+	if backward {
+		return t.compare(op, *value)
+	}
+	return sq.Or{t.compare(op, *value), t.isNull()}
+}
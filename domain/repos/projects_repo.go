@@ -0,0 +1,84 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+type ProjectsRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewProjectsRepo(conn *pgxpool.Pool) *ProjectsRepo {
+	return &ProjectsRepo{Conn: conn}
+}
+
+func (repo *ProjectsRepo) Create(ctx context.Context, workspaceId int, name string, userId int) (models.ProjectData, error) {
+	query, args := utils.PgxSB.
+		Insert("projects").Columns("workspace_id", "name", "user_id").
+		Values(workspaceId, name, userId).
+		Suffix("RETURNING id, name, created_at, user_id, workspace_id").
+		MustSql()
+
+	startTime := time.Now()
+	project, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.ProjectData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.ProjectData{}, fmt.Errorf("db: failed to create project: %w", err)
+	}
+	return project, nil
+}
+
+func (repo *ProjectsRepo) GetById(ctx context.Context, workspaceId int, id int) (models.ProjectData, error) {
+	query, args := utils.PgxSB.
+		Select("id", "name", "created_at", "user_id", "workspace_id").
+		From("projects").
+		Where(sq.Eq{"id": id, "workspace_id": workspaceId}).
+		MustSql()
+
+	startTime := time.Now()
+	project, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.ProjectData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ProjectData{}, ErrNotFound
+	}
+	if err != nil {
+		return models.ProjectData{}, fmt.Errorf("db: failed to query project with ID %d: %w", id, err)
+	}
+	return project, nil
+}
+
+// ListByUserId returns projects of the workspace owned by the user or shared with them.
+func (repo *ProjectsRepo) ListByUserId(ctx context.Context, workspaceId int, userId int) ([]models.ProjectData, error) {
+	query, args := utils.PgxSB.
+		Select("id", "name", "created_at", "user_id", "workspace_id").
+		From("projects").
+		Where(sq.Eq{"workspace_id": workspaceId}).
+		Where(sq.Or{
+			sq.Eq{"user_id": userId},
+			sq.Expr("id IN (SELECT project_id FROM acl_entries WHERE user_id = ? AND project_id IS NOT NULL)", userId),
+		}).
+		OrderBy("id").
+		MustSql()
+
+	startTime := time.Now()
+	projects, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.ProjectData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query projects by user id %d: %w", userId, err)
+	}
+	return projects, nil
+}
@@ -0,0 +1,124 @@
+package repos
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/query"
+	"api-server/utils"
+	"fmt"
+	"strconv"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var querySqlOps = map[string]string{
+	query.OpEq:  "=",
+	query.OpLt:  "<",
+	query.OpLte: "<=",
+	query.OpGt:  ">",
+	query.OpGte: ">=",
+}
+
+type notExpr struct {
+	sq.Sqlizer
+}
+
+func (n notExpr) ToSql() (string, []any, error) {
+	sql, args, err := n.Sqlizer.ToSql()
+	return "NOT (" + sql + ")", args, err
+}
+
+// compileQuery compiles the parsed search query into conditions on tasks.
+// Days resolve in the location of now.
+func compileQuery(node query.Node, userId int, now time.Time) sq.Sqlizer {
+	switch n := node.(type) {
+	case query.And:
+		and := sq.And{}
+		for _, child := range n.Nodes {
+			and = append(and, compileQuery(child, userId, now))
+		}
+		return and
+	case query.Or:
+		or := sq.Or{}
+		for _, child := range n.Nodes {
+			or = append(or, compileQuery(child, userId, now))
+		}
+		return or
+	case query.Not:
+		// excluded text must not be there at all, typos aside
+		if term, ok := n.Node.(query.Term); ok && term.Field == "" {
+			search := termTextSearch(term)
+			return notExpr{sq.Expr(matchesText(search.Match), search.Match, search.Match)}
+		}
+		return notExpr{compileQuery(n.Node, userId, now)}
+	case query.Term:
+		return compileTerm(n, userId, now)
+	}
+	panic(fmt.Sprintf("query: unknown node %T", node))
+}
+
+func termTextSearch(term query.Term) models.TextSearch {
+	if term.Phrase {
+		return models.ParseTextSearch(`"` + term.Value + `"`)
+	}
+	return models.ParseTextSearch(term.Value)
+}
+
+// dayCondition compares the timestamp column with the day the term refers to.
+func dayCondition(column string, term query.Term, now time.Time) sq.Sqlizer {
+	from, to, _ := utils.DayRange(term.Value, now)
+	from, to = from.UTC(), to.UTC()
+	switch term.Op {
+	case query.OpLt:
+		return sq.Expr(column+" < ?", from)
+	case query.OpLte:
+		return sq.Expr(column+" < ?", to)
+	case query.OpGt:
+		return sq.Expr(column+" >= ?", to)
+	case query.OpGte:
+		return sq.Expr(column+" >= ?", from)
+	}
+	return sq.Expr(column+" >= ? AND "+column+" < ?", from, to)
+}
+
+func compileTerm(term query.Term, userId int, now time.Time) sq.Sqlizer {
+	switch term.Field {
+	case "":
+		return textSearchCondition(termTextSearch(term))
+	case query.FieldStatus:
+		return sq.Eq{"tasks.status": term.Value}
+	case query.FieldLabel:
+		return sq.Expr("tasks.labels @> ARRAY[?::text]", term.Value)
+	case query.FieldProject:
+		projectId, _ := strconv.Atoi(term.Value)
+		return sq.Eq{"tasks.project_id": projectId}
+	case query.FieldAssignee:
+		assigneeId, err := strconv.Atoi(term.Value)
+		if err != nil {
+			assigneeId = userId
+		}
+		return sq.Expr("tasks.id IN (SELECT task_id FROM task_assignees WHERE user_id = ?)", assigneeId)
+	case query.FieldPriority:
+		return sq.Expr(fmt.Sprintf("tasks.priority %s ?::task_priority", querySqlOps[term.Op]), term.Value)
+	case query.FieldDue:
+		return dayCondition("tasks.due_date", term, now)
+	case query.FieldCreated:
+		return dayCondition("tasks.created_at", term, now)
+	case query.FieldIs:
+		return sq.Expr(isOverdue, now.UTC())
+	case query.FieldHas:
+		switch term.Value {
+		case "due":
+			return sq.Expr("tasks.due_date IS NOT NULL")
+		case "description":
+			return sq.Expr("coalesce(tasks.description, '') <> ''")
+		case "labels":
+			return sq.Expr("cardinality(tasks.labels) > 0")
+		case "assignee":
+			return sq.Expr("EXISTS (SELECT 1 FROM task_assignees a WHERE a.task_id = tasks.id)")
+		case "project":
+			return sq.Expr("tasks.project_id IS NOT NULL")
+		}
+	}
+	panic(fmt.Sprintf("query: unknown term %s%s%s", term.Field, term.Op, term.Value))
+}
@@ -0,0 +1,205 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+type RemindersRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewRemindersRepo(conn *pgxpool.Pool) *RemindersRepo {
+	return &RemindersRepo{Conn: conn}
+}
+
+func (repo *RemindersRepo) Create(ctx context.Context, taskId int, remindAt time.Time, offsetMinutes *int) (models.ReminderData, error) {
+	query, args := utils.PgxSB.
+		Insert("task_reminders").Columns("task_id", "remind_at", "offset_minutes").
+		Values(taskId, remindAt, offsetMinutes).
+		Suffix("RETURNING id, task_id, remind_at, offset_minutes, sent_at, failed_at, created_at").
+		MustSql()
+
+	startTime := time.Now()
+	reminder, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.ReminderData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.ReminderData{}, fmt.Errorf("db: failed to create reminder for task %d: %w", taskId, err)
+	}
+	return reminder, nil
+}
+
+func (repo *RemindersRepo) ListByTaskId(ctx context.Context, taskId int) ([]models.ReminderData, error) {
+	query, args := utils.PgxSB.
+		Select("id", "task_id", "remind_at", "offset_minutes", "sent_at", "failed_at", "created_at").
+		From("task_reminders").
+		Where(sq.Eq{"task_id": taskId}).
+		OrderBy("remind_at", "id").
+		MustSql()
+
+	startTime := time.Now()
+	reminders, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.ReminderData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query reminders of task %d: %w", taskId, err)
+	}
+	return reminders, nil
+}
+
+func (repo *RemindersRepo) DeleteById(ctx context.Context, taskId int, id int) error {
+	query, args := utils.PgxSB.
+		Delete("task_reminders").
+		Where(sq.Eq{"id": id, "task_id": taskId}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := pgxutil.ExecRow(ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("db: failed to delete reminder with ID %d: %w", id, err)
+	}
+	return nil
+}
+
+// rescheduleReminders moves unsent reminders of the task set relative to its
+// due date to the due date in tx, resetting their delivery attempts. They are
+// deleted if the task no longer has a due date.
+func rescheduleReminders(ctx context.Context, tx pgx.Tx, taskId int, dueDate *time.Time) error {
+	cond := sq.And{
+		sq.Eq{"task_id": taskId, "sent_at": nil, "failed_at": nil},
+		sq.NotEq{"offset_minutes": nil},
+	}
+
+	var query string
+	var args []any
+	if dueDate == nil {
+		query, args = utils.PgxSB.Delete("task_reminders").Where(cond).MustSql()
+	} else {
+		remindAt := sq.Expr("?::timestamp - make_interval(mins => offset_minutes)", *dueDate)
+		query, args = utils.PgxSB.
+			Update("task_reminders").
+			Set("remind_at", remindAt).
+			Set("attempts", 0).
+			Set("next_attempt_at", nil).
+			Where(cond).
+			Where(sq.Expr("remind_at <> ?::timestamp - make_interval(mins => offset_minutes)", *dueDate)).
+			MustSql()
+	}
+
+	startTime := time.Now()
+	_, err := tx.Exec(ctx, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return fmt.Errorf("db: failed to reschedule reminders of task %d: %w", taskId, err)
+	}
+	return nil
+}
+
+// ProcessDue claims up to limit unsent reminders due at or before now and
+// passes each one to handle. Claiming counts an attempt and postpones the
+// next one by backoff, doubled with every attempt, in a statement of its own
+// using FOR UPDATE SKIP LOCKED, so concurrent server instances never claim
+// the same reminder and handle runs without holding locks. Each handled
+// reminder is marked as sent right away. A crash in between, a failed handle
+// or a batch outlasting the backoff leave the reminder to be claimed again,
+// so reminders are delivered at least once. Reminders failing maxAttempts
+// times are marked as failed and never claimed again, nor do they hold up
+// later ones meanwhile.
+func (repo *RemindersRepo) ProcessDue(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+	backoff time.Duration,
+	maxAttempts int,
+	handle func(models.DueReminder) error,
+) (int, error) {
+	// the outer statement numbers placeholders of the whole query
+	claimed := sq.
+		Select("r.id").
+		From("task_reminders r").
+		Join("tasks t ON t.id = r.task_id").
+		Where(sq.Eq{"r.sent_at": nil, "r.failed_at": nil, "t.deleted_at": nil}).
+		Where(sq.LtOrEq{"r.remind_at": now}).
+		Where(sq.Or{sq.Eq{"r.next_attempt_at": nil}, sq.LtOrEq{"r.next_attempt_at": now}}).
+		OrderBy("COALESCE(r.next_attempt_at, r.remind_at)", "r.id").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE OF r SKIP LOCKED")
+	query, args := utils.PgxSB.
+		Update("task_reminders r").
+		Set("attempts", sq.Expr("r.attempts + 1")).
+		Set("next_attempt_at", sq.Expr("?::timestamp + make_interval(secs => ? * power(2, r.attempts))", now, backoff.Seconds())).
+		From("claimed, tasks t").
+		Where("r.id = claimed.id AND t.id = r.task_id").
+		PrefixExpr(sq.Expr("WITH claimed AS (?)", claimed)).
+		Suffix("RETURNING r.id, r.task_id, r.remind_at, t.name, t.due_date, t.user_id, r.attempts").
+		MustSql()
+
+	startTime := time.Now()
+	due, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.DueReminder])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return 0, fmt.Errorf("db: failed to claim due reminders: %w", err)
+	}
+
+	// delivered reminders are marked even if ctx got cancelled meanwhile
+	markCtx := context.WithoutCancel(ctx)
+	sent := 0
+	var handleErr error
+	for _, reminder := range due {
+		if err := handle(reminder); err != nil {
+			handleErr = errors.Join(handleErr, fmt.Errorf("reminder %d: %w", reminder.Id, err))
+			if reminder.Attempts < maxAttempts {
+				continue
+			}
+			if err := repo.mark(markCtx, []int{reminder.Id}, "failed_at", now); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		if err := repo.mark(markCtx, []int{reminder.Id}, "sent_at", now); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, handleErr
+}
+
+// mark sets column, sent_at or failed_at, of the reminders to the time.
+func (repo *RemindersRepo) mark(ctx context.Context, ids []int, column string, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query, args := utils.PgxSB.
+		Update("task_reminders").
+		Set(column, at).
+		Where(sq.Eq{"id": ids}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := repo.Conn.Exec(ctx, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return fmt.Errorf("db: failed to mark reminders with %s: %w", column, err)
+	}
+	return nil
+}
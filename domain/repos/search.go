@@ -0,0 +1,57 @@
+package repos
+
+import (
+	"api-server/domain/models"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// tsQuery parses a query built by models.ParseTextSearch with the configuration
+// search vectors of tasks and comments are built with.
+const tsQuery = "to_tsquery('english', ?)"
+
+// matchesText matches tasks whose name, description or any comment match the query.
+func matchesText(query string) string {
+	return "(tasks.search_vector @@ " + tsQuery + " OR EXISTS (" +
+		"SELECT 1 FROM task_comments c WHERE c.task_id = tasks.id AND c.deleted_at IS NULL AND c.search_vector @@ " + tsQuery +
+		"))"
+}
+
+func textSearchCondition(search models.TextSearch) sq.Sqlizer {
+	condition := sq.And{}
+	if search.Match != "" {
+		// trigram similarity of names catches typos full-text search can't
+		condition = append(condition, sq.Expr("("+matchesText(search.Match)+" OR tasks.name % ?)", search.Match, search.Match, search.Fuzzy))
+	}
+	if search.Exclude != "" {
+		condition = append(condition, sq.Expr("NOT "+matchesText(search.Exclude), search.Exclude, search.Exclude))
+	}
+	return condition
+}
+
+// relevanceTerm ranks matches in names over descriptions over comments, with
+// similarity of the name added for typos.
+func relevanceTerm(search models.TextSearch) sortTerm {
+	return sortTerm{
+		Expr: "(ts_rank(tasks.search_vector, " + tsQuery + ") + COALESCE((" +
+			"SELECT max(ts_rank(c.search_vector, " + tsQuery + ")) FROM task_comments c WHERE c.task_id = tasks.id AND c.deleted_at IS NULL" +
+			"), 0) + similarity(tasks.name, ?))",
+		Args: []any{search.Match, search.Match, search.Fuzzy},
+		Type: "real",
+		Desc: true,
+	}
+}
+
+// snippetColumn highlights matches in the name, description and matching
+// comments of the task.
+func snippetColumn(search models.TextSearch) sq.Sqlizer {
+	if search.Match == "" {
+		return sq.Expr("NULL::text")
+	}
+	return sq.Expr(
+		"ts_headline('english', concat_ws(' ', tasks.name, tasks.description, ("+
+			"SELECT string_agg(c.body, ' ' ORDER BY c.id) FROM task_comments c WHERE c.task_id = tasks.id AND c.deleted_at IS NULL AND c.search_vector @@ "+tsQuery+
+			")), "+tsQuery+", 'MaxFragments=2, MaxWords=20, MinWords=5')",
+		search.Match, search.Match,
+	)
+}
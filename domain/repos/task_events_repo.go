@@ -0,0 +1,124 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+	log "github.com/sirupsen/logrus"
+)
+
+// taskEventsChannel is the channel the tasks_notify_event trigger notifies.
+const taskEventsChannel = "task_events"
+
+var taskEventColumns = []string{"id", "type", "task_id", "workspace_id"}
+
+type TaskEventsRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewTaskEventsRepo(conn *pgxpool.Pool) *TaskEventsRepo {
+	return &TaskEventsRepo{Conn: conn}
+}
+
+// Listen holds a connection of the pool listening for task events and passes
+// them to handle until ctx is cancelled or the connection fails. listening
+// is called once the connection listens.
+func (repo *TaskEventsRepo) Listen(ctx context.Context, listening func(), handle func(models.TaskEvent)) error {
+	pooled, err := repo.Conn.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("db: failed to acquire connection for task events: %w", err)
+	}
+	// the connection keeps listening, so it must not return to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+taskEventsChannel); err != nil {
+		return fmt.Errorf("db: failed to listen for task events: %w", err)
+	}
+	listening()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("db: failed to wait for task events: %w", err)
+		}
+
+		var event models.TaskEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			log.WithFields(log.Fields{"payload": notification.Payload, "err": err}).Error("Malformed task event")
+			continue
+		}
+		handle(event)
+	}
+}
+
+// ListSince returns up to limit events of the workspace logged after the
+// event with the given id, the oldest first.
+func (repo *TaskEventsRepo) ListSince(ctx context.Context, workspaceId int, afterId int64, limit uint64) ([]models.TaskEvent, error) {
+	query, args := utils.PgxSB.
+		Select(taskEventColumns...).
+		From("task_event_log").
+		Where(sq.Eq{"workspace_id": workspaceId}).
+		Where(sq.Gt{"id": afterId}).
+		OrderBy("id").
+		Limit(limit).
+		MustSql()
+
+	startTime := time.Now()
+	events, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.TaskEvent])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query task events of workspace %d: %w", workspaceId, err)
+	}
+	return events, nil
+}
+
+// IdRange returns ids of the oldest and the newest events logged in the
+// workspace, zeros when there are none.
+func (repo *TaskEventsRepo) IdRange(ctx context.Context, workspaceId int) (int64, int64, error) {
+	query, args := utils.PgxSB.
+		Select("COALESCE(MIN(id), 0)", "COALESCE(MAX(id), 0)").
+		From("task_event_log").
+		Where(sq.Eq{"workspace_id": workspaceId}).
+		MustSql()
+
+	var oldest, newest int64
+	startTime := time.Now()
+	err := repo.Conn.QueryRow(ctx, query, args...).Scan(&oldest, &newest)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return 0, 0, fmt.Errorf("db: failed to query task event ids of workspace %d: %w", workspaceId, err)
+	}
+	return oldest, newest, nil
+}
+
+// DeleteBefore deletes events logged before the given time, except for the
+// newest one of each workspace, which tells how far its log goes even when
+// nothing happens for long.
+func (repo *TaskEventsRepo) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	query, args := utils.PgxSB.
+		Delete("task_event_log l").
+		Where(sq.Lt{"l.created_at": before}).
+		Where("l.id < (SELECT MAX(n.id) FROM task_event_log n WHERE n.workspace_id = l.workspace_id)").
+		MustSql()
+
+	startTime := time.Now()
+	tag, err := repo.Conn.Exec(ctx, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return 0, fmt.Errorf("db: failed to delete logged task events: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
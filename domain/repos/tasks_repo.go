@@ -3,10 +3,13 @@ package repos
 import (
 	"api-server/app/logger"
 	"api-server/domain/models"
+	"api-server/domain/query"
 	"api-server/utils"
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -15,6 +18,151 @@ import (
 	"github.com/jackc/pgxutil"
 )
 
+var (
+	taskColumns        = []string{"id", "name", "description", "due_date", "status", "priority", "labels", "created_at", "user_id", "project_id", "workspace_id", "version"}
+	taskReturnedFields = "RETURNING " + strings.Join(taskColumns, ", ")
+)
+
+// visibleTasks matches tasks the user owns or is assigned to, tasks of
+// projects the user owns and tasks shared with the user directly or through
+// their project.
+func visibleTasks(userId int) sq.Sqlizer {
+	return sq.Or{
+		sq.Eq{"user_id": userId},
+		sq.Expr("project_id IN (SELECT id FROM projects WHERE user_id = ?)", userId),
+		sq.Expr("id IN (SELECT task_id FROM task_assignees WHERE user_id = ?)", userId),
+		sq.Expr("id IN (SELECT task_id FROM acl_entries WHERE user_id = ? AND task_id IS NOT NULL)", userId),
+		sq.Expr("project_id IN (SELECT project_id FROM acl_entries WHERE user_id = ? AND project_id IS NOT NULL)", userId),
+	}
+}
+
+// accessibleTasks matches tasks of given workspace visible to the user,
+// trashed tasks never are.
+func accessibleTasks(workspaceId int, userId int) sq.Sqlizer {
+	return sq.And{sq.Eq{"workspace_id": workspaceId, "deleted_at": nil}, visibleTasks(userId)}
+}
+
+// statusAttr selects an attribute of the task status from the workflow of its
+// project, or from the default workflow when the project has none.
+func statusAttr(column string) string {
+	return fmt.Sprintf(`COALESCE(
+	(SELECT s.%[1]s FROM workflow_statuses s WHERE s.project_id = tasks.project_id AND s.name = tasks.status),
+	(SELECT s.%[1]s FROM workflow_statuses s WHERE s.project_id IS NULL AND s.name = tasks.status)
+)`, column)
+}
+
+var (
+	statusPosition = statusAttr("position")
+	// overdue tasks are due in the past and neither done nor cancelled
+	isOverdue = "(tasks.due_date IS NOT NULL AND tasks.due_date < ? AND " + statusAttr("category") + " NOT IN ('done', 'cancelled'))"
+)
+
+// taskSortTerms returns keyset terms of the sort order, ending with the id
+// so that every task has a distinct key.
+func taskSortTerms(order []models.TaskOrder) []sortTerm {
+	terms := make([]sortTerm, 0, len(order)+1)
+	for _, o := range order {
+		var term sortTerm
+		switch {
+		case o.Field != nil:
+			term = fieldSortTerm(*o.Field)
+		case o.Key == models.SortDueDate:
+			term = sortTerm{Expr: "tasks.due_date", Type: "timestamp"}
+		case o.Key == models.SortCreatedAt:
+			term = sortTerm{Expr: "tasks.created_at", Type: "timestamp"}
+		case o.Key == models.SortName:
+			term = sortTerm{Expr: "tasks.name", Type: "text"}
+		case o.Key == models.SortStatus:
+			term = sortTerm{Expr: statusPosition, Type: "int"}
+		case o.Key == models.SortPriority:
+			term = sortTerm{Expr: "tasks.priority", Type: "task_priority"}
+		}
+		term.Desc = o.Desc
+		terms = append(terms, term)
+	}
+	return append(terms, sortTerm{Expr: "tasks.id", Type: "int"})
+}
+
+// taskRow is a listed task along with its sort key for cursors.
+type taskRow struct {
+	models.TaskListItem
+	SortKey []*string
+}
+
+// TasksQuery is a listing of tasks with its filters resolved. Expr is the
+// parsed search query if any, days resolve in the location of Now. Ids
+// restricts the listing to given tasks unless it's nil.
+type TasksQuery struct {
+	Filter models.TasksFilter
+	Fields models.FieldsQuery
+	Expr   query.Node
+	Now    time.Time
+	Ids    []int
+}
+
+// conditions matches tasks of the listing accessible to the user.
+func (tasksQuery TasksQuery) conditions(workspaceId int, userId int) sq.And {
+	tasksFilter, fieldsQuery, now := tasksQuery.Filter, tasksQuery.Fields, tasksQuery.Now
+	search := tasksFilter.TextSearch()
+	conditions := sq.And{accessibleTasks(workspaceId, userId)}
+	if tasksQuery.Ids != nil {
+		conditions = append(conditions, sq.Expr("id = ANY(?)", tasksQuery.Ids))
+	}
+
+	if search.Match != "" || search.Exclude != "" {
+		conditions = append(conditions, textSearchCondition(search))
+	}
+
+	if tasksQuery.Expr != nil {
+		conditions = append(conditions, compileQuery(tasksQuery.Expr, userId, now))
+	}
+
+	// due dates are stored in UTC
+	dueFrom, dueTo := tasksFilter.DueRange(now)
+	if dueFrom != nil {
+		conditions = append(conditions, sq.Expr("due_date >= ?", dueFrom.UTC()))
+	}
+	if dueTo != nil {
+		conditions = append(conditions, sq.Expr("due_date < ?", dueTo.UTC()))
+	}
+
+	if tasksFilter.HasDueDate != nil {
+		if *tasksFilter.HasDueDate {
+			conditions = append(conditions, sq.Expr("due_date IS NOT NULL"))
+		} else {
+			conditions = append(conditions, sq.Expr("due_date IS NULL"))
+		}
+	}
+
+	if tasksFilter.Overdue != nil {
+		if *tasksFilter.Overdue {
+			conditions = append(conditions, sq.Expr(isOverdue, now.UTC()))
+		} else {
+			conditions = append(conditions, sq.Expr("NOT "+isOverdue, now.UTC()))
+		}
+	}
+
+	if tasksFilter.Status != nil {
+		conditions = append(conditions, sq.Eq{"status": *tasksFilter.Status})
+	}
+
+	if tasksFilter.ProjectId != nil {
+		conditions = append(conditions, sq.Eq{"project_id": *tasksFilter.ProjectId})
+	}
+
+	if assigneeId := tasksFilter.AssigneeId(userId); assigneeId != nil {
+		conditions = append(conditions, sq.Expr("id IN (SELECT task_id FROM task_assignees WHERE user_id = ?)", *assigneeId))
+	}
+
+	for _, condition := range fieldsQuery.Conditions {
+		conditions = append(conditions, sq.Expr(
+			"id IN (SELECT task_id FROM task_field_values WHERE field_id = ? AND value @> ?::jsonb)",
+			condition.Field.Id, jsonValue(condition.Value),
+		))
+	}
+	return conditions
+}
+
 type TasksRepo struct {
 	Conn *pgxpool.Pool
 }
@@ -23,45 +171,78 @@ func NewTasksRepo(conn *pgxpool.Pool) *TasksRepo {
 	return &TasksRepo{Conn: conn}
 }
 
-func (repo *TasksRepo) ListByUserId(ctx context.Context, userId int, tasksFilter models.TasksFilter) ([]models.TaskData, error) {
+func (repo *TasksRepo) ListByUserId(
+	ctx context.Context,
+	workspaceId int,
+	userId int,
+	tasksQuery TasksQuery,
+	cursor *models.TasksCursor,
+	limit int,
+) (models.TasksSlice, error) {
+	search := tasksQuery.Filter.TextSearch()
 	qBuilder := utils.PgxSB.
-		Select("id", "name", "due_date", "status", "created_at", "user_id").
+		Select(taskColumns...).
+		Column("(SELECT count(*) FROM task_comments c WHERE c.task_id = tasks.id AND c.deleted_at IS NULL)").
+		Column("ARRAY(SELECT a.user_id FROM task_assignees a WHERE a.task_id = tasks.id ORDER BY a.user_id)").
+		Column("(SELECT COALESCE(jsonb_object_agg(v.field_id, v.value), '{}') FROM task_field_values v WHERE v.task_id = tasks.id)").
+		Column(snippetColumn(search)).
 		From("tasks").
-		Where(sq.Eq{"user_id": userId})
-
-	if tasksFilter.Query != nil && *tasksFilter.Query != "" {
-		qBuilder = qBuilder.Where("name like ?", fmt.Sprint("%", *tasksFilter.Query, "%"))
-	}
+		Where(tasksQuery.conditions(workspaceId, userId))
 
-	if tasksFilter.DueDate() != nil {
-		dd := *tasksFilter.DueDate()
-		fromDueDate := time.Date(dd.Year(), dd.Month(), dd.Day(), 0, 0, 0, 0, time.UTC)
-		toDueDate := fromDueDate.Add(24 * time.Hour)
-		qBuilder = qBuilder.Where("due_date >= ? and due_date < ?", fromDueDate, toDueDate)
+	terms := taskSortTerms(tasksQuery.Fields.Order)
+	if len(tasksQuery.Fields.Order) == 0 && search.Match != "" {
+		terms = append([]sortTerm{relevanceTerm(search)}, terms...)
 	}
-
-	if tasksFilter.Status != nil {
-		qBuilder = qBuilder.Where(sq.Eq{"status": *tasksFilter.Status})
+	backward := cursor != nil && cursor.Backward
+	if cursor != nil {
+		if len(cursor.Key) != len(terms) {
+			return models.TasksSlice{}, fmt.Errorf("db: cursor key doesn't match sorting of tasks")
+		}
+		qBuilder = qBuilder.Where(keysetCondition(terms, cursor.Key, backward))
 	}
+	qBuilder = orderByTerms(qBuilder.Column(sortKeyColumn(terms)), terms, backward).
+		Limit(uint64(limit + 1))
 
 	query, args := qBuilder.MustSql()
 
 	startTime := time.Now()
-	tasks, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.TaskData])
+	rows, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[taskRow])
 	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
 
 	if err != nil {
-		return nil, fmt.Errorf("db: failed to query tasks by user id %d: %w", userId, err)
+		return models.TasksSlice{}, fmt.Errorf("db: failed to query tasks by user id %d: %w", userId, err)
 	}
 
-	return tasks, nil
+	// one extra row tells whether there is more to page through
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+	if backward {
+		slices.Reverse(rows)
+	}
+
+	slice := models.TasksSlice{Items: make([]models.TaskListItem, len(rows))}
+	for i, row := range rows {
+		slice.Items[i] = row.TaskListItem
+	}
+	if len(rows) > 0 {
+		slice.FirstKey = rows[0].SortKey
+		slice.LastKey = rows[len(rows)-1].SortKey
+	}
+	if backward {
+		slice.HasBefore, slice.HasAfter = hasMore, true
+	} else {
+		slice.HasBefore, slice.HasAfter = cursor != nil, hasMore
+	}
+	return slice, nil
 }
 
-func (repo *TasksRepo) GetById(ctx context.Context, id int) (models.TaskData, error) {
+func (repo *TasksRepo) GetById(ctx context.Context, workspaceId int, id int) (models.TaskData, error) {
 	query, args := utils.PgxSB.
-		Select("id", "name", "due_date", "status", "created_at", "user_id").
+		Select(taskColumns...).
 		From("tasks").
-		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"id": id, "workspace_id": workspaceId, "deleted_at": nil}).
 		MustSql()
 
 	startTime := time.Now()
@@ -78,80 +259,548 @@ func (repo *TasksRepo) GetById(ctx context.Context, id int) (models.TaskData, er
 	return task, nil
 }
 
-func (repo *TasksRepo) DeleteById(ctx context.Context, id int) error {
+// lockTask selects the task for update in tx, from the trash if trashed is
+// set.
+func lockTask(ctx context.Context, tx pgx.Tx, workspaceId int, id int, trashed bool) (models.TrashedTask, error) {
+	var deleted sq.Sqlizer = sq.Eq{"deleted_at": nil}
+	if trashed {
+		deleted = sq.NotEq{"deleted_at": nil}
+	}
 	query, args := utils.PgxSB.
-		Delete("tasks").
-		Where(sq.Eq{"id": id}).
+		Select(trashedTaskColumns...).
+		From("tasks").
+		Where(sq.Eq{"id": id, "workspace_id": workspaceId}).
+		Where(deleted).
+		Suffix("FOR UPDATE").
 		MustSql()
 
 	startTime := time.Now()
-	_, err := pgxutil.ExecRow(ctx, repo.Conn, query, args...)
+	task, err := pgxutil.SelectRow(ctx, tx, query, args, pgx.RowToStructByPos[models.TrashedTask])
 	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
 
 	if errors.Is(err, pgx.ErrNoRows) {
-		return nil
+		return models.TrashedTask{}, ErrNotFound
 	}
 	if err != nil {
-		return fmt.Errorf("query: %s args: %d: %w", query, args[0], err)
+		return models.TrashedTask{}, fmt.Errorf("db: failed to lock task with ID %d: %w", id, err)
 	}
+	return task, nil
+}
 
-	return nil
+// versionCondition matches tasks in the version, any version if it's nil.
+func versionCondition(version *int) sq.Sqlizer {
+	if version == nil {
+		return sq.And{}
+	}
+	return sq.Eq{"version": *version}
 }
 
-func (repo *TasksRepo) UpdateStatus(ctx context.Context, id int, newStatus string) (models.TaskData, error) {
+// updateTask sets columns of the task in tx, bumps its version and records
+// fields it changed in the task history. Only tasks outside of the trash can
+// be updated, unless it's update of the restored task. The task is only
+// updated if it still meets cond, e.g. it's still in the version the change
+// was made against.
+func updateTask(
+	ctx context.Context,
+	tx pgx.Tx,
+	workspaceId int,
+	id int,
+	set map[string]any,
+	cond sq.Sqlizer,
+	userId int,
+	restore bool,
+) (models.TaskData, error) {
+	old, err := lockTask(ctx, tx, workspaceId, id, restore)
+	if err != nil {
+		return models.TaskData{}, err
+	}
+
 	query, args := utils.PgxSB.
 		Update("tasks").
-		Set("status", newStatus).
+		SetMap(set).
+		Set("version", sq.Expr("version + 1")).
 		Where(sq.Eq{"id": id}).
-		Suffix("RETURNING id, name, due_date, status, created_at, user_id").
+		Where(cond).
+		Suffix(taskReturnedFields).
 		MustSql()
 
 	startTime := time.Now()
-	task, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.TaskData])
+	updated, err := pgxutil.SelectRow(ctx, tx, query, args, pgx.RowToStructByPos[models.TaskData])
 	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
 
+	// the task is locked, so it can only be missing because of cond
 	if errors.Is(err, pgx.ErrNoRows) {
-		return models.TaskData{}, nil
+		return models.TaskData{}, ErrVersionMismatch
 	}
 	if err != nil {
 		return models.TaskData{}, fmt.Errorf("db: failed to update task with ID %d: %w", id, err)
 	}
 
-	return task, nil
+	var entries []models.HistoryEntry
+	if restore {
+		entries = append(entries, actionEntry(id, &userId, models.HistoryRestored))
+	}
+	entries = append(entries, updateEntries(old.TaskData, updated, &userId)...)
+	return updated, insertHistory(ctx, tx, workspaceId, entries)
 }
 
-func (repo *TasksRepo) Create(ctx context.Context, name string, dueDate *time.Time, userId int) (models.TaskData, error) {
+// Trash moves the task to the trash, where it's hidden from everything but
+// the trash itself, and returns the trashed task. With version set the task
+// is only trashed if it's still in that version.
+func (repo *TasksRepo) Trash(ctx context.Context, workspaceId int, id int, version *int, userId int) (models.TaskData, error) {
 	query, args := utils.PgxSB.
-		Insert("tasks").Columns("name", "due_date", "user_id").
-		Values(name, dueDate, userId).
-		Suffix("RETURNING id, name, due_date, status, created_at, user_id").
+		Update("tasks").
+		Set("deleted_at", time.Now().UTC()).
+		Set("deleted_by", userId).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"id": id}).
+		Where(versionCondition(version)).
+		Suffix(taskReturnedFields).
+		MustSql()
+
+	var trashed models.TaskData
+	err := pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) (err error) {
+		if _, err := lockTask(ctx, tx, workspaceId, id, false); err != nil {
+			return err
+		}
+
+		startTime := time.Now()
+		trashed, err = pgxutil.SelectRow(ctx, tx, query, args, pgx.RowToStructByPos[models.TaskData])
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrVersionMismatch
+		}
+		if err != nil {
+			return fmt.Errorf("db: failed to trash task with ID %d: %w", id, err)
+		}
+		return insertHistory(ctx, tx, workspaceId, []models.HistoryEntry{actionEntry(id, &userId, models.HistoryDeleted)})
+	})
+	return trashed, err
+}
+
+// UpdateStatus moves the task from oldStatus to newStatus, ErrVersionMismatch
+// is returned if it's no longer in oldStatus or, with version set, in that
+// version.
+func (repo *TasksRepo) UpdateStatus(
+	ctx context.Context,
+	workspaceId int,
+	id int,
+	oldStatus string,
+	newStatus string,
+	version *int,
+	userId int,
+) (models.TaskData, error) {
+	cond := sq.And{sq.Eq{"status": oldStatus}, versionCondition(version)}
+
+	var task models.TaskData
+	err := pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) (err error) {
+		task, err = updateTask(ctx, tx, workspaceId, id, map[string]any{"status": newStatus}, cond, userId, false)
+		return err
+	})
+	return task, err
+}
+
+// Edit replaces editable fields of the task. With move set the task is moved
+// to edit.ProjectId in newStatus if it's still in oldStatus, values of custom
+// fields of the old project are dropped. Reminders set relative to the due
+// date follow it.
+func (repo *TasksRepo) Edit(
+	ctx context.Context,
+	workspaceId int,
+	id int,
+	edit models.TaskEdit,
+	move bool,
+	oldStatus string,
+	newStatus string,
+	version *int,
+	userId int,
+) (models.TaskData, error) {
+	set := map[string]any{
+		"name":        edit.Name,
+		"description": edit.Description,
+		"due_date":    edit.DueDate,
+		"priority":    edit.Priority,
+	}
+	cond := versionCondition(version)
+	if move {
+		set["project_id"] = edit.ProjectId
+		set["status"] = newStatus
+		cond = sq.And{sq.Eq{"status": oldStatus}, cond}
+	}
+
+	var task models.TaskData
+	err := pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) (err error) {
+		task, err = updateTask(ctx, tx, workspaceId, id, set, cond, userId, false)
+		if err != nil {
+			return err
+		}
+		if err := rescheduleReminders(ctx, tx, id, task.DueDate); err != nil {
+			return err
+		}
+		if !move {
+			return nil
+		}
+
+		query, args := dropFieldValues(id, edit.ProjectId)
+		startTime := time.Now()
+		_, err = tx.Exec(ctx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to drop field values of task with ID %d: %w", id, err)
+		}
+		return nil
+	})
+	return task, err
+}
+
+// insertTask runs the insert of a task the user creates and records the
+// creation in the task history.
+func (repo *TasksRepo) insertTask(ctx context.Context, workspaceId int, insert sq.InsertBuilder, userId int) (models.TaskData, error) {
+	query, args := insert.Suffix(taskReturnedFields).MustSql()
+
+	var created models.TaskData
+	err := pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) (err error) {
+		startTime := time.Now()
+		created, err = pgxutil.SelectRow(ctx, tx, query, args, pgx.RowToStructByPos[models.TaskData])
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to create task: %w", err)
+		}
+		entry := actionEntry(created.Id, &userId, models.HistoryCreated)
+		entry.NewValue = created
+		return insertHistory(ctx, tx, workspaceId, []models.HistoryEntry{entry})
+	})
+	return created, err
+}
+
+func (repo *TasksRepo) Create(ctx context.Context, workspaceId int, task models.TaskCreate, status string, userId int) (models.TaskData, error) {
+	return repo.insertTask(ctx, workspaceId, utils.PgxSB.
+		Insert("tasks").Columns("workspace_id", "name", "description", "due_date", "status", "priority", "project_id", "user_id").
+		Values(workspaceId, task.Name, task.Description, task.DueDate, status, task.Priority, task.ProjectId, userId),
+		userId,
+	)
+}
+
+func (repo *TasksRepo) CreateWithStatus(ctx context.Context, workspaceId int, name string, dueDate *time.Time, status string, userId int) (models.TaskData, error) {
+	return repo.insertTask(ctx, workspaceId, utils.PgxSB.
+		Insert("tasks").Columns("workspace_id", "name", "due_date", "status", "user_id").
+		Values(workspaceId, name, dueDate, status, userId),
+		userId,
+	)
+}
+
+// ListIdsByUserId returns ids of tasks of the listing, at most limit of them.
+func (repo *TasksRepo) ListIdsByUserId(ctx context.Context, workspaceId int, userId int, tasksQuery TasksQuery, limit int) ([]int, error) {
+	query, args := utils.PgxSB.
+		Select("id").
+		From("tasks").
+		Where(tasksQuery.conditions(workspaceId, userId)).
+		OrderBy("id").
+		Limit(uint64(limit)).
 		MustSql()
 
 	startTime := time.Now()
-	task, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.TaskData])
+	ids, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowTo[int])
 	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
 
 	if err != nil {
-		return models.TaskData{}, fmt.Errorf("db: failed to create task: %w", err)
+		return nil, fmt.Errorf("db: failed to query task ids by user id %d: %w", userId, err)
 	}
+	return ids, nil
+}
 
-	return task, nil
+// ListVisibleIds returns those of the tasks with given ids of the workspace
+// visible to the user, trashed tasks included.
+func (repo *TasksRepo) ListVisibleIds(ctx context.Context, workspaceId int, userId int, ids []int) ([]int, error) {
+	query, args := utils.PgxSB.
+		Select("id").
+		From("tasks").
+		Where(sq.Eq{"workspace_id": workspaceId}).
+		Where("id = ANY(?)", ids).
+		Where(visibleTasks(userId)).
+		OrderBy("id").
+		MustSql()
+
+	startTime := time.Now()
+	visibleIds, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowTo[int])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query task ids visible to user %d: %w", userId, err)
+	}
+	return visibleIds, nil
 }
 
-func (repo *TasksRepo) CreateWithStatus(ctx context.Context, name string, dueDate *time.Time, status string, userId int) (models.TaskData, error) {
+func (repo *TasksRepo) GetByIds(ctx context.Context, workspaceId int, ids []int) ([]models.TaskData, error) {
 	query, args := utils.PgxSB.
-		Insert("tasks").Columns("name", "due_date", "status", "user_id").
-		Values(name, dueDate, status, userId).
-		Suffix("RETURNING id, name, due_date, status, created_at, user_id").
+		Select(taskColumns...).
+		From("tasks").
+		Where(sq.Eq{"workspace_id": workspaceId, "deleted_at": nil}).
+		Where("id = ANY(?)", ids).
 		MustSql()
 
 	startTime := time.Now()
-	task, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.TaskData])
+	tasks, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.TaskData])
 	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
 
 	if err != nil {
-		return models.TaskData{}, fmt.Errorf("db: failed to create task with status: %w", err)
+		return nil, fmt.Errorf("db: failed to query tasks by ids: %w", err)
+	}
+	return tasks, nil
+}
+
+// ApplyChanges applies changes of bulk operations the user makes in a single
+// transaction, recording them in the task history, and returns the changes
+// which were applied. Changes of tasks which left the version they were made
+// against, or the workspace, are skipped with ErrVersionMismatch or
+// ErrNotFound by task id. Deleted tasks go to the trash, tasks moved to
+// another project lose values of custom fields of the old one.
+func (repo *TasksRepo) ApplyChanges(
+	ctx context.Context,
+	workspaceId int,
+	userId int,
+	changes []models.TaskChange,
+) ([]models.AppliedChange, map[int]error, error) {
+	deletedAt := time.Now().UTC()
+	type statement struct {
+		query string
+		args  []any
+		// change is set for statements returning the changed task
+		change *models.TaskChange
+	}
+	var ids []int
+	for _, change := range changes {
+		if change.Delete || change.Status != nil || change.Labels != nil || change.Move {
+			ids = append(ids, change.Id)
+		}
+	}
+
+	var (
+		applied []models.AppliedChange
+		skipped map[int]error
+	)
+	err := pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) error {
+		applied, skipped = nil, make(map[int]error)
+
+		// old versions of changed tasks, locked until the changes are recorded
+		query, args := utils.PgxSB.
+			Select(taskColumns...).
+			From("tasks").
+			Where(sq.Eq{"workspace_id": workspaceId, "deleted_at": nil}).
+			Where("id = ANY(?)", ids).
+			Suffix("FOR UPDATE").
+			MustSql()
+
+		startTime := time.Now()
+		oldTasks, err := pgxutil.Select(ctx, tx, query, args, pgx.RowToStructByPos[models.TaskData])
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to lock tasks of bulk changes: %w", err)
+		}
+		oldById := make(map[int]models.TaskData, len(oldTasks))
+		for _, task := range oldTasks {
+			oldById[task.Id] = task
+		}
+
+		var statements []statement
+		for _, change := range changes {
+			if !change.Delete && change.Status == nil && change.Labels == nil && !change.Move {
+				continue
+			}
+			old, ok := oldById[change.Id]
+			if !ok {
+				skipped[change.Id] = ErrNotFound
+				continue
+			}
+			if old.Version != change.BaseVersion {
+				skipped[change.Id] = ErrVersionMismatch
+				continue
+			}
+
+			update := utils.PgxSB.
+				Update("tasks").
+				Set("version", sq.Expr("version + 1")).
+				Where(sq.Eq{"id": change.Id, "workspace_id": workspaceId, "deleted_at": nil, "version": change.BaseVersion}).
+				Suffix(taskReturnedFields)
+			if change.Delete {
+				update = update.Set("deleted_at", deletedAt).Set("deleted_by", userId)
+			}
+			if change.Status != nil {
+				update = update.Set("status", *change.Status)
+			}
+			if change.Labels != nil {
+				update = update.Set("labels", change.Labels)
+			}
+			if change.Move {
+				update = update.Set("project_id", change.ProjectId)
+
+				query, args := dropFieldValues(change.Id, change.ProjectId)
+				statements = append(statements, statement{query: query, args: args})
+			}
+			query, args := update.MustSql()
+			statements = append(statements, statement{query: query, args: args, change: &change})
+		}
+		if len(statements) == 0 {
+			return nil
+		}
+
+		batch := &pgx.Batch{}
+		for _, st := range statements {
+			batch.Queue(st.query, st.args...)
+		}
+
+		var entries []models.HistoryEntry
+		results := tx.SendBatch(ctx, batch)
+		defer results.Close()
+		for _, st := range statements {
+			startTime := time.Now()
+			if st.change != nil {
+				var changed []models.TaskData
+				rows, err := results.Query()
+				if err == nil {
+					changed, err = pgx.CollectRows(rows, pgx.RowToStructByPos[models.TaskData])
+				}
+				logger.LogDbQueryTime(st.query, st.args, err, time.Since(startTime))
+
+				if err != nil {
+					return fmt.Errorf("db: failed to apply bulk changes of tasks: %w", err)
+				}
+				// the tasks are locked, so they can't have changed meanwhile
+				for _, task := range changed {
+					if st.change.Delete {
+						entries = append(entries, actionEntry(task.Id, &userId, models.HistoryDeleted))
+					} else {
+						entries = append(entries, updateEntries(oldById[task.Id], task, &userId)...)
+					}
+					applied = append(applied, models.AppliedChange{TaskChange: *st.change, Old: oldById[task.Id], Version: task.Version})
+				}
+				continue
+			}
+
+			_, err := results.Exec()
+			logger.LogDbQueryTime(st.query, st.args, err, time.Since(startTime))
+
+			if err != nil {
+				return fmt.Errorf("db: failed to apply bulk changes of tasks: %w", err)
+			}
+		}
+		if err := results.Close(); err != nil {
+			return fmt.Errorf("db: failed to apply bulk changes of tasks: %w", err)
+		}
+		return insertHistory(ctx, tx, workspaceId, entries)
+	})
+	if err != nil {
+		return nil, nil, err
 	}
+	return applied, skipped, nil
+}
+
+// dropFieldValues deletes values of the task for custom fields outside of
+// the project the task is moved to.
+func dropFieldValues(taskId int, projectId *int) (string, []any) {
+	return utils.PgxSB.
+		Delete("task_field_values").
+		Where(sq.Eq{"task_id": taskId}).
+		Where("field_id NOT IN (SELECT id FROM custom_fields WHERE project_id IS NOT DISTINCT FROM ?)", projectId).
+		MustSql()
+}
+
+var trashedTaskColumns = append(slices.Clone(taskColumns), "deleted_at", "deleted_by")
 
+// ListTrash returns trashed tasks of the workspace the user owns or deleted,
+// the most recently deleted first.
+func (repo *TasksRepo) ListTrash(ctx context.Context, workspaceId int, userId int) ([]models.TrashedTask, error) {
+	query, args := utils.PgxSB.
+		Select(trashedTaskColumns...).
+		From("tasks").
+		Where(sq.Eq{"workspace_id": workspaceId}).
+		Where(sq.NotEq{"deleted_at": nil}).
+		Where(sq.Or{sq.Eq{"user_id": userId}, sq.Eq{"deleted_by": userId}}).
+		OrderBy("deleted_at DESC", "id DESC").
+		MustSql()
+
+	startTime := time.Now()
+	tasks, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.TrashedTask])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query trash of user %d: %w", userId, err)
+	}
+	return tasks, nil
+}
+
+func (repo *TasksRepo) GetTrashedById(ctx context.Context, workspaceId int, id int) (models.TrashedTask, error) {
+	query, args := utils.PgxSB.
+		Select(trashedTaskColumns...).
+		From("tasks").
+		Where(sq.Eq{"id": id, "workspace_id": workspaceId}).
+		Where(sq.NotEq{"deleted_at": nil}).
+		MustSql()
+
+	startTime := time.Now()
+	task, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.TrashedTask])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.TrashedTask{}, ErrNotFound
+	}
+	if err != nil {
+		return models.TrashedTask{}, fmt.Errorf("db: failed to query trashed task with ID %d: %w", id, err)
+	}
 	return task, nil
 }
+
+// Restore takes the task out of the trash in given status.
+func (repo *TasksRepo) Restore(ctx context.Context, workspaceId int, id int, status string, userId int) (models.TaskData, error) {
+	set := map[string]any{"deleted_at": nil, "deleted_by": nil, "status": status}
+
+	var task models.TaskData
+	err := pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) (err error) {
+		task, err = updateTask(ctx, tx, workspaceId, id, set, sq.And{}, userId, true)
+		return err
+	})
+	return task, err
+}
+
+// purge permanently deletes trashed tasks matching the condition and records
+// it in the task history, the actor is missing for purges of expired tasks.
+// Blob keys of their attachments are collected in the same statement, as
+// the attachments themselves are deleted along with the tasks.
+func (repo *TasksRepo) purge(ctx context.Context, condition sq.Sqlizer, actorId *int) (models.PurgedTasks, error) {
+	// the outer statement numbers placeholders of the whole query
+	purged := sq.
+		Delete("tasks").
+		Where(sq.NotEq{"deleted_at": nil}).
+		Where(condition).
+		Suffix("RETURNING id, workspace_id")
+	query, args := utils.PgxSB.
+		Select("count(*)::int").
+		Column("ARRAY(SELECT a.blob_key FROM task_attachments a WHERE a.task_id IN (SELECT id FROM purged))").
+		From("purged").
+		PrefixExpr(sq.Expr(`WITH purged AS (?), history AS (
+	INSERT INTO task_history (task_id, workspace_id, user_id, action) SELECT id, workspace_id, ?::int, ? FROM purged
+)`, purged, actorId, models.HistoryPurged)).
+		MustSql()
+
+	startTime := time.Now()
+	result, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.PurgedTasks])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.PurgedTasks{}, fmt.Errorf("db: failed to purge tasks: %w", err)
+	}
+	return result, nil
+}
+
+func (repo *TasksRepo) PurgeById(ctx context.Context, workspaceId int, id int, userId int) (models.PurgedTasks, error) {
+	return repo.purge(ctx, sq.Eq{"id": id, "workspace_id": workspaceId}, &userId)
+}
+
+// PurgeDeletedBefore permanently deletes tasks trashed before given time.
+func (repo *TasksRepo) PurgeDeletedBefore(ctx context.Context, before time.Time) (models.PurgedTasks, error) {
+	return repo.purge(ctx, sq.Lt{"deleted_at": before.UTC()}, nil)
+}
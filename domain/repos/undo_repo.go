@@ -0,0 +1,171 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+var undoEntryColumns = []string{"token", "user_id", "workspace_id", "action", "steps", "created_at", "expires_at"}
+
+type UndoRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewUndoRepo(conn *pgxpool.Pool) *UndoRepo {
+	return &UndoRepo{Conn: conn}
+}
+
+// Push puts the entry on the undo stack of its user, dropping entries of the
+// user expired by then.
+func (repo *UndoRepo) Push(ctx context.Context, entry models.UndoEntry) error {
+	deleteQuery, deleteArgs := utils.PgxSB.
+		Delete("undo_entries").
+		Where(sq.Eq{"user_id": entry.UserId}).
+		Where(sq.LtOrEq{"expires_at": entry.CreatedAt}).
+		MustSql()
+	insertQuery, insertArgs := utils.PgxSB.
+		Insert("undo_entries").
+		Columns(undoEntryColumns...).
+		Values(entry.Token, entry.UserId, entry.WorkspaceId, entry.Action, entry.Steps, entry.CreatedAt, entry.ExpiresAt).
+		MustSql()
+
+	return pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) error {
+		startTime := time.Now()
+		_, err := tx.Exec(ctx, deleteQuery, deleteArgs...)
+		logger.LogDbQueryTime(deleteQuery, deleteArgs, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to delete expired undo entries: %w", err)
+		}
+
+		startTime = time.Now()
+		_, err = tx.Exec(ctx, insertQuery, insertArgs...)
+		logger.LogDbQueryTime(insertQuery, insertArgs, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to push undo entry: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListByUserId returns the undo stack of the user in the workspace, entries
+// not expired at the given time and the most recent first.
+func (repo *UndoRepo) ListByUserId(ctx context.Context, workspaceId int, userId int, now time.Time) ([]models.UndoEntry, error) {
+	query, args := utils.PgxSB.
+		Select(undoEntryColumns...).
+		From("undo_entries").
+		Where(sq.Eq{"user_id": userId, "workspace_id": workspaceId}).
+		Where(sq.Gt{"expires_at": now}).
+		OrderBy("created_at DESC").
+		MustSql()
+
+	startTime := time.Now()
+	entries, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.UndoEntry])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query undo entries of user %d: %w", userId, err)
+	}
+	return entries, nil
+}
+
+func (repo *UndoRepo) GetByToken(ctx context.Context, workspaceId int, userId int, token string) (models.UndoEntry, error) {
+	query, args := utils.PgxSB.
+		Select(undoEntryColumns...).
+		From("undo_entries").
+		Where(sq.Eq{"token": token, "user_id": userId, "workspace_id": workspaceId}).
+		MustSql()
+
+	startTime := time.Now()
+	entry, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.UndoEntry])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.UndoEntry{}, ErrNotFound
+	}
+	if err != nil {
+		return models.UndoEntry{}, fmt.Errorf("db: failed to query undo entry: %w", err)
+	}
+	return entry, nil
+}
+
+// Apply takes the entry off the undo stack and applies its steps in a single
+// transaction, returning the tasks as the steps left them. Nothing changes
+// unless every task is still in the version the step applies to, otherwise
+// ErrVersionMismatch is returned. Tasks moved back to their project lose
+// values of custom fields of the project the undone move took them to.
+func (repo *UndoRepo) Apply(ctx context.Context, entry models.UndoEntry) ([]models.TaskData, error) {
+	query, args := utils.PgxSB.
+		Delete("undo_entries").
+		Where(sq.Eq{"token": entry.Token}).
+		MustSql()
+
+	var tasks []models.TaskData
+	err := pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) error {
+		startTime := time.Now()
+		tag, err := tx.Exec(ctx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to pop undo entry: %w", err)
+		}
+		// undone by a concurrent request
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		for _, step := range entry.Steps {
+			set := make(map[string]any)
+			if step.Restore {
+				set["deleted_at"], set["deleted_by"] = nil, nil
+			}
+			if step.Status != nil {
+				set["status"] = *step.Status
+			}
+			if step.Labels != nil {
+				set["labels"] = step.Labels
+			}
+			if step.Move {
+				set["project_id"] = step.ProjectId
+			}
+
+			task, err := updateTask(ctx, tx, entry.WorkspaceId, step.TaskId, set, versionCondition(&step.Version), entry.UserId, step.Restore)
+			// the task was purged or restored meanwhile
+			if err == ErrNotFound {
+				return ErrVersionMismatch
+			}
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+
+			if step.Move {
+				query, args := dropFieldValues(step.TaskId, step.ProjectId)
+
+				startTime := time.Now()
+				_, err := tx.Exec(ctx, query, args...)
+				logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+				if err != nil {
+					return fmt.Errorf("db: failed to drop field values of task with ID %d: %w", step.TaskId, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
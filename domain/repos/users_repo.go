@@ -43,26 +43,37 @@ func (repo *UsersRepo) EmailExists(ctx context.Context, email string) (bool, err
 	return emailExists, nil
 }
 
+// Create registers the user together with their personal workspace.
 func (repo *UsersRepo) Create(ctx context.Context, email string, passwordHash string) (models.UserData, error) {
-	query, args := utils.PgxSB.
-		Insert("users").Columns("email", "password_hash").
-		Values(email, passwordHash).
-		Suffix("RETURNING id, email, password_hash, created_at").
-		MustSql()
+	var user models.UserData
+	err := pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) error {
+		query, args := utils.PgxSB.
+			Insert("users").Columns("email", "password_hash").
+			Values(email, passwordHash).
+			Suffix("RETURNING id, email, password_hash, time_zone, created_at").
+			MustSql()
 
-	startTime := time.Now()
-	user, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.UserData])
-	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+		startTime := time.Now()
+		var err error
+		user, err = pgxutil.SelectRow(ctx, tx, query, args, pgx.RowToStructByPos[models.UserData])
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to create user: %w", err)
+		}
 
+		_, err = insertWorkspace(ctx, tx, "Personal", true, user.Id)
+		return err
+	})
 	if err != nil {
-		return models.UserData{}, fmt.Errorf("db: failed to create user: %w", err)
+		return models.UserData{}, err
 	}
 	return user, nil
 }
 
 func (repo *UsersRepo) GetByEmail(ctx context.Context, email string) (models.UserData, error) {
 	query, args := utils.PgxSB.
-		Select("id", "email", "password_hash", "created_at").
+		Select("id", "email", "password_hash", "time_zone", "created_at").
 		From("users").
 		Where(sq.Eq{"email": email}).
 		MustSql()
@@ -80,3 +91,20 @@ func (repo *UsersRepo) GetByEmail(ctx context.Context, email string) (models.Use
 
 	return user, nil
 }
+
+func (repo *UsersRepo) UpdateTimeZone(ctx context.Context, id int, timeZone string) error {
+	query, args := utils.PgxSB.
+		Update("users").
+		Set("time_zone", timeZone).
+		Where(sq.Eq{"id": id}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := repo.Conn.Exec(ctx, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return fmt.Errorf("db: failed to update time zone of user %d: %w", id, err)
+	}
+	return nil
+}
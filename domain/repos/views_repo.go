@@ -0,0 +1,128 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+var viewColumns = []string{"id", "name", "filter", "group_by", "shared", "user_id", "workspace_id", "created_at"}
+
+type ViewsRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewViewsRepo(conn *pgxpool.Pool) *ViewsRepo {
+	return &ViewsRepo{Conn: conn}
+}
+
+func (repo *ViewsRepo) Create(ctx context.Context, workspaceId int, view models.ViewCreate, userId int) (models.ViewData, error) {
+	query, args := utils.PgxSB.
+		Insert("saved_views").Columns("workspace_id", "name", "filter", "group_by", "shared", "user_id").
+		Values(workspaceId, view.Name, view.Filter, view.GroupBy, view.Shared, userId).
+		Suffix("RETURNING " + strings.Join(viewColumns, ", ")).
+		MustSql()
+
+	startTime := time.Now()
+	created, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.ViewData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.ViewData{}, fmt.Errorf("db: failed to create view: %w", err)
+	}
+	return created, nil
+}
+
+func (repo *ViewsRepo) GetById(ctx context.Context, workspaceId int, id int) (models.ViewData, error) {
+	query, args := utils.PgxSB.
+		Select(viewColumns...).
+		From("saved_views").
+		Where(sq.Eq{"id": id, "workspace_id": workspaceId}).
+		MustSql()
+
+	startTime := time.Now()
+	view, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.ViewData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ViewData{}, ErrNotFound
+	}
+	if err != nil {
+		return models.ViewData{}, fmt.Errorf("db: failed to query view with ID %d: %w", id, err)
+	}
+	return view, nil
+}
+
+// ListByUserId returns views of the workspace saved by the user or shared
+// by other members.
+func (repo *ViewsRepo) ListByUserId(ctx context.Context, workspaceId int, userId int) ([]models.ViewData, error) {
+	query, args := utils.PgxSB.
+		Select(viewColumns...).
+		From("saved_views").
+		Where(sq.Eq{"workspace_id": workspaceId}).
+		Where(sq.Or{sq.Eq{"user_id": userId}, sq.Eq{"shared": true}}).
+		OrderBy("id").
+		MustSql()
+
+	startTime := time.Now()
+	views, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.ViewData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query views by user id %d: %w", userId, err)
+	}
+	return views, nil
+}
+
+func (repo *ViewsRepo) Update(ctx context.Context, workspaceId int, id int, view models.ViewCreate) (models.ViewData, error) {
+	query, args := utils.PgxSB.
+		Update("saved_views").
+		Set("name", view.Name).
+		Set("filter", view.Filter).
+		Set("group_by", view.GroupBy).
+		Set("shared", view.Shared).
+		Where(sq.Eq{"id": id, "workspace_id": workspaceId}).
+		Suffix("RETURNING " + strings.Join(viewColumns, ", ")).
+		MustSql()
+
+	startTime := time.Now()
+	updated, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.ViewData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ViewData{}, ErrNotFound
+	}
+	if err != nil {
+		return models.ViewData{}, fmt.Errorf("db: failed to update view with ID %d: %w", id, err)
+	}
+	return updated, nil
+}
+
+func (repo *ViewsRepo) DeleteById(ctx context.Context, workspaceId int, id int) error {
+	query, args := utils.PgxSB.
+		Delete("saved_views").
+		Where(sq.Eq{"id": id, "workspace_id": workspaceId}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := pgxutil.ExecRow(ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("db: failed to delete view with ID %d: %w", id, err)
+	}
+	return nil
+}
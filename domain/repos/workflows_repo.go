@@ -0,0 +1,184 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+type WorkflowsRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewWorkflowsRepo(conn *pgxpool.Pool) *WorkflowsRepo {
+	return &WorkflowsRepo{Conn: conn}
+}
+
+// workflowOf matches statuses of the project workflow, or of the default one
+// when projectId is nil.
+func workflowOf(column string, projectId *int) sq.Sqlizer {
+	if projectId == nil {
+		return sq.Eq{column: nil}
+	}
+	return sq.Eq{column: *projectId}
+}
+
+// GetByProjectId returns the workflow defined for the project, or the default
+// workflow when projectId is nil. Returns ErrNotFound if the project has no
+// workflow of its own.
+func (repo *WorkflowsRepo) GetByProjectId(ctx context.Context, projectId *int) (models.WorkflowData, error) {
+	query, args := utils.PgxSB.
+		Select("id", "name", "category", "position").
+		From("workflow_statuses").
+		Where(workflowOf("project_id", projectId)).
+		OrderBy("position").
+		MustSql()
+
+	startTime := time.Now()
+	statuses, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.StatusData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.WorkflowData{}, fmt.Errorf("db: failed to query workflow statuses: %w", err)
+	}
+	if len(statuses) == 0 {
+		return models.WorkflowData{}, ErrNotFound
+	}
+
+	query, args = utils.PgxSB.
+		Select("f.name", "t.name").
+		From("workflow_transitions tr").
+		Join("workflow_statuses f ON f.id = tr.from_status_id").
+		Join("workflow_statuses t ON t.id = tr.to_status_id").
+		Where(workflowOf("f.project_id", projectId)).
+		OrderBy("f.position", "t.position").
+		MustSql()
+
+	startTime = time.Now()
+	transitions, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.TransitionData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.WorkflowData{}, fmt.Errorf("db: failed to query workflow transitions: %w", err)
+	}
+	return models.WorkflowData{ProjectId: projectId, Statuses: statuses, Transitions: transitions}, nil
+}
+
+// Replace replaces the workflow of the project. Fails with ErrStatusInUse
+// if a task of the project is in a status missing from the new workflow.
+func (repo *WorkflowsRepo) Replace(
+	ctx context.Context,
+	projectId int,
+	statuses []models.StatusCreate,
+	transitions []models.TransitionData,
+) (models.WorkflowData, error) {
+	err := pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) error {
+		query, args := utils.PgxSB.
+			Delete("workflow_statuses").
+			Where(sq.Eq{"project_id": projectId}).
+			MustSql()
+
+		startTime := time.Now()
+		_, err := tx.Exec(ctx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to delete workflow of project %d: %w", projectId, err)
+		}
+
+		names := make([]string, len(statuses))
+		statusesInsert := utils.PgxSB.
+			Insert("workflow_statuses").Columns("project_id", "name", "category", "position")
+		for i, status := range statuses {
+			names[i] = status.Name
+			statusesInsert = statusesInsert.Values(projectId, status.Name, status.Category, i)
+		}
+		query, args = statusesInsert.MustSql()
+
+		startTime = time.Now()
+		_, err = tx.Exec(ctx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to create workflow statuses of project %d: %w", projectId, err)
+		}
+
+		if len(transitions) > 0 {
+			transitionsInsert := utils.PgxSB.
+				Insert("workflow_transitions").Columns("from_status_id", "to_status_id")
+			for _, transition := range transitions {
+				transitionsInsert = transitionsInsert.Values(
+					sq.Expr("(SELECT id FROM workflow_statuses WHERE project_id = ? AND name = ?)", projectId, transition.From),
+					sq.Expr("(SELECT id FROM workflow_statuses WHERE project_id = ? AND name = ?)", projectId, transition.To),
+				)
+			}
+			query, args = transitionsInsert.Suffix("ON CONFLICT DO NOTHING").MustSql()
+
+			startTime = time.Now()
+			_, err = tx.Exec(ctx, query, args...)
+			logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+			if err != nil {
+				return fmt.Errorf("db: failed to create workflow transitions of project %d: %w", projectId, err)
+			}
+		}
+
+		query, args = utils.PgxSB.
+			Select("1").
+			Prefix("SELECT EXISTS (").
+			From("tasks").
+			Where(sq.Eq{"project_id": projectId, "deleted_at": nil}).
+			Where(sq.NotEq{"status": names}).
+			Suffix(")").
+			MustSql()
+
+		startTime = time.Now()
+		inUse, err := pgxutil.SelectValue[bool](ctx, tx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to query statuses of tasks in project %d: %w", projectId, err)
+		}
+		if inUse {
+			return ErrStatusInUse
+		}
+		return nil
+	})
+	if err != nil {
+		return models.WorkflowData{}, err
+	}
+	return repo.GetByProjectId(ctx, &projectId)
+}
+
+// StatusExists reports whether any workflow usable in the workspace has
+// a status with given name.
+func (repo *WorkflowsRepo) StatusExists(ctx context.Context, workspaceId int, name string) (bool, error) {
+	query, args := utils.PgxSB.
+		Select("1").
+		Prefix("SELECT EXISTS (").
+		From("workflow_statuses").
+		Where(sq.Eq{"name": name}).
+		Where(sq.Or{
+			sq.Eq{"project_id": nil},
+			sq.Expr("project_id IN (SELECT id FROM projects WHERE workspace_id = ?)", workspaceId),
+		}).
+		Suffix(")").
+		MustSql()
+
+	startTime := time.Now()
+	exists, err := pgxutil.SelectValue[bool](ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return false, fmt.Errorf("db: failed to query status %s: %w", name, err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,341 @@
+package repos
+
+import (
+	"api-server/app/logger"
+	"api-server/domain/models"
+	"api-server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxutil"
+)
+
+const workspaceInvitationReturnedFields = "RETURNING id, workspace_id, email, role, invited_by, created_at, accepted_at"
+
+type WorkspacesRepo struct {
+	Conn *pgxpool.Pool
+}
+
+func NewWorkspacesRepo(conn *pgxpool.Pool) *WorkspacesRepo {
+	return &WorkspacesRepo{Conn: conn}
+}
+
+func selectUserWorkspaces() sq.SelectBuilder {
+	return utils.PgxSB.
+		Select("w.id", "w.name", "w.personal", "w.created_at", "w.user_id", "m.role").
+		From("workspaces w").
+		Join("workspace_members m ON m.workspace_id = w.id")
+}
+
+// insertWorkspace creates a workspace within tx and makes userId its owner.
+func insertWorkspace(ctx context.Context, tx pgx.Tx, name string, personal bool, userId int) (models.UserWorkspace, error) {
+	query, args := utils.PgxSB.
+		Insert("workspaces").Columns("name", "personal", "user_id").
+		Values(name, personal, userId).
+		Suffix("RETURNING id, name, personal, created_at, user_id").
+		MustSql()
+
+	startTime := time.Now()
+	workspace, err := pgxutil.SelectRow(ctx, tx, query, args, pgx.RowToStructByPos[models.WorkspaceData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.UserWorkspace{}, fmt.Errorf("db: failed to create workspace: %w", err)
+	}
+
+	query, args = utils.PgxSB.
+		Insert("workspace_members").Columns("workspace_id", "user_id", "role").
+		Values(workspace.Id, userId, models.WorkspaceRoleOwner).
+		MustSql()
+
+	startTime = time.Now()
+	_, err = tx.Exec(ctx, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.UserWorkspace{}, fmt.Errorf("db: failed to add owner of workspace %d: %w", workspace.Id, err)
+	}
+	return models.UserWorkspace{WorkspaceData: workspace, Role: models.WorkspaceRoleOwner}, nil
+}
+
+func (repo *WorkspacesRepo) Create(ctx context.Context, name string, userId int) (models.UserWorkspace, error) {
+	var workspace models.UserWorkspace
+	err := pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) error {
+		var err error
+		workspace, err = insertWorkspace(ctx, tx, name, false, userId)
+		return err
+	})
+	return workspace, err
+}
+
+// ListByUserId returns workspaces the user is a member of.
+func (repo *WorkspacesRepo) ListByUserId(ctx context.Context, userId int) ([]models.UserWorkspace, error) {
+	query, args := selectUserWorkspaces().
+		Where(sq.Eq{"m.user_id": userId}).
+		OrderBy("w.id").
+		MustSql()
+
+	startTime := time.Now()
+	workspaces, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.UserWorkspace])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query workspaces by user id %d: %w", userId, err)
+	}
+	return workspaces, nil
+}
+
+// GetMembership returns the workspace if the user is its member.
+func (repo *WorkspacesRepo) GetMembership(ctx context.Context, workspaceId int, userId int) (models.UserWorkspace, error) {
+	query, args := selectUserWorkspaces().
+		Where(sq.Eq{"w.id": workspaceId, "m.user_id": userId}).
+		MustSql()
+
+	startTime := time.Now()
+	workspace, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.UserWorkspace])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.UserWorkspace{}, ErrNotFound
+	}
+	if err != nil {
+		return models.UserWorkspace{}, fmt.Errorf("db: failed to query membership of user %d in workspace %d: %w", userId, workspaceId, err)
+	}
+	return workspace, nil
+}
+
+func (repo *WorkspacesRepo) GetPersonal(ctx context.Context, userId int) (models.UserWorkspace, error) {
+	query, args := selectUserWorkspaces().
+		Where(sq.Eq{"w.user_id": userId, "m.user_id": userId, "w.personal": true}).
+		MustSql()
+
+	startTime := time.Now()
+	workspace, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.UserWorkspace])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.UserWorkspace{}, ErrNotFound
+	}
+	if err != nil {
+		return models.UserWorkspace{}, fmt.Errorf("db: failed to query personal workspace of user %d: %w", userId, err)
+	}
+	return workspace, nil
+}
+
+func (repo *WorkspacesRepo) ListMembers(ctx context.Context, workspaceId int) ([]models.WorkspaceMemberData, error) {
+	query, args := utils.PgxSB.
+		Select("m.user_id", "u.email", "m.role", "m.created_at").
+		From("workspace_members m").
+		Join("users u ON u.id = m.user_id").
+		Where(sq.Eq{"m.workspace_id": workspaceId}).
+		OrderBy("m.created_at", "m.user_id").
+		MustSql()
+
+	startTime := time.Now()
+	members, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.WorkspaceMemberData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query members of workspace %d: %w", workspaceId, err)
+	}
+	return members, nil
+}
+
+// IsMemberEmail checks membership by a normalized email, matching users'
+// emails regardless of case.
+func (repo *WorkspacesRepo) IsMemberEmail(ctx context.Context, workspaceId int, email string) (bool, error) {
+	query, args := utils.PgxSB.
+		Select("1").
+		Prefix("SELECT EXISTS (").
+		From("workspace_members m").
+		Join("users u ON u.id = m.user_id").
+		Where(sq.Eq{"m.workspace_id": workspaceId}).
+		Where("lower(u.email) = ?", email).
+		Suffix(")").
+		MustSql()
+
+	startTime := time.Now()
+	isMember, err := pgxutil.SelectValue[bool](ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return false, fmt.Errorf("db: failed to query membership of %s in workspace %d: %w", email, workspaceId, err)
+	}
+	return isMember, nil
+}
+
+// DeleteMember removes the user from the workspace together with any shares
+// and assignments they were given on its tasks and projects.
+func (repo *WorkspacesRepo) DeleteMember(ctx context.Context, workspaceId int, userId int) error {
+	return pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) error {
+		query, args := utils.PgxSB.
+			Delete("workspace_members").
+			Where(sq.Eq{"workspace_id": workspaceId, "user_id": userId}).
+			MustSql()
+
+		startTime := time.Now()
+		_, err := pgxutil.ExecRow(ctx, tx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("db: failed to delete member %d of workspace %d: %w", userId, workspaceId, err)
+		}
+
+		query, args = utils.PgxSB.
+			Delete("acl_entries").
+			Where(sq.Eq{"user_id": userId}).
+			Where(sq.Or{
+				sq.Expr("task_id IN (SELECT id FROM tasks WHERE workspace_id = ?)", workspaceId),
+				sq.Expr("project_id IN (SELECT id FROM projects WHERE workspace_id = ?)", workspaceId),
+			}).
+			MustSql()
+
+		startTime = time.Now()
+		_, err = tx.Exec(ctx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to delete shares of member %d of workspace %d: %w", userId, workspaceId, err)
+		}
+
+		query, args = utils.PgxSB.
+			Delete("task_assignees").
+			Where(sq.Eq{"user_id": userId}).
+			Where("task_id IN (SELECT id FROM tasks WHERE workspace_id = ?)", workspaceId).
+			MustSql()
+
+		startTime = time.Now()
+		_, err = tx.Exec(ctx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to delete assignments of member %d of workspace %d: %w", userId, workspaceId, err)
+		}
+		return nil
+	})
+}
+
+// CreateInvitation creates a pending invitation or, when the email already
+// has one for the workspace, updates its role.
+func (repo *WorkspacesRepo) CreateInvitation(ctx context.Context, workspaceId int, email string, role string, invitedBy int) (models.WorkspaceInvitationData, error) {
+	query, args := utils.PgxSB.
+		Insert("workspace_invitations").Columns("workspace_id", "email", "role", "invited_by").
+		Values(workspaceId, email, role, invitedBy).
+		Suffix("ON CONFLICT (email, workspace_id) WHERE accepted_at IS NULL DO UPDATE SET role = EXCLUDED.role, invited_by = EXCLUDED.invited_by").
+		Suffix(workspaceInvitationReturnedFields).
+		MustSql()
+
+	startTime := time.Now()
+	invitation, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.WorkspaceInvitationData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return models.WorkspaceInvitationData{}, fmt.Errorf("db: failed to create workspace invitation: %w", err)
+	}
+	return invitation, nil
+}
+
+func selectWorkspaceInvitations() sq.SelectBuilder {
+	return utils.PgxSB.
+		Select("id", "workspace_id", "email", "role", "invited_by", "created_at", "accepted_at").
+		From("workspace_invitations")
+}
+
+func (repo *WorkspacesRepo) ListPendingInvitations(ctx context.Context, email string) ([]models.WorkspaceInvitationData, error) {
+	query, args := selectWorkspaceInvitations().
+		Where(sq.Eq{"email": email, "accepted_at": nil}).
+		OrderBy("id").
+		MustSql()
+
+	startTime := time.Now()
+	invitations, err := pgxutil.Select(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.WorkspaceInvitationData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to query workspace invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+func (repo *WorkspacesRepo) GetPendingInvitation(ctx context.Context, id int) (models.WorkspaceInvitationData, error) {
+	query, args := selectWorkspaceInvitations().
+		Where(sq.Eq{"id": id, "accepted_at": nil}).
+		MustSql()
+
+	startTime := time.Now()
+	invitation, err := pgxutil.SelectRow(ctx, repo.Conn, query, args, pgx.RowToStructByPos[models.WorkspaceInvitationData])
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.WorkspaceInvitationData{}, ErrNotFound
+	}
+	if err != nil {
+		return models.WorkspaceInvitationData{}, fmt.Errorf("db: failed to query workspace invitation with ID %d: %w", id, err)
+	}
+	return invitation, nil
+}
+
+// AcceptInvitation marks the invitation accepted and adds the user to the
+// workspace. Existing members keep their current role.
+func (repo *WorkspacesRepo) AcceptInvitation(ctx context.Context, invitation models.WorkspaceInvitationData, userId int) error {
+	return pgx.BeginFunc(ctx, repo.Conn, func(tx pgx.Tx) error {
+		query, args := utils.PgxSB.
+			Update("workspace_invitations").
+			Set("accepted_at", sq.Expr("CURRENT_TIMESTAMP")).
+			Where(sq.Eq{"id": invitation.Id, "accepted_at": nil}).
+			MustSql()
+
+		startTime := time.Now()
+		_, err := pgxutil.ExecRow(ctx, tx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("db: failed to accept workspace invitation with ID %d: %w", invitation.Id, err)
+		}
+
+		query, args = utils.PgxSB.
+			Insert("workspace_members").Columns("workspace_id", "user_id", "role").
+			Values(invitation.WorkspaceId, userId, invitation.Role).
+			Suffix("ON CONFLICT (workspace_id, user_id) DO NOTHING").
+			MustSql()
+
+		startTime = time.Now()
+		_, err = tx.Exec(ctx, query, args...)
+		logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+		if err != nil {
+			return fmt.Errorf("db: failed to add member of workspace invitation %d: %w", invitation.Id, err)
+		}
+		return nil
+	})
+}
+
+func (repo *WorkspacesRepo) DeleteInvitation(ctx context.Context, id int) error {
+	query, args := utils.PgxSB.
+		Delete("workspace_invitations").
+		Where(sq.Eq{"id": id, "accepted_at": nil}).
+		MustSql()
+
+	startTime := time.Now()
+	_, err := pgxutil.ExecRow(ctx, repo.Conn, query, args...)
+	logger.LogDbQueryTime(query, args, err, time.Since(startTime))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("db: failed to delete workspace invitation with ID %d: %w", id, err)
+	}
+	return nil
+}
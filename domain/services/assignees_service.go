@@ -0,0 +1,76 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"context"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrAssigneeDoesNotExist = errors.New("user is not assigned to the task")
+
+type AssigneesService struct {
+	Repo           *repos.AssigneesRepo
+	WorkspacesRepo *repos.WorkspacesRepo
+	Auth           *Authorizer
+}
+
+func NewAssigneesService(repo *repos.AssigneesRepo, workspacesRepo *repos.WorkspacesRepo, auth *Authorizer) *AssigneesService {
+	return &AssigneesService{Repo: repo, WorkspacesRepo: workspacesRepo, Auth: auth}
+}
+
+func (s *AssigneesService) ListByTaskId(ctx context.Context, workspaceId int, taskId int, reqUserId int) ([]models.AssigneeData, error) {
+	if _, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.Repo.ListByTaskId(ctx, taskId)
+}
+
+// Assign assigns the user to the task. Only members of the task's workspace
+// can be assigned.
+func (s *AssigneesService) Assign(ctx context.Context, workspaceId int, taskId int, userId int, reqUserId int) error {
+	if _, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleEditor); err != nil {
+		return err
+	}
+
+	_, err := s.WorkspacesRepo.GetMembership(ctx, workspaceId, userId)
+	if err == repos.ErrNotFound {
+		return ErrMemberDoesNotExist
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.Repo.Assign(ctx, taskId, userId, reqUserId); err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"task_id":     taskId,
+		"user_id":     userId,
+		"assigned_by": reqUserId,
+	}).Info("User assigned to task")
+	return nil
+}
+
+// Unassign removes the user from assignees of the task. Editors may unassign
+// anyone, assignees may always unassign themselves.
+func (s *AssigneesService) Unassign(ctx context.Context, workspaceId int, taskId int, userId int, reqUserId int) error {
+	var err error
+	if userId == reqUserId {
+		_, err = s.Auth.AuthorizeTaskOrAssignee(ctx, workspaceId, taskId, reqUserId, models.RoleEditor)
+	} else {
+		_, err = s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleEditor)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.Repo.Unassign(ctx, taskId, userId)
+	if err == repos.ErrNotFound {
+		return ErrAssigneeDoesNotExist
+	}
+	return err
+}
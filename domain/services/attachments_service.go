@@ -0,0 +1,200 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/storage"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultMaxAttachmentSize is 10 MiB
+const DefaultMaxAttachmentSize int64 = 10 << 20
+
+// AllowedAttachmentTypes lists content types accepted for upload. The type is
+// sniffed from file content, the one declared by the client is ignored.
+var AllowedAttachmentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"application/pdf",
+	"text/plain",
+	"application/zip",
+	"application/x-gzip",
+}
+
+var (
+	ErrAttachmentDoesNotExist   = errors.New("attachment with given id does not exist")
+	ErrAttachmentTooLarge       = errors.New("attachment exceeds maximum allowed size")
+	ErrAttachmentTypeNotAllowed = errors.New("attachment content type is not allowed")
+)
+
+type AttachmentsService struct {
+	Repo    *repos.AttachmentsRepo
+	Auth    *Authorizer
+	Store   storage.BlobStore
+	MaxSize int64
+}
+
+func NewAttachmentsService(repo *repos.AttachmentsRepo, auth *Authorizer, store storage.BlobStore) *AttachmentsService {
+	return &AttachmentsService{
+		Repo:    repo,
+		Auth:    auth,
+		Store:   store,
+		MaxSize: DefaultMaxAttachmentSize,
+	}
+}
+
+func newBlobKey(taskId int) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate blob key: %w", err)
+	}
+	return fmt.Sprintf("tasks/%d/%s", taskId, hex.EncodeToString(b)), nil
+}
+
+// sniffContentType detects content type of the given file head, dropping
+// media type parameters such as charset.
+func sniffContentType(head []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+func (s *AttachmentsService) Upload(
+	ctx context.Context,
+	workspaceId int,
+	taskId int,
+	fileName string,
+	content io.Reader,
+	size int64,
+	reqUserId int,
+) (models.AttachmentData, error) {
+	if _, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleEditor); err != nil {
+		return models.AttachmentData{}, err
+	}
+	if size > s.MaxSize {
+		return models.AttachmentData{}, ErrAttachmentTooLarge
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return models.AttachmentData{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	head = head[:n]
+
+	contentType := sniffContentType(head)
+	if !slices.Contains(AllowedAttachmentTypes, contentType) {
+		return models.AttachmentData{}, ErrAttachmentTypeNotAllowed
+	}
+
+	key, err := newBlobKey(taskId)
+	if err != nil {
+		return models.AttachmentData{}, err
+	}
+
+	err = s.Store.Put(ctx, key, io.MultiReader(bytes.NewReader(head), content), size, contentType)
+	if err != nil {
+		return models.AttachmentData{}, err
+	}
+
+	attachment, err := s.Repo.Create(ctx, models.AttachmentData{
+		TaskId:      taskId,
+		UserId:      reqUserId,
+		FileName:    filepath.Base(fileName),
+		ContentType: contentType,
+		Size:        size,
+		BlobKey:     key,
+	})
+	if err != nil {
+		s.DeleteBlobs(ctx, []string{key})
+		return models.AttachmentData{}, err
+	}
+	return attachment, nil
+}
+
+func (s *AttachmentsService) ListByTaskId(ctx context.Context, workspaceId int, taskId int, reqUserId int) ([]models.AttachmentData, error) {
+	if _, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.Repo.ListByTaskId(ctx, taskId)
+}
+
+func (s *AttachmentsService) getTaskAttachment(
+	ctx context.Context,
+	workspaceId int,
+	taskId int,
+	attachmentId int,
+	reqUserId int,
+	required string,
+) (models.AttachmentData, error) {
+	if _, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, required); err != nil {
+		return models.AttachmentData{}, err
+	}
+
+	attachment, err := s.Repo.GetById(ctx, taskId, attachmentId)
+	if err == repos.ErrNotFound {
+		return models.AttachmentData{}, ErrAttachmentDoesNotExist
+	}
+	return attachment, err
+}
+
+// Open returns attachment metadata and its content, which must be closed by the caller.
+func (s *AttachmentsService) Open(ctx context.Context, workspaceId int, taskId int, attachmentId int, reqUserId int) (models.AttachmentData, io.ReadCloser, error) {
+	attachment, err := s.getTaskAttachment(ctx, workspaceId, taskId, attachmentId, reqUserId, models.RoleViewer)
+	if err != nil {
+		return models.AttachmentData{}, nil, err
+	}
+
+	content, err := s.Store.Get(ctx, attachment.BlobKey)
+	if err == storage.ErrBlobNotFound {
+		return models.AttachmentData{}, nil, ErrAttachmentDoesNotExist
+	}
+	if err != nil {
+		return models.AttachmentData{}, nil, err
+	}
+	return attachment, content, nil
+}
+
+func (s *AttachmentsService) DeleteById(ctx context.Context, workspaceId int, taskId int, attachmentId int, reqUserId int) error {
+	attachment, err := s.getTaskAttachment(ctx, workspaceId, taskId, attachmentId, reqUserId, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+
+	err = s.Repo.DeleteById(ctx, attachmentId)
+	if err == repos.ErrNotFound {
+		return ErrAttachmentDoesNotExist
+	}
+	if err != nil {
+		return err
+	}
+
+	s.DeleteBlobs(ctx, []string{attachment.BlobKey})
+	return nil
+}
+
+// DeleteBlobs removes content of already deleted attachments. Failures are
+// only logged, as metadata is gone and the blob can't be reached anymore.
+func (s *AttachmentsService) DeleteBlobs(ctx context.Context, blobKeys []string) {
+	for _, key := range blobKeys {
+		if err := s.Store.Delete(ctx, key); err != nil {
+			log.WithFields(log.Fields{"blob_key": key, "err": err}).Error("Failed to delete attachment blob")
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"context"
+	"errors"
+)
+
+var (
+	ErrForbidden           = errors.New("user has no permission for this action")
+	ErrProjectDoesNotExist = errors.New("project with given id does not exist")
+)
+
+// Authorizer resolves the role a user has on tasks and projects. Owners of a
+// task or project are implicit owners, everyone else needs an ACL entry either
+// on the task itself or on the project it belongs to. Assignees of a task are
+// its viewers and may additionally change its status.
+type Authorizer struct {
+	TasksRepo     *repos.TasksRepo
+	ProjectsRepo  *repos.ProjectsRepo
+	ACLRepo       *repos.ACLRepo
+	AssigneesRepo *repos.AssigneesRepo
+}
+
+func NewAuthorizer(
+	tasksRepo *repos.TasksRepo,
+	projectsRepo *repos.ProjectsRepo,
+	aclRepo *repos.ACLRepo,
+	assigneesRepo *repos.AssigneesRepo,
+) *Authorizer {
+	return &Authorizer{TasksRepo: tasksRepo, ProjectsRepo: projectsRepo, ACLRepo: aclRepo, AssigneesRepo: assigneesRepo}
+}
+
+func (a *Authorizer) TaskRole(ctx context.Context, task models.TaskData, userId int) (string, error) {
+	if task.UserId == userId {
+		return models.RoleOwner, nil
+	}
+	return a.ACLRepo.GetTaskRole(ctx, task, userId)
+}
+
+// AuthorizeTask returns the task with given id from the workspace if the user
+// has at least the required role on it.
+func (a *Authorizer) AuthorizeTask(ctx context.Context, workspaceId int, taskId int, userId int, required string) (models.TaskData, error) {
+	taskDb, err := a.TasksRepo.GetById(ctx, workspaceId, taskId)
+	if err == repos.ErrNotFound {
+		return models.TaskData{}, ErrTaskDoesNotExist
+	}
+	if err != nil {
+		return models.TaskData{}, err
+	}
+
+	role, err := a.TaskRole(ctx, taskDb, userId)
+	if err != nil {
+		return models.TaskData{}, err
+	}
+	if !models.RoleAtLeast(role, required) {
+		return models.TaskData{}, ErrForbidden
+	}
+	return taskDb, nil
+}
+
+// AuthorizeTaskOrAssignee is like AuthorizeTask but also lets through users
+// assigned to the task regardless of their role.
+func (a *Authorizer) AuthorizeTaskOrAssignee(ctx context.Context, workspaceId int, taskId int, userId int, required string) (models.TaskData, error) {
+	taskDb, err := a.AuthorizeTask(ctx, workspaceId, taskId, userId, required)
+	if err != ErrForbidden {
+		return taskDb, err
+	}
+
+	assigned, err := a.AssigneesRepo.IsAssigned(ctx, taskId, userId)
+	if err != nil {
+		return models.TaskData{}, err
+	}
+	if !assigned {
+		return models.TaskData{}, ErrForbidden
+	}
+	return a.TasksRepo.GetById(ctx, workspaceId, taskId)
+}
+
+// AuthorizeTrashedTask returns the task with given id from the trash of the
+// workspace if the user deleted it or owns it.
+func (a *Authorizer) AuthorizeTrashedTask(ctx context.Context, workspaceId int, taskId int, userId int) (models.TrashedTask, error) {
+	taskDb, err := a.TasksRepo.GetTrashedById(ctx, workspaceId, taskId)
+	if err == repos.ErrNotFound {
+		return models.TrashedTask{}, ErrTaskDoesNotExist
+	}
+	if err != nil {
+		return models.TrashedTask{}, err
+	}
+	if taskDb.DeletedBy != nil && *taskDb.DeletedBy == userId {
+		return taskDb, nil
+	}
+
+	role, err := a.TaskRole(ctx, taskDb.TaskData, userId)
+	if err != nil {
+		return models.TrashedTask{}, err
+	}
+	if !models.RoleAtLeast(role, models.RoleOwner) {
+		return models.TrashedTask{}, ErrForbidden
+	}
+	return taskDb, nil
+}
+
+// TaskAuthorization is the outcome of authorizing one of many tasks, Err is
+// ErrTaskDoesNotExist or ErrForbidden when the user can't act on the task.
+type TaskAuthorization struct {
+	Id   int
+	Task models.TaskData
+	Err  error
+}
+
+// AuthorizeTasks is AuthorizeTask for many tasks at once, with outcomes in
+// the order of taskIds. With orAssignee set assignees are let through like
+// by AuthorizeTaskOrAssignee.
+func (a *Authorizer) AuthorizeTasks(
+	ctx context.Context,
+	workspaceId int,
+	taskIds []int,
+	userId int,
+	required string,
+	orAssignee bool,
+) ([]TaskAuthorization, error) {
+	tasks, err := a.TasksRepo.GetByIds(ctx, workspaceId, taskIds)
+	if err != nil {
+		return nil, err
+	}
+	access, err := a.ACLRepo.GetTaskAccess(ctx, taskIds, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	tasksById := make(map[int]models.TaskData, len(tasks))
+	for _, task := range tasks {
+		tasksById[task.Id] = task
+	}
+
+	authorizations := make([]TaskAuthorization, len(taskIds))
+	for i, taskId := range taskIds {
+		authorizations[i].Id = taskId
+		task, ok := tasksById[taskId]
+		if !ok {
+			authorizations[i].Err = ErrTaskDoesNotExist
+			continue
+		}
+
+		role := access[taskId].Role
+		if task.UserId == userId {
+			role = models.RoleOwner
+		}
+		if !models.RoleAtLeast(role, required) && !(orAssignee && access[taskId].Assigned) {
+			authorizations[i].Err = ErrForbidden
+			continue
+		}
+		authorizations[i].Task = task
+	}
+	return authorizations, nil
+}
+
+func (a *Authorizer) ProjectRole(ctx context.Context, project models.ProjectData, userId int) (string, error) {
+	if project.UserId == userId {
+		return models.RoleOwner, nil
+	}
+	return a.ACLRepo.GetProjectRole(ctx, project.Id, userId)
+}
+
+// AuthorizeProject returns the project with given id from the workspace if
+// the user has at least the required role on it.
+func (a *Authorizer) AuthorizeProject(ctx context.Context, workspaceId int, projectId int, userId int, required string) (models.ProjectData, error) {
+	projectDb, err := a.ProjectsRepo.GetById(ctx, workspaceId, projectId)
+	if err == repos.ErrNotFound {
+		return models.ProjectData{}, ErrProjectDoesNotExist
+	}
+	if err != nil {
+		return models.ProjectData{}, err
+	}
+
+	role, err := a.ProjectRole(ctx, projectDb, userId)
+	if err != nil {
+		return models.ProjectData{}, err
+	}
+	if !models.RoleAtLeast(role, required) {
+		return models.ProjectData{}, ErrForbidden
+	}
+	return projectDb, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"context"
+	"errors"
+	"strings"
+)
+
+var ErrCommentDoesNotExist = errors.New("comment with given id does not exist")
+
+type CommentsService struct {
+	Repo *repos.CommentsRepo
+	Auth *Authorizer
+}
+
+func NewCommentsService(repo *repos.CommentsRepo, auth *Authorizer) *CommentsService {
+	return &CommentsService{Repo: repo, Auth: auth}
+}
+
+func (s *CommentsService) ListByTaskId(
+	ctx context.Context,
+	workspaceId int,
+	taskId int,
+	pageParams models.PageParams,
+	reqUserId int,
+) (models.Page[models.CommentData], error) {
+	if _, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleViewer); err != nil {
+		return models.Page[models.CommentData]{}, err
+	}
+
+	comments, total, err := s.Repo.ListByTaskId(ctx, taskId, pageParams.Limit(), pageParams.Offset())
+	if err != nil {
+		return models.Page[models.CommentData]{}, err
+	}
+	return models.NewPage(comments, total, pageParams), nil
+}
+
+func (s *CommentsService) Create(ctx context.Context, workspaceId int, taskId int, body string, reqUserId int) (models.CommentData, error) {
+	if _, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleEditor); err != nil {
+		return models.CommentData{}, err
+	}
+	return s.Repo.Create(ctx, taskId, reqUserId, strings.TrimSpace(body))
+}
+
+// getAuthoredComment returns the comment of given task if it was written by
+// reqUserId. Users with the moderatorRole on the task may access any comment.
+func (s *CommentsService) getAuthoredComment(
+	ctx context.Context,
+	workspaceId int,
+	taskId int,
+	commentId int,
+	reqUserId int,
+	moderatorRole string,
+) (models.CommentData, error) {
+	taskDb, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleViewer)
+	if err != nil {
+		return models.CommentData{}, err
+	}
+
+	commentDb, err := s.Repo.GetById(ctx, commentId)
+	if err == repos.ErrNotFound || (err == nil && commentDb.TaskId != taskId) {
+		return models.CommentData{}, ErrCommentDoesNotExist
+	}
+	if err != nil {
+		return models.CommentData{}, err
+	}
+
+	if commentDb.AuthorId == reqUserId {
+		return commentDb, nil
+	}
+	if moderatorRole != "" {
+		role, err := s.Auth.TaskRole(ctx, taskDb, reqUserId)
+		if err != nil {
+			return models.CommentData{}, err
+		}
+		if models.RoleAtLeast(role, moderatorRole) {
+			return commentDb, nil
+		}
+	}
+	return models.CommentData{}, ErrNotOwner
+}
+
+func (s *CommentsService) Edit(ctx context.Context, workspaceId int, taskId int, commentId int, body string, reqUserId int) (models.CommentData, error) {
+	if _, err := s.getAuthoredComment(ctx, workspaceId, taskId, commentId, reqUserId, ""); err != nil {
+		return models.CommentData{}, err
+	}
+
+	comment, err := s.Repo.UpdateBody(ctx, commentId, strings.TrimSpace(body))
+	if err == repos.ErrNotFound {
+		return models.CommentData{}, ErrCommentDoesNotExist
+	}
+	return comment, err
+}
+
+// DeleteById removes a comment written by the user. Task owners may also
+// remove comments of others.
+func (s *CommentsService) DeleteById(ctx context.Context, workspaceId int, taskId int, commentId int, reqUserId int) error {
+	if _, err := s.getAuthoredComment(ctx, workspaceId, taskId, commentId, reqUserId, models.RoleOwner); err != nil {
+		return err
+	}
+
+	err := s.Repo.SoftDeleteById(ctx, commentId)
+	if err == repos.ErrNotFound {
+		return ErrCommentDoesNotExist
+	}
+	return err
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrFieldDoesNotExist      = errors.New("custom field with given id does not exist")
+	ErrFieldAlreadyExists     = errors.New("custom field with such name already exists in the project")
+	ErrFieldValueDoesNotExist = errors.New("custom field is not set on the task")
+)
+
+const fieldSortKeyPrefix = "fields."
+
+// CustomFieldsService manages custom fields project owners define and their
+// values on tasks. A task can only have values of fields of its own project.
+type CustomFieldsService struct {
+	Repo *repos.CustomFieldsRepo
+	Auth *Authorizer
+}
+
+func NewCustomFieldsService(repo *repos.CustomFieldsRepo, auth *Authorizer) *CustomFieldsService {
+	return &CustomFieldsService{Repo: repo, Auth: auth}
+}
+
+func (s *CustomFieldsService) ListByProjectId(ctx context.Context, workspaceId int, projectId int, reqUserId int) ([]models.CustomFieldData, error) {
+	if _, err := s.Auth.AuthorizeProject(ctx, workspaceId, projectId, reqUserId, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.Repo.ListByProjectId(ctx, projectId)
+}
+
+func (s *CustomFieldsService) Create(
+	ctx context.Context,
+	workspaceId int,
+	projectId int,
+	field models.CustomFieldCreate,
+	reqUserId int,
+) (models.CustomFieldData, error) {
+	if _, err := s.Auth.AuthorizeProject(ctx, workspaceId, projectId, reqUserId, models.RoleOwner); err != nil {
+		return models.CustomFieldData{}, err
+	}
+
+	// only select fields have options, and they need at least one
+	isSelect := field.Type == models.FieldTypeSelect || field.Type == models.FieldTypeMultiSelect
+	if isSelect && len(field.Options) == 0 {
+		return models.CustomFieldData{}, &utils.ValidationError{Namespace: "CustomFieldCreate.Options", Field: "Options", Tag: "required"}
+	}
+	if !isSelect && len(field.Options) > 0 {
+		return models.CustomFieldData{}, &utils.ValidationError{Namespace: "CustomFieldCreate.Options", Field: "Options", Tag: "excluded"}
+	}
+
+	fields, err := s.Repo.ListByProjectId(ctx, projectId)
+	if err != nil {
+		return models.CustomFieldData{}, err
+	}
+	for _, f := range fields {
+		if f.Name == field.Name {
+			return models.CustomFieldData{}, ErrFieldAlreadyExists
+		}
+	}
+
+	created, err := s.Repo.Create(ctx, projectId, field)
+	if err != nil {
+		return models.CustomFieldData{}, err
+	}
+
+	log.WithFields(log.Fields{
+		"field_id":   created.Id,
+		"project_id": projectId,
+		"type":       created.Type,
+	}).Info("Custom field created")
+	return created, nil
+}
+
+func (s *CustomFieldsService) DeleteById(ctx context.Context, workspaceId int, projectId int, fieldId int, reqUserId int) error {
+	if _, err := s.Auth.AuthorizeProject(ctx, workspaceId, projectId, reqUserId, models.RoleOwner); err != nil {
+		return err
+	}
+
+	err := s.Repo.DeleteById(ctx, projectId, fieldId)
+	if err == repos.ErrNotFound {
+		return ErrFieldDoesNotExist
+	}
+	return err
+}
+
+func (s *CustomFieldsService) ListValues(ctx context.Context, workspaceId int, taskId int, reqUserId int) ([]models.FieldValueData, error) {
+	if _, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.Repo.ListValues(ctx, taskId)
+}
+
+// getTaskField returns the field if it belongs to the project of the task
+// the user has at least the required role on.
+func (s *CustomFieldsService) getTaskField(
+	ctx context.Context,
+	workspaceId int,
+	taskId int,
+	fieldId int,
+	reqUserId int,
+	required string,
+) (models.CustomFieldData, error) {
+	taskDb, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, required)
+	if err != nil {
+		return models.CustomFieldData{}, err
+	}
+	if taskDb.ProjectId == nil {
+		return models.CustomFieldData{}, ErrFieldDoesNotExist
+	}
+
+	field, err := s.Repo.GetById(ctx, *taskDb.ProjectId, fieldId)
+	if err == repos.ErrNotFound {
+		return models.CustomFieldData{}, ErrFieldDoesNotExist
+	}
+	return field, err
+}
+
+func (s *CustomFieldsService) SetValue(
+	ctx context.Context,
+	workspaceId int,
+	taskId int,
+	fieldId int,
+	value any,
+	reqUserId int,
+) (models.FieldValueData, error) {
+	field, err := s.getTaskField(ctx, workspaceId, taskId, fieldId, reqUserId, models.RoleEditor)
+	if err != nil {
+		return models.FieldValueData{}, err
+	}
+
+	normalized, err := field.NormalizeValue(value)
+	if err != nil {
+		return models.FieldValueData{}, err
+	}
+	if err := s.Repo.SetValue(ctx, workspaceId, taskId, fieldId, normalized, reqUserId); err != nil {
+		return models.FieldValueData{}, err
+	}
+	return models.FieldValueData{FieldId: field.Id, Name: field.Name, Type: field.Type, Value: normalized}, nil
+}
+
+func (s *CustomFieldsService) DeleteValue(ctx context.Context, workspaceId int, taskId int, fieldId int, reqUserId int) error {
+	if _, err := s.getTaskField(ctx, workspaceId, taskId, fieldId, reqUserId, models.RoleEditor); err != nil {
+		return err
+	}
+
+	err := s.Repo.DeleteValue(ctx, workspaceId, taskId, fieldId, reqUserId)
+	if err == repos.ErrNotFound {
+		return ErrFieldValueDoesNotExist
+	}
+	return err
+}
+
+// ResolveFilter resolves custom field conditions and sort keys of the filter
+// against field definitions of the workspace.
+func (s *CustomFieldsService) ResolveFilter(ctx context.Context, workspaceId int, tasksFilter models.TasksFilter) (models.FieldsQuery, error) {
+	var ids []int
+	for key := range tasksFilter.Fields {
+		id, _ := strconv.Atoi(key)
+		ids = append(ids, id)
+	}
+	for _, sortKey := range tasksFilter.SortKeys() {
+		if slices.Contains(models.TaskSortKeys, sortKey.Key) {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(sortKey.Key, fieldSortKeyPrefix))
+		if !strings.HasPrefix(sortKey.Key, fieldSortKeyPrefix) || err != nil {
+			return models.FieldsQuery{}, &utils.ValidationError{Namespace: "TasksFilter.Sort", Field: "Sort", Tag: "sortKey"}
+		}
+		ids = append(ids, id)
+	}
+
+	fieldsById := make(map[string]models.CustomFieldData)
+	if len(ids) > 0 {
+		fields, err := s.Repo.GetByIds(ctx, workspaceId, ids)
+		if err != nil {
+			return models.FieldsQuery{}, err
+		}
+		for _, field := range fields {
+			fieldsById[strconv.Itoa(field.Id)] = field
+		}
+	}
+
+	var fieldsQuery models.FieldsQuery
+	for key, value := range tasksFilter.Fields {
+		field, ok := fieldsById[key]
+		if !ok {
+			namespace := fmt.Sprintf("Fields[%s]", key)
+			return models.FieldsQuery{}, &utils.ValidationError{Namespace: "TasksFilter." + namespace, Field: namespace, Tag: "customField"}
+		}
+		condition, err := field.ParseCondition(value)
+		if err != nil {
+			return models.FieldsQuery{}, err
+		}
+		fieldsQuery.Conditions = append(fieldsQuery.Conditions, condition)
+	}
+	for _, sortKey := range tasksFilter.SortKeys() {
+		order := models.TaskOrder{Key: sortKey.Key, Desc: sortKey.Desc}
+		if !slices.Contains(models.TaskSortKeys, sortKey.Key) {
+			field, ok := fieldsById[strings.TrimPrefix(sortKey.Key, fieldSortKeyPrefix)]
+			if !ok {
+				return models.FieldsQuery{}, &utils.ValidationError{Namespace: "TasksFilter.Sort", Field: "Sort", Tag: "sortKey"}
+			}
+			order.Field = &field
+		}
+		fieldsQuery.Order = append(fieldsQuery.Order, order)
+	}
+	return fieldsQuery, nil
+}
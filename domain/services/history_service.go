@@ -0,0 +1,57 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"context"
+)
+
+// HistoryService exposes the task history, changes of tasks are recorded by
+// repos along with the changes themselves.
+type HistoryService struct {
+	Repo *repos.HistoryRepo
+	Auth *Authorizer
+}
+
+func NewHistoryService(repo *repos.HistoryRepo, auth *Authorizer) *HistoryService {
+	return &HistoryService{Repo: repo, Auth: auth}
+}
+
+func (s *HistoryService) ListByTaskId(
+	ctx context.Context,
+	workspaceId int,
+	taskId int,
+	pageParams models.PageParams,
+	reqUserId int,
+) (models.Page[models.HistoryEntry], error) {
+	if _, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleViewer); err != nil {
+		return models.Page[models.HistoryEntry]{}, err
+	}
+
+	entries, total, err := s.Repo.ListByTaskId(ctx, taskId, pageParams.Limit(), pageParams.Offset())
+	if err != nil {
+		return models.Page[models.HistoryEntry]{}, err
+	}
+	return models.NewPage(entries, total, pageParams), nil
+}
+
+// ListActivity returns changes made by the user of the params, the requesting
+// user by default. Changes of tasks the requesting user can't see are left
+// out.
+func (s *HistoryService) ListActivity(
+	ctx context.Context,
+	workspaceId int,
+	params models.ActivityParams,
+	reqUserId int,
+) (models.Page[models.HistoryEntry], error) {
+	userId := reqUserId
+	if params.UserId != nil {
+		userId = *params.UserId
+	}
+
+	entries, total, err := s.Repo.ListByUserId(ctx, workspaceId, userId, reqUserId, params.Limit(), params.Offset())
+	if err != nil {
+		return models.Page[models.HistoryEntry]{}, err
+	}
+	return models.NewPage(entries, total, params.PageParams), nil
+}
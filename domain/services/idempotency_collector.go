@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const DefaultIdempotencyPollInterval = time.Hour
+
+// IdempotencyCollector periodically deletes expired idempotency keys. Expired
+// keys are taken over by new requests anyway, the collector only keeps the
+// table from growing.
+type IdempotencyCollector struct {
+	Keys         *IdempotencyService
+	PollInterval time.Duration
+}
+
+func NewIdempotencyCollector(keys *IdempotencyService) *IdempotencyCollector {
+	return &IdempotencyCollector{Keys: keys, PollInterval: DefaultIdempotencyPollInterval}
+}
+
+// RunOnce deletes keys expired at the given time and returns how many of
+// them were deleted.
+func (s *IdempotencyCollector) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	return s.Keys.PurgeExpired(ctx, now)
+}
+
+// Run polls for expired keys until ctx is cancelled.
+func (s *IdempotencyCollector) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.RunOnce(ctx, time.Now().UTC())
+		if err != nil {
+			log.WithFields(log.Fields{"err": err}).Error("Failed to purge idempotency keys")
+		} else if purged > 0 {
+			log.WithFields(log.Fields{"purged": purged}).Info("Idempotency keys purged")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"context"
+	"errors"
+	"time"
+)
+
+const DefaultIdempotencyKeyTTL = 24 * time.Hour
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("request with the same idempotency key is in progress")
+)
+
+// IdempotencyService makes retries of mutating requests safe: the response
+// to the first request sent with a key is stored and replayed to later
+// requests of the same user with the same key until the key expires.
+type IdempotencyService struct {
+	Repo *repos.IdempotencyRepo
+	TTL  time.Duration
+}
+
+func NewIdempotencyService(repo *repos.IdempotencyRepo) *IdempotencyService {
+	return &IdempotencyService{Repo: repo, TTL: DefaultIdempotencyKeyTTL}
+}
+
+// Begin claims the key for the request with the given hash. The stored
+// response is returned if the request was already handled, nil if it is
+// up to the caller to handle it and Complete or Release the key.
+func (s *IdempotencyService) Begin(ctx context.Context, userId int, key string, requestHash string) (*models.IdempotentResponse, error) {
+	now := time.Now().UTC()
+	existing, acquired, err := s.Repo.Acquire(ctx, userId, key, requestHash, now, now.Add(s.TTL))
+	if err == repos.ErrNotFound {
+		// expired and purged in between, the retry of the client will claim it
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	if acquired {
+		return nil, nil
+	}
+
+	if existing.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if existing.Status == nil {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	return &models.IdempotentResponse{Status: *existing.Status, Headers: existing.Headers, Body: existing.Body}, nil
+}
+
+// Complete stores the response to the request which claimed the key.
+func (s *IdempotencyService) Complete(ctx context.Context, userId int, key string, response models.IdempotentResponse) error {
+	return s.Repo.SaveResponse(ctx, userId, key, response)
+}
+
+// Release frees the key for a retry of a request which failed without
+// a response worth replaying.
+func (s *IdempotencyService) Release(ctx context.Context, userId int, key string) error {
+	return s.Repo.Delete(ctx, userId, key)
+}
+
+// PurgeExpired deletes keys expired at the given time and returns how many
+// of them there were.
+func (s *IdempotencyService) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	return s.Repo.DeleteExpired(ctx, now)
+}
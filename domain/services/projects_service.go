@@ -0,0 +1,28 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"context"
+)
+
+type ProjectsService struct {
+	Repo *repos.ProjectsRepo
+	Auth *Authorizer
+}
+
+func NewProjectsService(repo *repos.ProjectsRepo, auth *Authorizer) *ProjectsService {
+	return &ProjectsService{Repo: repo, Auth: auth}
+}
+
+func (s *ProjectsService) Create(ctx context.Context, workspaceId int, project models.ProjectCreate, userId int) (models.ProjectData, error) {
+	return s.Repo.Create(ctx, workspaceId, project.Name, userId)
+}
+
+func (s *ProjectsService) ListByUserId(ctx context.Context, workspaceId int, userId int) ([]models.ProjectData, error) {
+	return s.Repo.ListByUserId(ctx, workspaceId, userId)
+}
+
+func (s *ProjectsService) GetById(ctx context.Context, workspaceId int, projectId int, reqUserId int) (models.ProjectData, error) {
+	return s.Auth.AuthorizeProject(ctx, workspaceId, projectId, reqUserId, models.RoleViewer)
+}
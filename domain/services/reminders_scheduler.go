@@ -0,0 +1,108 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultReminderPollInterval = 30 * time.Second
+	DefaultReminderBatchSize    = 100
+	DefaultReminderRetryBackoff = time.Minute
+	DefaultReminderMaxAttempts  = 5
+)
+
+// Notifier delivers a due reminder to the task owner. Reminders are delivered
+// at least once, a Notifier passes idempotencyKey, the same for every attempt
+// of the reminder, on to deduplicate them.
+type Notifier interface {
+	Notify(ctx context.Context, idempotencyKey string, reminder models.DueReminder) error
+}
+
+// reminderIdempotencyKey is the idempotency key of deliveries of the reminder.
+func reminderIdempotencyKey(reminder models.DueReminder) string {
+	return fmt.Sprintf("reminder-%d", reminder.Id)
+}
+
+// LogNotifier is a Notifier which only writes reminders to the application log.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, idempotencyKey string, reminder models.DueReminder) error {
+	log.WithFields(log.Fields{
+		"idempotency_key": idempotencyKey,
+		"reminder_id":     reminder.Id,
+		"task_id":         reminder.TaskId,
+		"task_name":       reminder.TaskName,
+		"user_id":         reminder.UserId,
+		"remind_at":       reminder.RemindAt,
+	}).Info("Task reminder fired")
+	return nil
+}
+
+// RemindersScheduler periodically fires due reminders through a Notifier.
+// It is safe to run one scheduler in every server instance. Reminders the
+// Notifier fails on are retried after RetryBackoff, doubled with every
+// attempt, until MaxAttempts.
+type RemindersScheduler struct {
+	Repo         *repos.RemindersRepo
+	Notifier     Notifier
+	PollInterval time.Duration
+	BatchSize    int
+	RetryBackoff time.Duration
+	MaxAttempts  int
+}
+
+func NewRemindersScheduler(repo *repos.RemindersRepo, notifier Notifier) *RemindersScheduler {
+	return &RemindersScheduler{
+		Repo:         repo,
+		Notifier:     notifier,
+		PollInterval: DefaultReminderPollInterval,
+		BatchSize:    DefaultReminderBatchSize,
+		RetryBackoff: DefaultReminderRetryBackoff,
+		MaxAttempts:  DefaultReminderMaxAttempts,
+	}
+}
+
+// RunOnce fires all reminders which are due at the given time and returns
+// how many of them were delivered.
+func (s *RemindersScheduler) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	total := 0
+	for {
+		sent, err := s.Repo.ProcessDue(ctx, now, s.BatchSize, s.RetryBackoff, s.MaxAttempts, func(reminder models.DueReminder) error {
+			return s.Notifier.Notify(ctx, reminderIdempotencyKey(reminder), reminder)
+		})
+		total += sent
+		if err != nil {
+			return total, err
+		}
+		if sent < s.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+// Run polls for due reminders until ctx is cancelled.
+func (s *RemindersScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := s.RunOnce(ctx, time.Now().UTC())
+		if err != nil {
+			log.WithFields(log.Fields{"err": err, "sent": sent}).Error("Failed to fire reminders")
+		} else if sent > 0 {
+			log.WithFields(log.Fields{"sent": sent}).Info("Reminders fired")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrReminderDoesNotExist = errors.New("reminder with given id does not exist")
+	ErrTaskHasNoDueDate     = errors.New("task has no due date to offset reminder from")
+)
+
+type RemindersService struct {
+	Repo *repos.RemindersRepo
+	Auth *Authorizer
+}
+
+func NewRemindersService(repo *repos.RemindersRepo, auth *Authorizer) *RemindersService {
+	return &RemindersService{Repo: repo, Auth: auth}
+}
+
+func (s *RemindersService) Create(ctx context.Context, workspaceId int, taskId int, reminder models.ReminderCreate, reqUserId int) (models.ReminderData, error) {
+	taskDb, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleEditor)
+	if err != nil {
+		return models.ReminderData{}, err
+	}
+
+	if reminder.RemindAt != nil {
+		return s.Repo.Create(ctx, taskId, reminder.RemindAt.UTC(), nil)
+	}
+
+	if taskDb.DueDate == nil {
+		return models.ReminderData{}, ErrTaskHasNoDueDate
+	}
+	remindAt := taskDb.DueDate.Add(-time.Duration(*reminder.OffsetMinutes) * time.Minute)
+	return s.Repo.Create(ctx, taskId, remindAt, reminder.OffsetMinutes)
+}
+
+func (s *RemindersService) ListByTaskId(ctx context.Context, workspaceId int, taskId int, reqUserId int) ([]models.ReminderData, error) {
+	if _, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.Repo.ListByTaskId(ctx, taskId)
+}
+
+func (s *RemindersService) DeleteById(ctx context.Context, workspaceId int, taskId int, reminderId int, reqUserId int) error {
+	if _, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleEditor); err != nil {
+		return err
+	}
+
+	err := s.Repo.DeleteById(ctx, taskId, reminderId)
+	if err == repos.ErrNotFound {
+		return ErrReminderDoesNotExist
+	}
+	return err
+}
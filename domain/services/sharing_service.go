@@ -0,0 +1,138 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/utils"
+	"context"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvitationDoesNotExist = errors.New("invitation with given id does not exist")
+	ErrShareDoesNotExist      = errors.New("user has no share on this item")
+	ErrCannotInviteSelf       = errors.New("user can't invite themselves")
+	ErrNotWorkspaceMember     = errors.New("user with given email is not a member of the workspace")
+)
+
+// SharingService manages who besides the owner can access a task or a project.
+// Access is granted through invitations, which become ACL entries once
+// accepted by the user with the invited email. Only members of the workspace
+// the item belongs to can be invited.
+type SharingService struct {
+	ACLRepo        *repos.ACLRepo
+	WorkspacesRepo *repos.WorkspacesRepo
+	Auth           *Authorizer
+}
+
+func NewSharingService(aclRepo *repos.ACLRepo, workspacesRepo *repos.WorkspacesRepo, auth *Authorizer) *SharingService {
+	return &SharingService{ACLRepo: aclRepo, WorkspacesRepo: workspacesRepo, Auth: auth}
+}
+
+// authorizeOwner checks the user may manage shares of the target.
+func (s *SharingService) authorizeOwner(ctx context.Context, workspaceId int, target models.ShareTarget, reqUserId int) error {
+	if target.TaskId != nil {
+		_, err := s.Auth.AuthorizeTask(ctx, workspaceId, *target.TaskId, reqUserId, models.RoleOwner)
+		return err
+	}
+	_, err := s.Auth.AuthorizeProject(ctx, workspaceId, *target.ProjectId, reqUserId, models.RoleOwner)
+	return err
+}
+
+func (s *SharingService) Invite(ctx context.Context, workspaceId int, target models.ShareTarget, share models.ShareCreate, reqUser models.UserData) (models.InvitationData, error) {
+	if err := s.authorizeOwner(ctx, workspaceId, target, reqUser.Id); err != nil {
+		return models.InvitationData{}, err
+	}
+	share.Email = utils.NormalizeEmail(share.Email)
+	if share.Email == utils.NormalizeEmail(reqUser.Email) {
+		return models.InvitationData{}, ErrCannotInviteSelf
+	}
+	isMember, err := s.WorkspacesRepo.IsMemberEmail(ctx, workspaceId, share.Email)
+	if err != nil {
+		return models.InvitationData{}, err
+	}
+	if !isMember {
+		return models.InvitationData{}, ErrNotWorkspaceMember
+	}
+
+	invitation, err := s.ACLRepo.CreateInvitation(ctx, target, share.Email, share.Role, reqUser.Id)
+	if err != nil {
+		return models.InvitationData{}, err
+	}
+
+	log.WithFields(log.Fields{
+		"invitation_id": invitation.Id,
+		"email":         invitation.Email,
+		"role":          invitation.Role,
+	}).Info("Share invitation created")
+	return invitation, nil
+}
+
+func (s *SharingService) ListShares(ctx context.Context, workspaceId int, target models.ShareTarget, reqUserId int) (models.SharesList, error) {
+	if err := s.authorizeOwner(ctx, workspaceId, target, reqUserId); err != nil {
+		return models.SharesList{}, err
+	}
+
+	members, err := s.ACLRepo.ListShares(ctx, target)
+	if err != nil {
+		return models.SharesList{}, err
+	}
+	invitations, err := s.ACLRepo.ListPendingInvitations(ctx, &target, nil)
+	if err != nil {
+		return models.SharesList{}, err
+	}
+	return models.SharesList{Members: members, Invitations: invitations}, nil
+}
+
+func (s *SharingService) Revoke(ctx context.Context, workspaceId int, target models.ShareTarget, userId int, reqUserId int) error {
+	if err := s.authorizeOwner(ctx, workspaceId, target, reqUserId); err != nil {
+		return err
+	}
+
+	err := s.ACLRepo.DeleteShare(ctx, target, userId)
+	if err == repos.ErrNotFound {
+		return ErrShareDoesNotExist
+	}
+	return err
+}
+
+func (s *SharingService) ListInvitations(ctx context.Context, reqUser models.UserData) ([]models.InvitationData, error) {
+	email := utils.NormalizeEmail(reqUser.Email)
+	return s.ACLRepo.ListPendingInvitations(ctx, nil, &email)
+}
+
+// getOwnInvitation returns a pending invitation addressed to the user's email.
+func (s *SharingService) getOwnInvitation(ctx context.Context, invitationId int, reqUser models.UserData) (models.InvitationData, error) {
+	invitation, err := s.ACLRepo.GetPendingInvitation(ctx, invitationId)
+	if err == repos.ErrNotFound || (err == nil && invitation.Email != utils.NormalizeEmail(reqUser.Email)) {
+		return models.InvitationData{}, ErrInvitationDoesNotExist
+	}
+	return invitation, err
+}
+
+func (s *SharingService) AcceptInvitation(ctx context.Context, invitationId int, reqUser models.UserData) error {
+	invitation, err := s.getOwnInvitation(ctx, invitationId, reqUser)
+	if err != nil {
+		return err
+	}
+
+	err = s.ACLRepo.AcceptInvitation(ctx, invitation, reqUser.Id)
+	if err == repos.ErrNotFound {
+		return ErrInvitationDoesNotExist
+	}
+	return err
+}
+
+func (s *SharingService) DeclineInvitation(ctx context.Context, invitationId int, reqUser models.UserData) error {
+	if _, err := s.getOwnInvitation(ctx, invitationId, reqUser); err != nil {
+		return err
+	}
+
+	err := s.ACLRepo.DeleteInvitation(ctx, invitationId)
+	if err == repos.ErrNotFound {
+		return ErrInvitationDoesNotExist
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const DefaultTaskEventLogPollInterval = time.Hour
+
+// TaskEventLogCollector periodically deletes task events logged longer than
+// the retention of the hub.
+type TaskEventLogCollector struct {
+	Events       *TaskEventsHub
+	PollInterval time.Duration
+}
+
+func NewTaskEventLogCollector(events *TaskEventsHub) *TaskEventLogCollector {
+	return &TaskEventLogCollector{Events: events, PollInterval: DefaultTaskEventLogPollInterval}
+}
+
+// RunOnce deletes events out of retention at the given time and returns how
+// many of them were deleted.
+func (s *TaskEventLogCollector) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	return s.Events.PurgeLog(ctx, now)
+}
+
+// Run polls for events out of retention until ctx is cancelled.
+func (s *TaskEventLogCollector) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.RunOnce(ctx, time.Now().UTC())
+		if err != nil {
+			log.WithFields(log.Fields{"err": err}).Error("Failed to purge task event log")
+		} else if purged > 0 {
+			log.WithFields(log.Fields{"purged": purged}).Info("Task event log purged")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrTaskEventsMissed = errors.New("task events since given id are no longer kept")
+
+const (
+	DefaultTaskEventsRetryInterval = 5 * time.Second
+	// DefaultTaskEventRetention is how long logged task events can be
+	// replayed.
+	DefaultTaskEventRetention = 24 * time.Hour
+	// MaxReplayedTaskEvents is how many events Since replays at most, clients
+	// further behind start over.
+	MaxReplayedTaskEvents = 1000
+	// taskEventsBuffer is how many events a subscriber can fall behind
+	// before it's dropped.
+	taskEventsBuffer = 256
+)
+
+// TaskSubscription receives events of tasks in a single workspace. Events is
+// closed when the subscriber falls too far behind, after which it has to
+// catch up some other way.
+type TaskSubscription struct {
+	Events      <-chan models.TaskEvent
+	events      chan models.TaskEvent
+	workspaceId int
+}
+
+// TaskEventsHub passes task events announced by the database to subscribers
+// of the workspace of the task, so changes made through any server instance
+// reach all of them. Events announced while the hub reconnects to the
+// database are lost to subscribers, though they can be replayed from the log
+// for Retention.
+type TaskEventsHub struct {
+	Repo          *repos.TaskEventsRepo
+	RetryInterval time.Duration
+	Retention     time.Duration
+
+	mu          sync.Mutex
+	subscribers map[int]map[*TaskSubscription]struct{}
+	ready       chan struct{}
+	readyOnce   sync.Once
+}
+
+func NewTaskEventsHub(repo *repos.TaskEventsRepo) *TaskEventsHub {
+	return &TaskEventsHub{
+		Repo:          repo,
+		RetryInterval: DefaultTaskEventsRetryInterval,
+		Retention:     DefaultTaskEventRetention,
+		subscribers:   make(map[int]map[*TaskSubscription]struct{}),
+		ready:         make(chan struct{}),
+	}
+}
+
+// Ready is closed once the hub listens for task events for the first time.
+func (h *TaskEventsHub) Ready() <-chan struct{} {
+	return h.ready
+}
+
+func (h *TaskEventsHub) Subscribe(workspaceId int) *TaskSubscription {
+	events := make(chan models.TaskEvent, taskEventsBuffer)
+	sub := &TaskSubscription{Events: events, events: events, workspaceId: workspaceId}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[workspaceId] == nil {
+		h.subscribers[workspaceId] = make(map[*TaskSubscription]struct{})
+	}
+	h.subscribers[workspaceId][sub] = struct{}{}
+	return sub
+}
+
+func (h *TaskEventsHub) Unsubscribe(sub *TaskSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+// remove drops the subscription and closes its events, h.mu must be held.
+func (h *TaskEventsHub) remove(sub *TaskSubscription) {
+	subs := h.subscribers[sub.workspaceId]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, sub.workspaceId)
+	}
+	close(sub.events)
+}
+
+func (h *TaskEventsHub) publish(event models.TaskEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers[event.WorkspaceId] {
+		select {
+		case sub.events <- event:
+		default:
+			log.WithFields(log.Fields{"workspace_id": event.WorkspaceId}).Warn("Task events subscriber fell behind")
+			h.remove(sub)
+		}
+	}
+}
+
+// Run listens for task events until ctx is cancelled, reconnecting whenever
+// the connection fails.
+func (h *TaskEventsHub) Run(ctx context.Context) {
+	listening := func() {
+		h.readyOnce.Do(func() { close(h.ready) })
+		log.Info("Listening for task events")
+	}
+	for {
+		err := h.Repo.Listen(ctx, listening, h.publish)
+		if ctx.Err() != nil {
+			return
+		}
+		log.WithFields(log.Fields{"err": err}).Error("Lost connection for task events")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(h.RetryInterval):
+		}
+	}
+}
+
+// LastId returns the id of the newest event logged in the workspace, events
+// after it can be replayed with Since. Ids are numbered per workspace.
+func (h *TaskEventsHub) LastId(ctx context.Context, workspaceId int) (int64, error) {
+	_, newest, err := h.Repo.IdRange(ctx, workspaceId)
+	return newest, err
+}
+
+// Since returns events of the workspace logged after the event with the
+// given id, ErrTaskEventsMissed if some of them are no longer kept or there
+// are more than MaxReplayedTaskEvents of them.
+func (h *TaskEventsHub) Since(ctx context.Context, workspaceId int, afterId int64) ([]models.TaskEvent, error) {
+	oldest, _, err := h.Repo.IdRange(ctx, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+	if afterId < oldest-1 {
+		return nil, ErrTaskEventsMissed
+	}
+
+	events, err := h.Repo.ListSince(ctx, workspaceId, afterId, MaxReplayedTaskEvents+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > MaxReplayedTaskEvents {
+		return nil, ErrTaskEventsMissed
+	}
+	return events, nil
+}
+
+// PurgeLog deletes events logged longer than Retention before the given time
+// and returns how many of them there were.
+func (h *TaskEventsHub) PurgeLog(ctx context.Context, now time.Time) (int, error) {
+	return h.Repo.DeleteBefore(ctx, now.Add(-h.Retention))
+}
@@ -2,63 +2,600 @@ package services
 
 import (
 	"api-server/domain/models"
+	"api-server/domain/query"
 	"api-server/domain/repos"
+	"api-server/utils"
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 var (
 	ErrTaskDoesNotExist = errors.New("task with given id does not exist")
 	ErrNotOwner         = errors.New("user is not owner of this item")
+	ErrTooManyTasks     = fmt.Errorf("bulk operations apply to at most %d tasks", models.MaxBulkTasks)
+	ErrVersionMismatch  = errors.New("task was changed since given version")
 )
 
+// DefaultTrashRetentionDays is how long deleted tasks stay in the trash.
+const DefaultTrashRetentionDays = 30
+
 type TasksService struct {
-	Repo *repos.TasksRepo
+	Repo        *repos.TasksRepo
+	Auth        *Authorizer
+	Attachments *AttachmentsService
+	Workflows   *WorkflowsService
+	Fields      *CustomFieldsService
+	Cursors     *utils.CursorCodec
+	Undo        *UndoService
+	// TrashRetention is how long deleted tasks stay in the trash before
+	// they're purged for good.
+	TrashRetention time.Duration
 }
 
-func NewTasksService(repo *repos.TasksRepo) *TasksService {
-	return &TasksService{Repo: repo}
+func NewTasksService(
+	repo *repos.TasksRepo,
+	auth *Authorizer,
+	attachments *AttachmentsService,
+	workflows *WorkflowsService,
+	fields *CustomFieldsService,
+	cursors *utils.CursorCodec,
+	undo *UndoService,
+) *TasksService {
+	return &TasksService{
+		Repo:           repo,
+		Auth:           auth,
+		Attachments:    attachments,
+		Workflows:      workflows,
+		Fields:         fields,
+		Cursors:        cursors,
+		Undo:           undo,
+		TrashRetention: DefaultTrashRetentionDays * 24 * time.Hour,
+	}
 }
 
-func (s *TasksService) Create(ctx context.Context, task models.TaskCreate, userId int) (models.TaskData, error) {
-	return s.Repo.Create(ctx, task.Name, task.DueDate, userId)
+func (s *TasksService) Create(ctx context.Context, workspaceId int, task models.TaskCreate, userId int) (models.TaskData, error) {
+	if task.ProjectId != nil {
+		if _, err := s.Auth.AuthorizeProject(ctx, workspaceId, *task.ProjectId, userId, models.RoleEditor); err != nil {
+			return models.TaskData{}, err
+		}
+	}
+
+	// due dates are stored in UTC, the database drops offsets of timestamps
+	if task.DueDate != nil {
+		dueDate := task.DueDate.UTC()
+		task.DueDate = &dueDate
+	}
+
+	// new tasks start in the first status of their workflow
+	workflow, err := s.Workflows.WorkflowOf(ctx, task.ProjectId)
+	if err != nil {
+		return models.TaskData{}, err
+	}
+	return s.Repo.Create(ctx, workspaceId, task, workflow.InitialStatus(), userId)
+}
+
+// resolveQuery validates the filter against the workspace and resolves it
+// into the query of the repo, with relative days taken in loc.
+func (s *TasksService) resolveQuery(ctx context.Context, workspaceId int, loc *time.Location, tasksFilter models.TasksFilter) (repos.TasksQuery, error) {
+	if tasksFilter.Status != nil {
+		exists, err := s.Workflows.Repo.StatusExists(ctx, workspaceId, *tasksFilter.Status)
+		if err != nil {
+			return repos.TasksQuery{}, err
+		}
+		if !exists {
+			return repos.TasksQuery{}, ErrInvalidStatus
+		}
+	}
+
+	fieldsQuery, err := s.Fields.ResolveFilter(ctx, workspaceId, tasksFilter)
+	if err != nil {
+		return repos.TasksQuery{}, err
+	}
+
+	tasksQuery := repos.TasksQuery{Filter: tasksFilter, Fields: fieldsQuery, Now: time.Now().In(loc)}
+	if tasksFilter.Expr != nil {
+		tasksQuery.Expr, err = query.Parse(*tasksFilter.Expr)
+		if err != nil {
+			return repos.TasksQuery{}, err
+		}
+	}
+	return tasksQuery, nil
+}
+
+// ValidateFilter checks the filter the same way task listings do, for
+// filters stored to be listed later.
+func (s *TasksService) ValidateFilter(ctx context.Context, workspaceId int, tasksFilter models.TasksFilter) error {
+	_, err := s.resolveQuery(ctx, workspaceId, time.UTC, tasksFilter)
+	return err
 }
 
 func (s *TasksService) ListByUserId(
 	ctx context.Context,
+	workspaceId int,
 	userId int,
+	loc *time.Location,
 	tasksFilter models.TasksFilter,
-) ([]models.TaskData, error) {
-	return s.Repo.ListByUserId(ctx, userId, tasksFilter)
+) (models.CursorPage[models.TaskListItem], error) {
+	tasksQuery, err := s.resolveQuery(ctx, workspaceId, loc, tasksFilter)
+	if err != nil {
+		return models.CursorPage[models.TaskListItem]{}, err
+	}
+
+	var cursor *models.TasksCursor
+	if tasksFilter.Cursor != nil {
+		cursor = &models.TasksCursor{}
+		err := s.Cursors.Decode(*tasksFilter.Cursor, cursor)
+		if err != nil || cursor.Sort != tasksFilter.SortString() {
+			return models.CursorPage[models.TaskListItem]{}, &utils.ValidationError{Namespace: "TasksFilter.Cursor", Field: "Cursor", Tag: "cursor"}
+		}
+	}
+
+	slice, err := s.Repo.ListByUserId(ctx, workspaceId, userId, tasksQuery, cursor, tasksFilter.PageLimit())
+	if err != nil {
+		return models.CursorPage[models.TaskListItem]{}, err
+	}
+
+	page := models.CursorPage[models.TaskListItem]{Items: slice.Items}
+	if slice.HasAfter && slice.LastKey != nil {
+		next, err := s.Cursors.Encode(models.TasksCursor{Key: slice.LastKey, Sort: tasksFilter.SortString()})
+		if err != nil {
+			return models.CursorPage[models.TaskListItem]{}, err
+		}
+		page.Next = &next
+	}
+	if slice.HasBefore && slice.FirstKey != nil {
+		prev, err := s.Cursors.Encode(models.TasksCursor{Key: slice.FirstKey, Sort: tasksFilter.SortString(), Backward: true})
+		if err != nil {
+			return models.CursorPage[models.TaskListItem]{}, err
+		}
+		page.Prev = &prev
+	}
+	return page, nil
 }
 
-func (s *TasksService) DeleteById(ctx context.Context, taskId int, reqUserId int) error {
-	taskDb, err := s.Repo.GetById(ctx, taskId)
-	if err == repos.ErrNotFound {
+// FilterTasks returns those of the tasks with given ids the user can see and
+// the filter matches, ignoring its paging.
+func (s *TasksService) FilterTasks(
+	ctx context.Context,
+	workspaceId int,
+	userId int,
+	loc *time.Location,
+	tasksFilter models.TasksFilter,
+	taskIds []int,
+) ([]models.TaskListItem, error) {
+	tasksQuery, err := s.resolveQuery(ctx, workspaceId, loc, tasksFilter)
+	if err != nil {
+		return nil, err
+	}
+	tasksQuery.Ids = taskIds
+
+	slice, err := s.Repo.ListByUserId(ctx, workspaceId, userId, tasksQuery, nil, len(taskIds))
+	if err != nil {
+		return nil, err
+	}
+	return slice.Items, nil
+}
+
+// VisibleTaskIds returns those of the tasks with given ids the user can see,
+// in the trash or not.
+func (s *TasksService) VisibleTaskIds(ctx context.Context, workspaceId int, userId int, taskIds []int) ([]int, error) {
+	return s.Repo.ListVisibleIds(ctx, workspaceId, userId, taskIds)
+}
+
+func (s *TasksService) GetById(ctx context.Context, workspaceId int, taskId int, reqUserId int) (models.TaskData, error) {
+	return s.Auth.AuthorizeTaskOrAssignee(ctx, workspaceId, taskId, reqUserId, models.RoleViewer)
+}
+
+// versionError translates errors of conditional updates of the task.
+func versionError(err error) error {
+	switch err {
+	case repos.ErrNotFound:
 		return ErrTaskDoesNotExist
+	case repos.ErrVersionMismatch:
+		return ErrVersionMismatch
+	}
+	return err
+}
+
+// pushUndo puts steps reversing the operation on the undo stack of the user
+// and returns the undo token. The operation is done by then, so failures are
+// only logged and leave it without a token.
+func (s *TasksService) pushUndo(ctx context.Context, workspaceId int, userId int, action string, steps []models.UndoStep) string {
+	if len(steps) == 0 {
+		return ""
 	}
+	token, err := s.Undo.Push(ctx, workspaceId, userId, action, steps)
 	if err != nil {
-		return err
+		log.WithFields(log.Fields{"action": action, "err": err}).Error("Failed to push undo entry")
+		return ""
 	}
+	return token
+}
 
-	if taskDb.UserId != reqUserId {
-		return ErrNotOwner
+// DeleteById moves the task to the trash and returns the undo token. It can
+// be restored until the trash retention passes. With version set the task
+// is only deleted if it's still in that version.
+func (s *TasksService) DeleteById(ctx context.Context, workspaceId int, taskId int, version *int, reqUserId int) (string, error) {
+	if _, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleOwner); err != nil {
+		return "", err
 	}
-	return s.Repo.DeleteById(ctx, taskId)
+	trashed, err := s.Repo.Trash(ctx, workspaceId, taskId, version, reqUserId)
+	if err != nil {
+		return "", versionError(err)
+	}
+
+	steps := []models.UndoStep{{TaskId: taskId, Version: trashed.Version, Restore: true}}
+	return s.pushUndo(ctx, workspaceId, reqUserId, models.BulkDelete, steps), nil
 }
 
-func (s *TasksService) UpdateStatus(ctx context.Context, taskId int, newStatus string, reqUserId int) (models.TaskData, error) {
-	taskDb, err := s.Repo.GetById(ctx, taskId)
-	if err == repos.ErrNotFound {
-		return models.TaskData{}, ErrTaskDoesNotExist
+// UpdateStatus moves the task to newStatus if the workflow of its project
+// allows the transition and returns the undo token along with the task.
+// Setting the current status again is a no-op without a token. With version
+// set the task is only updated if it's still in that version.
+func (s *TasksService) UpdateStatus(
+	ctx context.Context,
+	workspaceId int,
+	taskId int,
+	newStatus string,
+	version *int,
+	reqUserId int,
+) (models.TaskData, string, error) {
+	taskDb, err := s.Auth.AuthorizeTaskOrAssignee(ctx, workspaceId, taskId, reqUserId, models.RoleEditor)
+	if err != nil {
+		return models.TaskData{}, "", err
+	}
+	if version != nil && taskDb.Version != *version {
+		return models.TaskData{}, "", ErrVersionMismatch
+	}
+
+	workflow, err := s.Workflows.WorkflowOf(ctx, taskDb.ProjectId)
+	if err != nil {
+		return models.TaskData{}, "", err
+	}
+	if !workflow.HasStatus(newStatus) {
+		return models.TaskData{}, "", ErrInvalidStatus
+	}
+	if taskDb.Status == newStatus {
+		return taskDb, "", nil
+	}
+	if !workflow.CanTransition(taskDb.Status, newStatus) {
+		return models.TaskData{}, "", ErrTransitionNotAllowed
+	}
+	// the transition was only checked from the status read above
+	task, err := s.Repo.UpdateStatus(ctx, workspaceId, taskId, taskDb.Status, newStatus, version, reqUserId)
+	if err != nil {
+		return models.TaskData{}, "", versionError(err)
+	}
+
+	steps := []models.UndoStep{{TaskId: taskId, Version: task.Version, Status: &taskDb.Status}}
+	return task, s.pushUndo(ctx, workspaceId, reqUserId, models.BulkSetStatus, steps), nil
+}
+
+// Edit replaces editable fields of the task. Moving the task to another
+// project or out of it requires the owner role as with bulk moves, the task
+// keeps its status if the new workflow has it. With version set the task is
+// only updated if it's still in that version.
+func (s *TasksService) Edit(
+	ctx context.Context,
+	workspaceId int,
+	taskId int,
+	edit models.TaskEdit,
+	version *int,
+	reqUserId int,
+) (models.TaskData, error) {
+	taskDb, err := s.Auth.AuthorizeTask(ctx, workspaceId, taskId, reqUserId, models.RoleEditor)
+	if err != nil {
+		return models.TaskData{}, err
 	}
+	if version != nil && taskDb.Version != *version {
+		return models.TaskData{}, ErrVersionMismatch
+	}
+
+	projectId := taskDb.ProjectId
+	if edit.RemoveProject {
+		projectId = nil
+	} else if edit.ProjectId != nil {
+		projectId = edit.ProjectId
+	}
+	edit.ProjectId = projectId
+
+	status := taskDb.Status
+	move := (projectId == nil) != (taskDb.ProjectId == nil) ||
+		projectId != nil && *projectId != *taskDb.ProjectId
+	if move {
+		role, err := s.Auth.TaskRole(ctx, taskDb, reqUserId)
+		if err != nil {
+			return models.TaskData{}, err
+		}
+		if !models.RoleAtLeast(role, models.RoleOwner) {
+			return models.TaskData{}, ErrForbidden
+		}
+		if edit.ProjectId != nil {
+			if _, err := s.Auth.AuthorizeProject(ctx, workspaceId, *edit.ProjectId, reqUserId, models.RoleEditor); err != nil {
+				return models.TaskData{}, err
+			}
+		}
+
+		workflow, err := s.Workflows.WorkflowOf(ctx, edit.ProjectId)
+		if err != nil {
+			return models.TaskData{}, err
+		}
+		if !workflow.HasStatus(status) {
+			status = workflow.InitialStatus()
+		}
+	}
+
+	// due dates are stored in UTC, the database drops offsets of timestamps
+	if edit.DueDate != nil {
+		dueDate := edit.DueDate.UTC()
+		edit.DueDate = &dueDate
+	}
+
+	// the status is only changed if the task is still in the status read above
+	task, err := s.Repo.Edit(ctx, workspaceId, taskId, edit, move, taskDb.Status, status, version, reqUserId)
+	if err != nil {
+		return models.TaskData{}, versionError(err)
+	}
+	return task, nil
+}
+
+// bulkRoles are roles bulk actions require, status changes are also open to
+// assignees as with UpdateStatus.
+var bulkRoles = map[string]string{
+	models.BulkSetStatus:  models.RoleEditor,
+	models.BulkDelete:     models.RoleOwner,
+	models.BulkMove:       models.RoleOwner,
+	models.BulkEditLabels: models.RoleEditor,
+}
+
+// Bulk applies the action of the request to all tasks it targets in a single
+// transaction. Tasks the action doesn't apply to are reported and skipped,
+// in a dry run nothing changes at all. The result carries the undo token of
+// the changes.
+func (s *TasksService) Bulk(
+	ctx context.Context,
+	workspaceId int,
+	reqUserId int,
+	loc *time.Location,
+	req models.BulkTasksRequest,
+) (models.BulkTasksResult, error) {
+	taskIds := req.Ids
+	if req.Filter != nil {
+		tasksQuery, err := s.resolveQuery(ctx, workspaceId, loc, *req.Filter)
+		if err != nil {
+			return models.BulkTasksResult{}, err
+		}
+		taskIds, err = s.Repo.ListIdsByUserId(ctx, workspaceId, reqUserId, tasksQuery, models.MaxBulkTasks+1)
+		if err != nil {
+			return models.BulkTasksResult{}, err
+		}
+		if len(taskIds) > models.MaxBulkTasks {
+			return models.BulkTasksResult{}, ErrTooManyTasks
+		}
+	}
+
+	if req.Action == models.BulkEditLabels && len(req.AddLabels) == 0 && len(req.RemoveLabels) == 0 {
+		return models.BulkTasksResult{}, &utils.ValidationError{Namespace: "BulkTasksRequest.AddLabels", Field: "AddLabels", Tag: "required_without"}
+	}
+	if req.Action == models.BulkMove && req.ProjectId != nil {
+		if _, err := s.Auth.AuthorizeProject(ctx, workspaceId, *req.ProjectId, reqUserId, models.RoleEditor); err != nil {
+			return models.BulkTasksResult{}, err
+		}
+	}
+
+	authorizations, err := s.Auth.AuthorizeTasks(ctx, workspaceId, taskIds, reqUserId, bulkRoles[req.Action], req.Action == models.BulkSetStatus)
+	if err != nil {
+		return models.BulkTasksResult{}, err
+	}
+
+	result := models.BulkTasksResult{DryRun: req.DryRun, Results: make([]models.BulkTaskResult, len(authorizations))}
+	var changes []models.TaskChange
+	workflows := make(map[int]models.WorkflowData)
+	for i, auth := range authorizations {
+		result.Results[i] = models.BulkTaskResult{Id: auth.Id, Result: models.BulkResultOk}
+		switch auth.Err {
+		case nil:
+		case ErrTaskDoesNotExist:
+			result.Results[i].Result = models.BulkResultNotFound
+			continue
+		case ErrForbidden:
+			result.Results[i].Result = models.BulkResultForbidden
+			continue
+		default:
+			return models.BulkTasksResult{}, auth.Err
+		}
+
+		change, err := s.bulkChange(ctx, auth.Task, req, workflows)
+		if err == ErrInvalidStatus || err == ErrTransitionNotAllowed {
+			result.Results[i].Result = models.BulkResultInvalid
+			result.Results[i].Error = err.Error()
+			continue
+		}
+		if err != nil {
+			return models.BulkTasksResult{}, err
+		}
+		changes = append(changes, change)
+	}
+	if req.DryRun || len(changes) == 0 {
+		return result, nil
+	}
+
+	applied, skipped, err := s.Repo.ApplyChanges(ctx, workspaceId, reqUserId, changes)
+	if err != nil {
+		return models.BulkTasksResult{}, err
+	}
+	// tasks changed or trashed since they were authorized
+	for i := range result.Results {
+		switch skipped[result.Results[i].Id] {
+		case repos.ErrNotFound:
+			result.Results[i].Result = models.BulkResultNotFound
+		case repos.ErrVersionMismatch:
+			result.Results[i].Result = models.BulkResultConflict
+			result.Results[i].Error = ErrVersionMismatch.Error()
+		}
+	}
+	steps := make([]models.UndoStep, len(applied))
+	for i, change := range applied {
+		steps[i] = undoStep(change)
+	}
+	result.UndoToken = s.pushUndo(ctx, workspaceId, reqUserId, req.Action, steps)
+	return result, nil
+}
+
+// undoStep reverses the change a bulk operation applied.
+func undoStep(change models.AppliedChange) models.UndoStep {
+	step := models.UndoStep{TaskId: change.Id, Version: change.Version, Restore: change.Delete}
+	if change.Status != nil {
+		step.Status = &change.Old.Status
+	}
+	if change.Labels != nil {
+		step.Labels = change.Old.Labels
+		if step.Labels == nil {
+			step.Labels = []string{}
+		}
+	}
+	if change.Move {
+		step.Move, step.ProjectId = true, change.Old.ProjectId
+	}
+	return step
+}
+
+// bulkChange is the change the bulk request makes to the task. Workflows
+// of projects are cached in workflows by project id, 0 stands for tasks
+// outside of projects.
+func (s *TasksService) bulkChange(
+	ctx context.Context,
+	task models.TaskData,
+	req models.BulkTasksRequest,
+	workflows map[int]models.WorkflowData,
+) (models.TaskChange, error) {
+	workflowOf := func(projectId *int) (models.WorkflowData, error) {
+		key := 0
+		if projectId != nil {
+			key = *projectId
+		}
+		if workflow, ok := workflows[key]; ok {
+			return workflow, nil
+		}
+		workflow, err := s.Workflows.WorkflowOf(ctx, projectId)
+		if err != nil {
+			return models.WorkflowData{}, err
+		}
+		workflows[key] = workflow
+		return workflow, nil
+	}
+
+	change := models.TaskChange{Id: task.Id, BaseVersion: task.Version}
+	switch req.Action {
+	case models.BulkDelete:
+		change.Delete = true
+
+	case models.BulkSetStatus:
+		workflow, err := workflowOf(task.ProjectId)
+		if err != nil {
+			return models.TaskChange{}, err
+		}
+		if !workflow.HasStatus(*req.Status) {
+			return models.TaskChange{}, ErrInvalidStatus
+		}
+		if task.Status != *req.Status {
+			if !workflow.CanTransition(task.Status, *req.Status) {
+				return models.TaskChange{}, ErrTransitionNotAllowed
+			}
+			change.Status = req.Status
+		}
+
+	case models.BulkMove:
+		change.Move, change.ProjectId = true, req.ProjectId
+		// tasks keep their status if the new workflow has it
+		workflow, err := workflowOf(req.ProjectId)
+		if err != nil {
+			return models.TaskChange{}, err
+		}
+		if !workflow.HasStatus(task.Status) {
+			status := workflow.InitialStatus()
+			change.Status = &status
+		}
+
+	case models.BulkEditLabels:
+		labels := []string{}
+		for _, label := range slices.Concat(task.Labels, req.AddLabels) {
+			if !slices.Contains(labels, label) && !slices.Contains(req.RemoveLabels, label) {
+				labels = append(labels, label)
+			}
+		}
+		change.Labels = labels
+	}
+	return change, nil
+}
+
+// ListTrash returns tasks in the trash the user owns or deleted, along with
+// the time each of them is purged at.
+func (s *TasksService) ListTrash(ctx context.Context, workspaceId int, reqUserId int) ([]models.TrashedTask, error) {
+	tasks, err := s.Repo.ListTrash(ctx, workspaceId, reqUserId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tasks {
+		tasks[i].PurgeAt = tasks[i].DeletedAt.Add(s.TrashRetention)
+	}
+	return tasks, nil
+}
+
+// RestoreById takes the task out of the trash. Tasks whose status was
+// removed from the workflow meanwhile start over in the initial status.
+func (s *TasksService) RestoreById(ctx context.Context, workspaceId int, taskId int, reqUserId int) (models.TaskData, error) {
+	taskDb, err := s.Auth.AuthorizeTrashedTask(ctx, workspaceId, taskId, reqUserId)
+	if err != nil {
+		return models.TaskData{}, err
+	}
+
+	workflow, err := s.Workflows.WorkflowOf(ctx, taskDb.ProjectId)
 	if err != nil {
 		return models.TaskData{}, err
 	}
+	status := taskDb.Status
+	if !workflow.HasStatus(status) {
+		status = workflow.InitialStatus()
+	}
+
+	task, err := s.Repo.Restore(ctx, workspaceId, taskId, status, reqUserId)
+	if err == repos.ErrNotFound {
+		return models.TaskData{}, ErrTaskDoesNotExist
+	}
+	return task, err
+}
+
+// PurgeById deletes the task in the trash for good, together with its
+// attachments.
+func (s *TasksService) PurgeById(ctx context.Context, workspaceId int, taskId int, reqUserId int) error {
+	if _, err := s.Auth.AuthorizeTrashedTask(ctx, workspaceId, taskId, reqUserId); err != nil {
+		return err
+	}
 
-	if taskDb.UserId != reqUserId {
-		return models.TaskData{}, ErrNotOwner
+	purged, err := s.Repo.PurgeById(ctx, workspaceId, taskId, reqUserId)
+	if err != nil {
+		return err
 	}
+	if purged.Count == 0 {
+		return ErrTaskDoesNotExist
+	}
+	s.Attachments.DeleteBlobs(ctx, purged.BlobKeys)
+	return nil
+}
 
-	return s.Repo.UpdateStatus(ctx, taskId, newStatus)
+// PurgeExpired deletes for good tasks which spent the trash retention in the
+// trash at the given time and returns how many of them there were.
+func (s *TasksService) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	purged, err := s.Repo.PurgeDeletedBefore(ctx, now.Add(-s.TrashRetention))
+	if err != nil {
+		return 0, err
+	}
+	s.Attachments.DeleteBlobs(ctx, purged.BlobKeys)
+	return purged.Count, nil
 }
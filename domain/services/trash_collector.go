@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const DefaultTrashPollInterval = time.Hour
+
+// TrashCollector periodically purges tasks which stayed in the trash longer
+// than the trash retention. It is safe to run one collector in every server
+// instance.
+type TrashCollector struct {
+	Tasks        *TasksService
+	PollInterval time.Duration
+}
+
+func NewTrashCollector(tasks *TasksService) *TrashCollector {
+	return &TrashCollector{Tasks: tasks, PollInterval: DefaultTrashPollInterval}
+}
+
+// RunOnce purges tasks expired at the given time and returns how many of
+// them were purged.
+func (s *TrashCollector) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	return s.Tasks.PurgeExpired(ctx, now)
+}
+
+// Run polls for expired tasks until ctx is cancelled.
+func (s *TrashCollector) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.RunOnce(ctx, time.Now().UTC())
+		if err != nil {
+			log.WithFields(log.Fields{"err": err}).Error("Failed to purge trashed tasks")
+		} else if purged > 0 {
+			log.WithFields(log.Fields{"purged": purged}).Info("Trashed tasks purged")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
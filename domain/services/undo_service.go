@@ -0,0 +1,173 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultUndoWindow is how long operations on tasks can be undone.
+const DefaultUndoWindow = 5 * time.Minute
+
+var (
+	ErrUndoDoesNotExist = errors.New("undo token does not exist")
+	ErrUndoExpired      = errors.New("undo window has passed")
+	ErrUndoConflict     = errors.New("tasks were changed since, undo would overwrite the changes")
+)
+
+// UndoService keeps the undo stack of every user. Undoable operations push
+// steps reversing them and hand out the token of the entry, which undoes the
+// operation while none of the tasks changed since.
+type UndoService struct {
+	Repo      *repos.UndoRepo
+	Auth      *Authorizer
+	Workflows *WorkflowsService
+	Window    time.Duration
+}
+
+func NewUndoService(repo *repos.UndoRepo, auth *Authorizer, workflows *WorkflowsService) *UndoService {
+	return &UndoService{Repo: repo, Auth: auth, Workflows: workflows, Window: DefaultUndoWindow}
+}
+
+func newUndoToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate undo token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Push puts the operation with steps reversing it on the undo stack of the
+// user and returns its token.
+func (s *UndoService) Push(ctx context.Context, workspaceId int, userId int, action string, steps []models.UndoStep) (string, error) {
+	token, err := newUndoToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	entry := models.UndoEntry{
+		Token:       token,
+		UserId:      userId,
+		WorkspaceId: workspaceId,
+		Action:      action,
+		Steps:       steps,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.Window),
+	}
+	if err := s.Repo.Push(ctx, entry); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ListByUserId returns operations of the user which can still be undone, the
+// most recent first.
+func (s *UndoService) ListByUserId(ctx context.Context, workspaceId int, reqUserId int) ([]models.UndoEntry, error) {
+	entries, err := s.Repo.ListByUserId(ctx, workspaceId, reqUserId, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		for _, step := range entries[i].Steps {
+			entries[i].TaskIds = append(entries[i].TaskIds, step.TaskId)
+		}
+	}
+	return entries, nil
+}
+
+// authorize checks the user may still make the changes of the steps, access
+// could have been revoked since the operation. Returns the tasks in the trash
+// the steps restore by their ids.
+func (s *UndoService) authorize(ctx context.Context, entry models.UndoEntry) (map[int]models.TrashedTask, error) {
+	var taskIds []int
+	trashed := make(map[int]models.TrashedTask)
+	for _, step := range entry.Steps {
+		if !step.Restore {
+			taskIds = append(taskIds, step.TaskId)
+			continue
+		}
+		task, err := s.Auth.AuthorizeTrashedTask(ctx, entry.WorkspaceId, step.TaskId, entry.UserId)
+		if err != nil {
+			return nil, err
+		}
+		trashed[step.TaskId] = task
+	}
+	if len(taskIds) == 0 {
+		return trashed, nil
+	}
+
+	authorizations, err := s.Auth.AuthorizeTasks(ctx, entry.WorkspaceId, taskIds, entry.UserId, bulkRoles[entry.Action], entry.Action == models.BulkSetStatus)
+	if err != nil {
+		return nil, err
+	}
+	for _, auth := range authorizations {
+		if auth.Err != nil {
+			return nil, auth.Err
+		}
+	}
+	for _, step := range entry.Steps {
+		if step.Move && step.ProjectId != nil {
+			if _, err := s.Auth.AuthorizeProject(ctx, entry.WorkspaceId, *step.ProjectId, entry.UserId, models.RoleEditor); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return trashed, nil
+}
+
+// Undo reverses the operation of the token and returns the tasks it changed
+// back. Operations are undone as a whole or not at all, undo is refused if
+// any of the tasks changed since the operation.
+func (s *UndoService) Undo(ctx context.Context, workspaceId int, token string, reqUserId int) ([]models.TaskData, error) {
+	entry, err := s.Repo.GetByToken(ctx, workspaceId, reqUserId, token)
+	if err == repos.ErrNotFound {
+		return nil, ErrUndoDoesNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().UTC().Before(entry.ExpiresAt) {
+		return nil, ErrUndoExpired
+	}
+
+	trashed, err := s.authorize(ctx, entry)
+	// tasks purged since can't be brought back
+	if err == ErrTaskDoesNotExist {
+		return nil, ErrUndoConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// restored tasks get the initial status of the workflow if it no longer
+	// has theirs, as with RestoreById
+	for i, step := range entry.Steps {
+		task, ok := trashed[step.TaskId]
+		if !step.Restore || !ok {
+			continue
+		}
+		workflow, err := s.Workflows.WorkflowOf(ctx, task.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		if !workflow.HasStatus(task.Status) {
+			status := workflow.InitialStatus()
+			entry.Steps[i].Status = &status
+		}
+	}
+
+	tasks, err := s.Repo.Apply(ctx, entry)
+	switch err {
+	case repos.ErrNotFound:
+		return nil, ErrUndoDoesNotExist
+	case repos.ErrVersionMismatch:
+		return nil, ErrUndoConflict
+	}
+	return tasks, err
+}
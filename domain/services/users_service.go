@@ -79,3 +79,13 @@ func (s *UsersService) Login(ctx context.Context, email string, password string)
 	}
 	return tokenString, nil
 }
+
+// UpdateTimeZone sets the time zone relative dates in task filters of the user
+// resolve in.
+func (s *UsersService) UpdateTimeZone(ctx context.Context, user models.UserData, timeZone string) (models.UserData, error) {
+	if err := s.Repo.UpdateTimeZone(ctx, user.Id, timeZone); err != nil {
+		return models.UserData{}, err
+	}
+	user.TimeZone = timeZone
+	return user, nil
+}
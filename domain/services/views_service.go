@@ -0,0 +1,132 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"context"
+	"errors"
+	"reflect"
+	"time"
+)
+
+var ErrViewDoesNotExist = errors.New("view with given id does not exist")
+
+// ViewsService manages saved views, task filters users keep under a name.
+// Views are private to their owner unless shared with the workspace, in
+// which case other members can list them too, but not change them.
+type ViewsService struct {
+	Repo  *repos.ViewsRepo
+	Tasks *TasksService
+}
+
+func NewViewsService(repo *repos.ViewsRepo, tasks *TasksService) *ViewsService {
+	return &ViewsService{Repo: repo, Tasks: tasks}
+}
+
+// validate checks the definition of the view the same way task listings
+// check their filter. Cursors point into a single listing and aren't saved.
+func (s *ViewsService) validate(ctx context.Context, workspaceId int, view *models.ViewCreate) error {
+	view.Filter.Cursor = nil
+	viewData := models.ViewData{Filter: view.Filter, GroupBy: view.GroupBy}
+	return s.Tasks.ValidateFilter(ctx, workspaceId, viewData.TasksFilter(models.ViewTasksParams{}))
+}
+
+func (s *ViewsService) Create(ctx context.Context, workspaceId int, view models.ViewCreate, userId int) (models.ViewData, error) {
+	if err := s.validate(ctx, workspaceId, &view); err != nil {
+		return models.ViewData{}, err
+	}
+	return s.Repo.Create(ctx, workspaceId, view, userId)
+}
+
+func (s *ViewsService) ListByUserId(ctx context.Context, workspaceId int, userId int) ([]models.ViewData, error) {
+	return s.Repo.ListByUserId(ctx, workspaceId, userId)
+}
+
+// GetById returns the view if it's visible to the user, private views of
+// other users don't exist for them.
+func (s *ViewsService) GetById(ctx context.Context, workspaceId int, viewId int, reqUserId int) (models.ViewData, error) {
+	view, err := s.Repo.GetById(ctx, workspaceId, viewId)
+	if err == repos.ErrNotFound {
+		return models.ViewData{}, ErrViewDoesNotExist
+	}
+	if err != nil {
+		return models.ViewData{}, err
+	}
+	if view.UserId != reqUserId && !view.Shared {
+		return models.ViewData{}, ErrViewDoesNotExist
+	}
+	return view, nil
+}
+
+func (s *ViewsService) getOwned(ctx context.Context, workspaceId int, viewId int, reqUserId int) (models.ViewData, error) {
+	view, err := s.GetById(ctx, workspaceId, viewId, reqUserId)
+	if err != nil {
+		return models.ViewData{}, err
+	}
+	if view.UserId != reqUserId {
+		return models.ViewData{}, ErrNotOwner
+	}
+	return view, nil
+}
+
+// Update replaces the definition of the view.
+func (s *ViewsService) Update(ctx context.Context, workspaceId int, viewId int, view models.ViewCreate, reqUserId int) (models.ViewData, error) {
+	if _, err := s.getOwned(ctx, workspaceId, viewId, reqUserId); err != nil {
+		return models.ViewData{}, err
+	}
+	if err := s.validate(ctx, workspaceId, &view); err != nil {
+		return models.ViewData{}, err
+	}
+
+	updated, err := s.Repo.Update(ctx, workspaceId, viewId, view)
+	if err == repos.ErrNotFound {
+		return models.ViewData{}, ErrViewDoesNotExist
+	}
+	return updated, err
+}
+
+func (s *ViewsService) DeleteById(ctx context.Context, workspaceId int, viewId int, reqUserId int) error {
+	if _, err := s.getOwned(ctx, workspaceId, viewId, reqUserId); err != nil {
+		return err
+	}
+
+	err := s.Repo.DeleteById(ctx, workspaceId, viewId)
+	if err == repos.ErrNotFound {
+		return ErrViewDoesNotExist
+	}
+	return err
+}
+
+// ListTasks runs the view for the requesting user, so a shared view lists
+// tasks visible to whoever opens it and "me" stands for them.
+func (s *ViewsService) ListTasks(
+	ctx context.Context,
+	workspaceId int,
+	viewId int,
+	reqUserId int,
+	loc *time.Location,
+	params models.ViewTasksParams,
+) (models.CursorPage[models.TaskGroup], error) {
+	view, err := s.GetById(ctx, workspaceId, viewId, reqUserId)
+	if err != nil {
+		return models.CursorPage[models.TaskGroup]{}, err
+	}
+
+	tasks, err := s.Tasks.ListByUserId(ctx, workspaceId, reqUserId, loc, view.TasksFilter(params))
+	if err != nil {
+		return models.CursorPage[models.TaskGroup]{}, err
+	}
+
+	// tasks come sorted by the group key, a group spanning several pages
+	// continues with the same key on the next one
+	groups := []models.TaskGroup{}
+	for _, task := range tasks.Items {
+		key := view.GroupKey(task)
+		if last := len(groups) - 1; last >= 0 && reflect.DeepEqual(groups[last].Key, key) {
+			groups[last].Tasks = append(groups[last].Tasks, task)
+			continue
+		}
+		groups = append(groups, models.TaskGroup{Key: key, Tasks: []models.TaskListItem{task}})
+	}
+	return models.CursorPage[models.TaskGroup]{Items: groups, Next: tasks.Next, Prev: tasks.Prev}, nil
+}
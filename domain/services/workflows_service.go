@@ -0,0 +1,92 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"context"
+	"errors"
+)
+
+var (
+	ErrInvalidStatus        = errors.New("status is not part of the workflow")
+	ErrDuplicateStatus      = errors.New("workflow status names must be unique")
+	ErrTransitionNotAllowed = errors.New("workflow doesn't allow this status transition")
+	ErrStatusInUse          = errors.New("tasks of the project are in a status missing from the workflow")
+	ErrInvalidTransition    = errors.New("workflow transition refers to an unknown status")
+)
+
+// WorkflowsService manages per project status workflows. Projects without
+// a workflow of their own and tasks outside of projects use the default one.
+type WorkflowsService struct {
+	Repo *repos.WorkflowsRepo
+	Auth *Authorizer
+}
+
+func NewWorkflowsService(repo *repos.WorkflowsRepo, auth *Authorizer) *WorkflowsService {
+	return &WorkflowsService{Repo: repo, Auth: auth}
+}
+
+// WorkflowOf returns the workflow tasks of given project follow, projectId
+// is nil for tasks outside of projects.
+func (s *WorkflowsService) WorkflowOf(ctx context.Context, projectId *int) (models.WorkflowData, error) {
+	if projectId != nil {
+		workflow, err := s.Repo.GetByProjectId(ctx, projectId)
+		if err != repos.ErrNotFound {
+			return workflow, err
+		}
+	}
+	return s.Repo.GetByProjectId(ctx, nil)
+}
+
+func (s *WorkflowsService) GetByProjectId(ctx context.Context, workspaceId int, projectId int, reqUserId int) (models.WorkflowData, error) {
+	if _, err := s.Auth.AuthorizeProject(ctx, workspaceId, projectId, reqUserId, models.RoleViewer); err != nil {
+		return models.WorkflowData{}, err
+	}
+	return s.WorkflowOf(ctx, &projectId)
+}
+
+// Update replaces the workflow of the project. Every task of the project must
+// stay in a status of the new workflow.
+func (s *WorkflowsService) Update(
+	ctx context.Context,
+	workspaceId int,
+	projectId int,
+	workflow models.WorkflowUpdate,
+	reqUserId int,
+) (models.WorkflowData, error) {
+	if _, err := s.Auth.AuthorizeProject(ctx, workspaceId, projectId, reqUserId, models.RoleOwner); err != nil {
+		return models.WorkflowData{}, err
+	}
+
+	names := make(map[string]bool, len(workflow.Statuses))
+	for _, status := range workflow.Statuses {
+		if names[status.Name] {
+			return models.WorkflowData{}, ErrDuplicateStatus
+		}
+		names[status.Name] = true
+	}
+
+	var transitions []models.TransitionData
+	if workflow.Transitions == nil {
+		for _, from := range workflow.Statuses {
+			for _, to := range workflow.Statuses {
+				if from.Name != to.Name {
+					transitions = append(transitions, models.TransitionData{From: from.Name, To: to.Name})
+				}
+			}
+		}
+	} else {
+		transitions = *workflow.Transitions
+		for _, transition := range transitions {
+			if !names[transition.From] || !names[transition.To] {
+				return models.WorkflowData{}, ErrInvalidTransition
+			}
+		}
+	}
+
+	updated, err := s.Repo.Replace(ctx, projectId, workflow.Statuses, transitions)
+	if err == repos.ErrStatusInUse {
+		return models.WorkflowData{}, ErrStatusInUse
+	}
+	return updated, err
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/utils"
+	"context"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrWorkspaceDoesNotExist = errors.New("workspace with given id does not exist")
+	ErrMemberDoesNotExist    = errors.New("user is not a member of the workspace")
+	ErrAlreadyMember         = errors.New("user is already a member of the workspace")
+	ErrCannotRemoveOwner     = errors.New("workspace owner can't be removed")
+)
+
+// WorkspacesService manages workspaces and their members. Workspaces the user
+// is not a member of are reported as missing so their existence doesn't leak.
+type WorkspacesService struct {
+	Repo *repos.WorkspacesRepo
+}
+
+func NewWorkspacesService(repo *repos.WorkspacesRepo) *WorkspacesService {
+	return &WorkspacesService{Repo: repo}
+}
+
+func (s *WorkspacesService) Create(ctx context.Context, workspace models.WorkspaceCreate, userId int) (models.UserWorkspace, error) {
+	return s.Repo.Create(ctx, workspace.Name, userId)
+}
+
+func (s *WorkspacesService) ListByUserId(ctx context.Context, userId int) ([]models.UserWorkspace, error) {
+	return s.Repo.ListByUserId(ctx, userId)
+}
+
+// getMembership returns the workspace if the user is its member with at least the required role.
+func (s *WorkspacesService) getMembership(ctx context.Context, workspaceId int, userId int, required string) (models.UserWorkspace, error) {
+	workspace, err := s.Repo.GetMembership(ctx, workspaceId, userId)
+	if err == repos.ErrNotFound {
+		return models.UserWorkspace{}, ErrWorkspaceDoesNotExist
+	}
+	if err != nil {
+		return models.UserWorkspace{}, err
+	}
+	if !models.WorkspaceRoleAtLeast(workspace.Role, required) {
+		return models.UserWorkspace{}, ErrForbidden
+	}
+	return workspace, nil
+}
+
+func (s *WorkspacesService) ListMembers(ctx context.Context, workspaceId int, reqUserId int) ([]models.WorkspaceMemberData, error) {
+	if _, err := s.getMembership(ctx, workspaceId, reqUserId, models.WorkspaceRoleMember); err != nil {
+		return nil, err
+	}
+	return s.Repo.ListMembers(ctx, workspaceId)
+}
+
+func (s *WorkspacesService) Invite(
+	ctx context.Context,
+	workspaceId int,
+	invite models.WorkspaceInvite,
+	reqUser models.UserData,
+) (models.WorkspaceInvitationData, error) {
+	if _, err := s.getMembership(ctx, workspaceId, reqUser.Id, models.WorkspaceRoleAdmin); err != nil {
+		return models.WorkspaceInvitationData{}, err
+	}
+	invite.Email = utils.NormalizeEmail(invite.Email)
+	if invite.Email == utils.NormalizeEmail(reqUser.Email) {
+		return models.WorkspaceInvitationData{}, ErrCannotInviteSelf
+	}
+	isMember, err := s.Repo.IsMemberEmail(ctx, workspaceId, invite.Email)
+	if err != nil {
+		return models.WorkspaceInvitationData{}, err
+	}
+	if isMember {
+		return models.WorkspaceInvitationData{}, ErrAlreadyMember
+	}
+
+	invitation, err := s.Repo.CreateInvitation(ctx, workspaceId, invite.Email, invite.Role, reqUser.Id)
+	if err != nil {
+		return models.WorkspaceInvitationData{}, err
+	}
+
+	log.WithFields(log.Fields{
+		"invitation_id": invitation.Id,
+		"workspace_id":  workspaceId,
+		"email":         invitation.Email,
+		"role":          invitation.Role,
+	}).Info("Workspace invitation created")
+	return invitation, nil
+}
+
+// RemoveMember removes the user from the workspace. Admins may remove anyone
+// but the owner, other members may only leave the workspace themselves.
+func (s *WorkspacesService) RemoveMember(ctx context.Context, workspaceId int, userId int, reqUserId int) error {
+	required := models.WorkspaceRoleAdmin
+	if userId == reqUserId {
+		required = models.WorkspaceRoleMember
+	}
+	if _, err := s.getMembership(ctx, workspaceId, reqUserId, required); err != nil {
+		return err
+	}
+
+	member, err := s.Repo.GetMembership(ctx, workspaceId, userId)
+	if err == repos.ErrNotFound {
+		return ErrMemberDoesNotExist
+	}
+	if err != nil {
+		return err
+	}
+	if member.Role == models.WorkspaceRoleOwner {
+		return ErrCannotRemoveOwner
+	}
+
+	err = s.Repo.DeleteMember(ctx, workspaceId, userId)
+	if err == repos.ErrNotFound {
+		return ErrMemberDoesNotExist
+	}
+	return err
+}
+
+func (s *WorkspacesService) ListInvitations(ctx context.Context, reqUser models.UserData) ([]models.WorkspaceInvitationData, error) {
+	return s.Repo.ListPendingInvitations(ctx, utils.NormalizeEmail(reqUser.Email))
+}
+
+// getOwnInvitation returns a pending invitation addressed to the user's email.
+func (s *WorkspacesService) getOwnInvitation(ctx context.Context, invitationId int, reqUser models.UserData) (models.WorkspaceInvitationData, error) {
+	invitation, err := s.Repo.GetPendingInvitation(ctx, invitationId)
+	if err == repos.ErrNotFound || (err == nil && invitation.Email != utils.NormalizeEmail(reqUser.Email)) {
+		return models.WorkspaceInvitationData{}, ErrInvitationDoesNotExist
+	}
+	return invitation, err
+}
+
+func (s *WorkspacesService) AcceptInvitation(ctx context.Context, invitationId int, reqUser models.UserData) error {
+	invitation, err := s.getOwnInvitation(ctx, invitationId, reqUser)
+	if err != nil {
+		return err
+	}
+
+	err = s.Repo.AcceptInvitation(ctx, invitation, reqUser.Id)
+	if err == repos.ErrNotFound {
+		return ErrInvitationDoesNotExist
+	}
+	return err
+}
+
+func (s *WorkspacesService) DeclineInvitation(ctx context.Context, invitationId int, reqUser models.UserData) error {
+	if _, err := s.getOwnInvitation(ctx, invitationId, reqUser); err != nil {
+		return err
+	}
+
+	err := s.Repo.DeleteInvitation(ctx, invitationId)
+	if err == repos.ErrNotFound {
+		return ErrInvitationDoesNotExist
+	}
+	return err
+}
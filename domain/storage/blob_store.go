@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"api-server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps binary content addressed by an opaque key.
+type BlobStore interface {
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewBlobStoreFromEnv creates blob store selected by BLOB_STORE env variable.
+func NewBlobStoreFromEnv() BlobStore {
+	switch backend := utils.GetenvOrDefault("BLOB_STORE", "local"); backend {
+	case "local":
+		return NewLocalBlobStore(utils.GetenvOrDefault("BLOB_DIR", "./data/blobs"))
+	case "s3":
+		return NewS3BlobStore(
+			utils.MustGetenv("S3_ENDPOINT"),
+			utils.MustGetenv("S3_BUCKET"),
+			utils.GetenvOrDefault("S3_REGION", "us-east-1"),
+			utils.MustGetenv("S3_ACCESS_KEY"),
+			utils.MustGetenv("S3_SECRET_KEY"),
+		)
+	default:
+		panic(fmt.Sprintf("unknown BLOB_STORE backend %q", backend))
+	}
+}
@@ -7,35 +7,112 @@ import (
 	"api-server/db"
 	"api-server/domain/repos"
 	"api-server/domain/services"
+	"api-server/domain/storage"
 	"api-server/utils"
+	"context"
+	"errors"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	log "github.com/sirupsen/logrus"
 )
 
 type Services struct {
-	TokenProvider *services.JwtTokenProvider
-	UsersService  *services.UsersService
-	UsersRepo     *repos.UsersRepo
-	TasksService  *services.TasksService
-	TasksRepo     *repos.TasksRepo
+	TokenProvider       *services.JwtTokenProvider
+	UsersService        *services.UsersService
+	UsersRepo           *repos.UsersRepo
+	TasksService        *services.TasksService
+	TasksRepo           *repos.TasksRepo
+	RemindersService    *services.RemindersService
+	RemindersScheduler  *services.RemindersScheduler
+	TrashCollector      *services.TrashCollector
+	CommentsService     *services.CommentsService
+	AttachmentsService  *services.AttachmentsService
+	ProjectsService     *services.ProjectsService
+	SharingService      *services.SharingService
+	WorkspacesService   *services.WorkspacesService
+	WorkspacesRepo      *repos.WorkspacesRepo
+	AssigneesService    *services.AssigneesService
+	WorkflowsService    *services.WorkflowsService
+	CustomFieldsService *services.CustomFieldsService
+	ViewsService        *services.ViewsService
+	HistoryService      *services.HistoryService
+	IdempotencyService  *services.IdempotencyService
+	UndoService         *services.UndoService
+	TaskEventsHub       *services.TaskEventsHub
 }
 
-func SetupDependencies(conn *pgxpool.Pool) *Services {
+func SetupDependencies(conn *pgxpool.Pool, blobStore storage.BlobStore) *Services {
 	tp := services.NewJwtTokenProvider()
 
 	userRepo := repos.NewUsersRepo(conn)
 	userService := services.NewUsersService(userRepo, tp)
 
+	workspacesRepo := repos.NewWorkspacesRepo(conn)
+	workspacesService := services.NewWorkspacesService(workspacesRepo)
+
 	tasksRepo := repos.NewTasksRepo(conn)
-	tasksService := services.NewTasksService(tasksRepo)
+	projectsRepo := repos.NewProjectsRepo(conn)
+	aclRepo := repos.NewACLRepo(conn)
+	assigneesRepo := repos.NewAssigneesRepo(conn)
+	authorizer := services.NewAuthorizer(tasksRepo, projectsRepo, aclRepo, assigneesRepo)
+	projectsService := services.NewProjectsService(projectsRepo, authorizer)
+	sharingService := services.NewSharingService(aclRepo, workspacesRepo, authorizer)
+	assigneesService := services.NewAssigneesService(assigneesRepo, workspacesRepo, authorizer)
+
+	attachmentsRepo := repos.NewAttachmentsRepo(conn)
+	attachmentsService := services.NewAttachmentsService(attachmentsRepo, authorizer, blobStore)
+	workflowsService := services.NewWorkflowsService(repos.NewWorkflowsRepo(conn), authorizer)
+	customFieldsService := services.NewCustomFieldsService(repos.NewCustomFieldsRepo(conn), authorizer)
+	cursorCodec := utils.NewCursorCodec(utils.GetenvOrDefault("CURSOR_SECRET", tp.JwtSecret))
+	undoService := services.NewUndoService(repos.NewUndoRepo(conn), authorizer, workflowsService)
+	undoService.Window = time.Duration(utils.GetenvIntOrDefault("UNDO_WINDOW_SECONDS", int(services.DefaultUndoWindow/time.Second))) * time.Second
+	tasksService := services.NewTasksService(tasksRepo, authorizer, attachmentsService, workflowsService, customFieldsService, cursorCodec, undoService)
+	tasksService.TrashRetention = time.Duration(utils.GetenvIntOrDefault("TRASH_RETENTION_DAYS", services.DefaultTrashRetentionDays)) * 24 * time.Hour
+	trashCollector := services.NewTrashCollector(tasksService)
+
+	viewsService := services.NewViewsService(repos.NewViewsRepo(conn), tasksService)
+	historyService := services.NewHistoryService(repos.NewHistoryRepo(conn), authorizer)
+
+	remindersRepo := repos.NewRemindersRepo(conn)
+	remindersService := services.NewRemindersService(remindersRepo, authorizer)
+	remindersScheduler := services.NewRemindersScheduler(remindersRepo, services.LogNotifier{})
+
+	idempotencyService := services.NewIdempotencyService(repos.NewIdempotencyRepo(conn))
+	idempotencyService.TTL = time.Duration(utils.GetenvIntOrDefault("IDEMPOTENCY_KEY_TTL_HOURS", int(services.DefaultIdempotencyKeyTTL/time.Hour))) * time.Hour
+
+	commentsRepo := repos.NewCommentsRepo(conn)
+	commentsService := services.NewCommentsService(commentsRepo, authorizer)
+
+	taskEventsHub := services.NewTaskEventsHub(repos.NewTaskEventsRepo(conn))
+	taskEventsHub.Retention = time.Duration(utils.GetenvIntOrDefault("TASK_EVENT_RETENTION_HOURS", int(services.DefaultTaskEventRetention/time.Hour))) * time.Hour
 
 	return &Services{
-		TokenProvider: tp,
-		UsersService:  userService,
-		UsersRepo:     userRepo,
-		TasksService:  tasksService,
-		TasksRepo:     tasksRepo,
+		TokenProvider:       tp,
+		UsersService:        userService,
+		UsersRepo:           userRepo,
+		TasksService:        tasksService,
+		TasksRepo:           tasksRepo,
+		RemindersService:    remindersService,
+		RemindersScheduler:  remindersScheduler,
+		TrashCollector:      trashCollector,
+		CommentsService:     commentsService,
+		AttachmentsService:  attachmentsService,
+		ProjectsService:     projectsService,
+		SharingService:      sharingService,
+		WorkspacesService:   workspacesService,
+		WorkspacesRepo:      workspacesRepo,
+		AssigneesService:    assigneesService,
+		WorkflowsService:    workflowsService,
+		CustomFieldsService: customFieldsService,
+		ViewsService:        viewsService,
+		HistoryService:      historyService,
+		IdempotencyService:  idempotencyService,
+		UndoService:         undoService,
+		TaskEventsHub:       taskEventsHub,
 	}
 }
 
@@ -47,8 +124,11 @@ func main() {
 	// Setup DB connection
 	conn := db.ConnectDB()
 
+	// Setup attachments storage
+	blobStore := storage.NewBlobStoreFromEnv()
+
 	// Setup deps
-	deps := SetupDependencies(conn)
+	deps := SetupDependencies(conn, blobStore)
 
 	// Register validators
 	utils.RegisterValidators()
@@ -56,13 +136,63 @@ func main() {
 	// Setup Auth middleware
 	jwtHeaderAuth := middlewares.NewJwtHeaderAuthenticator(deps.TokenProvider, deps.UsersRepo)
 	jwtCookieAuth := middlewares.NewJwtCookieAuthenticator(deps.TokenProvider, deps.UsersRepo)
+	jwtAuth := middlewares.NewJwtHeaderOrCookieAuthenticator(jwtHeaderAuth, jwtCookieAuth)
+	workspaceResolver := middlewares.NewWorkspaceResolver(deps.WorkspacesRepo, jwtHeaderAuth.AuthCtxKey)
+	idempotencyGuard := middlewares.NewIdempotencyGuard(deps.IdempotencyService, jwtHeaderAuth, workspaceResolver)
+	connectionTracker := middlewares.NewConnectionTracker(jwtCookieAuth.AuthCtxKey)
+	connectionTracker.MaxPerUser = utils.GetenvIntOrDefault("MAX_CONNECTIONS_PER_USER", middlewares.DefaultMaxConnectionsPerUser)
 
 	// Register all app routes
 	r := routes.SetupDefaultRouter()
-	routes.RegisterAuthRoutes(r, jwtHeaderAuth, deps.UsersService)
-	routes.RegisterTasksRoutes(r, jwtHeaderAuth, deps.TasksService)
-	routes.RegisterDashboardRoute(r, jwtCookieAuth, deps.TasksService)
+	routes.RegisterDashboardRoute(r, jwtCookieAuth, workspaceResolver, connectionTracker, deps.TasksService, deps.TaskEventsHub)
+	routes.RegisterTaskEventsRoute(r, jwtAuth, workspaceResolver, connectionTracker, deps.TasksService, deps.TaskEventsHub)
+	routes.RegisterHistoryRoutes(r, jwtHeaderAuth, workspaceResolver, deps.HistoryService)
 
-	log.WithFields(log.Fields{"host": addr}).Info("Starting server")
-	r.Run(addr)
+	// routes with mutating requests, the guard only sees requests matching them
+	guarded := r.Group("", idempotencyGuard.Handler)
+	routes.RegisterAuthRoutes(guarded, jwtHeaderAuth, deps.UsersService)
+	routes.RegisterTasksRoutes(guarded, jwtHeaderAuth, workspaceResolver, deps.TasksService)
+	routes.RegisterUndoRoutes(guarded, jwtHeaderAuth, workspaceResolver, deps.UndoService)
+	routes.RegisterRemindersRoutes(guarded, jwtHeaderAuth, workspaceResolver, deps.RemindersService)
+	routes.RegisterCommentsRoutes(guarded, jwtHeaderAuth, workspaceResolver, deps.CommentsService)
+	routes.RegisterAttachmentsRoutes(guarded, jwtHeaderAuth, workspaceResolver, deps.AttachmentsService)
+	routes.RegisterProjectsRoutes(guarded, jwtHeaderAuth, workspaceResolver, deps.ProjectsService)
+	routes.RegisterWorkflowsRoutes(guarded, jwtHeaderAuth, workspaceResolver, deps.WorkflowsService)
+	routes.RegisterCustomFieldsRoutes(guarded, jwtHeaderAuth, workspaceResolver, deps.CustomFieldsService)
+	routes.RegisterViewsRoutes(guarded, jwtHeaderAuth, workspaceResolver, deps.ViewsService)
+	routes.RegisterSharingRoutes(guarded, jwtHeaderAuth, workspaceResolver, deps.SharingService)
+	routes.RegisterAssigneesRoutes(guarded, jwtHeaderAuth, workspaceResolver, deps.AssigneesService)
+	routes.RegisterWorkspacesRoutes(guarded, jwtHeaderAuth, deps.WorkspacesService)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start background jobs
+	go deps.RemindersScheduler.Run(ctx)
+	go deps.TrashCollector.Run(ctx)
+	go services.NewIdempotencyCollector(deps.IdempotencyService).Run(ctx)
+	go deps.TaskEventsHub.Run(ctx)
+	go services.NewTaskEventLogCollector(deps.TaskEventsHub).Run(ctx)
+
+	server := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		log.WithFields(log.Fields{"host": addr}).Info("Starting server")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.WithFields(log.Fields{"err": err}).Fatal("Server failed")
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("Shutting down server")
+
+	// long-lived connections are closed first, server shutdown would wait on
+	// event streams otherwise, then requests in flight are finished
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := connectionTracker.Shutdown(shutdownCtx); err != nil {
+		log.WithFields(log.Fields{"err": err}).Error("Failed to close connections")
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.WithFields(log.Fields{"err": err}).Error("Failed to finish requests")
+	}
 }
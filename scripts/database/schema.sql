@@ -1,18 +1,448 @@
-CREATE TYPE task_status AS ENUM ('Won''t do', 'To do', 'In progress', 'Done');
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TYPE status_category AS ENUM ('todo', 'active', 'done', 'cancelled');
+CREATE TYPE share_role AS ENUM ('viewer', 'editor', 'owner');
+CREATE TYPE workspace_role AS ENUM ('member', 'admin', 'owner');
+CREATE TYPE custom_field_type AS ENUM ('text', 'number', 'date', 'select', 'multi_select', 'checkbox');
+CREATE TYPE task_priority AS ENUM ('low', 'medium', 'high', 'urgent');
 
 CREATE TABLE users (
     id SERIAL PRIMARY KEY,
     email VARCHAR(255) UNIQUE NOT NULL,
     password_hash TEXT NOT NULL,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Workspaces are tenants owning projects and tasks. Every user has a single
+-- personal workspace used when no other workspace is selected.
+CREATE TABLE workspaces (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    name TEXT NOT NULL,
+    personal BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX workspaces_personal_idx ON workspaces (user_id) WHERE personal;
+
+CREATE TABLE workspace_members (
+    workspace_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role workspace_role NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, user_id),
+    FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE workspace_invitations (
+    id SERIAL PRIMARY KEY,
+    workspace_id INT NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    role workspace_role NOT NULL,
+    invited_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    accepted_at TIMESTAMP,
+    FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+    FOREIGN KEY (invited_by) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- Emails are stored lowercased. A workspace holds at most one pending
+-- invitation per email, re-inviting updates it.
+CREATE UNIQUE INDEX workspace_invitations_pending_idx ON workspace_invitations (email, workspace_id)
+    WHERE accepted_at IS NULL;
+
+CREATE TABLE projects (
+    id SERIAL PRIMARY KEY,
+    workspace_id INT NOT NULL,
+    user_id INT NOT NULL,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+-- Ordered statuses tasks move through. Statuses without a project form the
+-- default workflow used by tasks outside of projects and by projects which
+-- haven't defined their own.
+CREATE TABLE workflow_statuses (
+    id SERIAL PRIMARY KEY,
+    project_id INT,
+    name TEXT NOT NULL,
+    category status_category NOT NULL,
+    position INT NOT NULL,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX workflow_statuses_project_name_idx ON workflow_statuses (project_id, name);
+CREATE UNIQUE INDEX workflow_statuses_default_name_idx ON workflow_statuses (name) WHERE project_id IS NULL;
+
+CREATE TABLE workflow_transitions (
+    from_status_id INT NOT NULL,
+    to_status_id INT NOT NULL,
+    PRIMARY KEY (from_status_id, to_status_id),
+    FOREIGN KEY (from_status_id) REFERENCES workflow_statuses (id) ON DELETE CASCADE,
+    FOREIGN KEY (to_status_id) REFERENCES workflow_statuses (id) ON DELETE CASCADE
+);
+
+INSERT INTO workflow_statuses (name, category, position) VALUES
+    ('To do', 'todo', 0),
+    ('In progress', 'active', 1),
+    ('Done', 'done', 2),
+    ('Won''t do', 'cancelled', 3);
+
+INSERT INTO workflow_transitions (from_status_id, to_status_id)
+SELECT f.id, t.id FROM workflow_statuses f, workflow_statuses t
+WHERE f.project_id IS NULL AND t.project_id IS NULL AND f.id <> t.id;
+
 CREATE TABLE tasks (
     id SERIAL PRIMARY KEY,
+    workspace_id INT NOT NULL,
     user_id INT NOT NULL,
+    project_id INT,
     name TEXT NOT NULL,
+    description TEXT,
     due_date TIMESTAMP,
-    status task_status NOT NULL DEFAULT 'To do' ,
+    status TEXT NOT NULL,
+    priority task_priority,
+    labels TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    -- bumped by every update, clients use it for conditional requests
+    version INT NOT NULL DEFAULT 1,
+    -- deleted tasks stay in the trash until restored or purged
+    deleted_at TIMESTAMP,
+    deleted_by INT,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', name), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+    ) STORED,
+    FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    FOREIGN KEY (deleted_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX tasks_workspace_id_idx ON tasks (workspace_id);
+CREATE INDEX tasks_deleted_at_idx ON tasks (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX tasks_search_idx ON tasks USING GIN (search_vector);
+CREATE INDEX tasks_name_trgm_idx ON tasks USING GIN (name gin_trgm_ops);
+CREATE INDEX tasks_labels_idx ON tasks USING GIN (labels);
+
+CREATE TABLE task_assignees (
+    task_id INT NOT NULL,
+    user_id INT NOT NULL,
+    assigned_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, user_id),
+    FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (assigned_by) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX task_assignees_user_id_idx ON task_assignees (user_id);
+
+-- Typed fields project owners define on tasks of their project. Options
+-- list allowed values of select fields.
+CREATE TABLE custom_fields (
+    id SERIAL PRIMARY KEY,
+    project_id INT NOT NULL,
+    name TEXT NOT NULL,
+    type custom_field_type NOT NULL,
+    options TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (project_id, name)
+);
+
+CREATE TABLE task_field_values (
+    task_id INT NOT NULL,
+    field_id INT NOT NULL,
+    value JSONB NOT NULL,
+    PRIMARY KEY (task_id, field_id),
+    FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    FOREIGN KEY (field_id) REFERENCES custom_fields (id) ON DELETE CASCADE
+);
+
+CREATE INDEX task_field_values_field_id_idx ON task_field_values (field_id);
+CREATE INDEX task_field_values_value_idx ON task_field_values USING GIN (value jsonb_path_ops);
+
+CREATE TABLE task_reminders (
+    id SERIAL PRIMARY KEY,
+    task_id INT NOT NULL,
+    remind_at TIMESTAMP NOT NULL,
+    -- reminders set relative to the due date follow its changes
+    offset_minutes INT,
+    sent_at TIMESTAMP,
+    -- delivery attempts, the next one is backed off until next_attempt_at
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    failed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE
+);
+
+CREATE INDEX task_reminders_pending_idx ON task_reminders (remind_at) WHERE sent_at IS NULL AND failed_at IS NULL;
+
+CREATE TABLE task_comments (
+    id SERIAL PRIMARY KEY,
+    task_id INT NOT NULL,
+    user_id INT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    search_vector TSVECTOR GENERATED ALWAYS AS (setweight(to_tsvector('english', body), 'C')) STORED,
+    FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX task_comments_task_id_idx ON task_comments (task_id, created_at) WHERE deleted_at IS NULL;
+
+CREATE TABLE task_attachments (
+    id SERIAL PRIMARY KEY,
+    task_id INT NOT NULL,
+    user_id INT NOT NULL,
+    file_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    blob_key TEXT UNIQUE NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
     FOREIGN KEY (user_id) REFERENCES users (id)
 );
+
+CREATE INDEX task_attachments_task_id_idx ON task_attachments (task_id);
+
+-- Append-only log of task changes. Entries outlive purged tasks, user_id
+-- is the actor and is missing for changes made by background jobs.
+CREATE TABLE task_history (
+    id BIGSERIAL PRIMARY KEY,
+    task_id INT NOT NULL,
+    workspace_id INT NOT NULL,
+    user_id INT,
+    action TEXT NOT NULL,
+    field TEXT,
+    old_value JSONB,
+    new_value JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX task_history_task_id_idx ON task_history (task_id, id);
+CREATE INDEX task_history_user_id_idx ON task_history (workspace_id, user_id, id);
+
+-- Task filters saved under a name, shared views are listed to all members
+-- of the workspace.
+CREATE TABLE saved_views (
+    id SERIAL PRIMARY KEY,
+    workspace_id INT NOT NULL,
+    user_id INT NOT NULL,
+    name TEXT NOT NULL,
+    filter JSONB NOT NULL DEFAULT '{}',
+    group_by TEXT,
+    shared BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX saved_views_workspace_id_idx ON saved_views (workspace_id, user_id);
+
+-- Access granted to users other than the owner, either to a single task or
+-- to a whole project including all of its tasks.
+CREATE TABLE acl_entries (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    task_id INT,
+    project_id INT,
+    role share_role NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    CHECK ((task_id IS NULL) <> (project_id IS NULL)),
+    UNIQUE (user_id, task_id),
+    UNIQUE (user_id, project_id)
+);
+
+CREATE TABLE share_invitations (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    task_id INT,
+    project_id INT,
+    role share_role NOT NULL,
+    invited_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    accepted_at TIMESTAMP,
+    FOREIGN KEY (invited_by) REFERENCES users (id),
+    FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    CHECK ((task_id IS NULL) <> (project_id IS NULL))
+);
+
+-- Emails are stored lowercased. A target holds at most one pending
+-- invitation per email, re-inviting updates it.
+CREATE UNIQUE INDEX share_invitations_pending_task_idx ON share_invitations (email, task_id)
+    WHERE accepted_at IS NULL AND task_id IS NOT NULL;
+CREATE UNIQUE INDEX share_invitations_pending_project_idx ON share_invitations (email, project_id)
+    WHERE accepted_at IS NULL AND project_id IS NOT NULL;
+
+-- Responses of mutating requests sent with an Idempotency-Key header, replayed
+-- to retries of the same request. status is missing while the first request
+-- is still being handled.
+CREATE TABLE idempotency_keys (
+    user_id INT NOT NULL,
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status INT,
+    headers JSONB,
+    body BYTEA,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, key),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+
+-- Operations on tasks users can undo within the undo window, steps reverse
+-- the changes of single tasks.
+CREATE TABLE undo_entries (
+    token TEXT PRIMARY KEY,
+    user_id INT NOT NULL,
+    workspace_id INT NOT NULL,
+    action TEXT NOT NULL,
+    steps JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE
+);
+
+CREATE INDEX undo_entries_user_id_idx ON undo_entries (user_id, workspace_id, created_at);
+
+-- Task events are kept for a while for event streams resuming after
+-- a disconnect. There are no foreign keys, events outlive their tasks and are
+-- logged while workspaces are deleted. Ids are numbered per workspace and
+-- follow the order transactions commit in, see task_event_counters.
+CREATE TABLE task_event_log (
+    workspace_id INT NOT NULL,
+    id BIGINT NOT NULL,
+    task_id INT NOT NULL,
+    type TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, id)
+);
+
+CREATE INDEX task_event_log_created_at_idx ON task_event_log (created_at);
+
+-- The last id of events logged in each workspace. Events are logged as
+-- transactions commit, and the row of the workspace stays locked by the
+-- transaction which took its last id until it commits. Ids are thus never
+-- taken by a transaction which commits earlier than one holding a lower id of
+-- the workspace, so streams resuming after an id can't miss events committed
+-- later, while changes in other workspaces don't wait for each other.
+CREATE TABLE task_event_counters (
+    workspace_id INT PRIMARY KEY,
+    id BIGINT NOT NULL
+);
+
+-- log_task_event logs the event to task_event_log and announces it on the
+-- task_events channel to every server instance.
+CREATE FUNCTION log_task_event(event_workspace_id INT, event_task_id INT, event_type TEXT) RETURNS void AS $$
+DECLARE
+    event_id BIGINT;
+BEGIN
+    INSERT INTO task_event_counters (workspace_id, id) VALUES (event_workspace_id, 1)
+    ON CONFLICT (workspace_id) DO UPDATE SET id = task_event_counters.id + 1
+    RETURNING id INTO event_id;
+
+    INSERT INTO task_event_log (id, workspace_id, task_id, type)
+    VALUES (event_id, event_workspace_id, event_task_id, event_type);
+
+    PERFORM pg_notify('task_events', json_build_object(
+        'id', event_id,
+        'type', event_type,
+        'task_id', event_task_id,
+        'workspace_id', event_workspace_id
+    )::text);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Changes of tasks are logged as task events. Restored tasks are announced as
+-- created and trashed ones as deleted, changes of tasks in the trash and
+-- purges are left out. The trigger is deferred to the commit, which keeps the
+-- counter of the workspace locked only while the transaction commits.
+CREATE FUNCTION notify_task_event() RETURNS trigger AS $$
+DECLARE
+    event_type TEXT;
+    task RECORD;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        event_type := 'created';
+        task := NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NULL;
+        END IF;
+        event_type := 'deleted';
+        task := OLD;
+    ELSIF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+        event_type := 'deleted';
+        task := NEW;
+    ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+        event_type := 'created';
+        task := NEW;
+    ELSIF NEW.deleted_at IS NULL THEN
+        event_type := 'updated';
+        task := NEW;
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    PERFORM log_task_event(task.workspace_id, task.id, event_type);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER tasks_notify_event
+    AFTER INSERT OR UPDATE OR DELETE ON tasks
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION notify_task_event();
+
+-- Changes of assignees and values of custom fields are logged as updates of
+-- their task, subscriptions filtering by them are told when the task starts
+-- or stops matching. Tasks in the trash and purged ones are left out.
+CREATE FUNCTION notify_task_detail_event() RETURNS trigger AS $$
+DECLARE
+    changed_task_id INT;
+    task RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed_task_id := OLD.task_id;
+    ELSE
+        changed_task_id := NEW.task_id;
+    END IF;
+
+    SELECT id, workspace_id INTO task FROM tasks WHERE id = changed_task_id AND deleted_at IS NULL;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    PERFORM log_task_event(task.workspace_id, task.id, 'updated');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER task_assignees_notify_event
+    AFTER INSERT OR UPDATE OR DELETE ON task_assignees
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION notify_task_detail_event();
+
+CREATE CONSTRAINT TRIGGER task_field_values_notify_event
+    AFTER INSERT OR UPDATE OR DELETE ON task_field_values
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION notify_task_detail_event();
@@ -8,3 +8,6 @@ export DB_NAME=${DB_NAME:-task_manager}  # Name of the database to create
 export SCHEMA_FILE=${SCHEMA_FILE:-"./scripts/database/schema.sql"}  # Path to the SQL schema file
 
 export JWT_SECRET=${SCHEMA_FILE:-"super-mega-secret"} # Secret used to sign JWT tokens
+
+export BLOB_STORE=${BLOB_STORE:-local} # Attachments storage backend: local or s3
+export BLOB_DIR=${BLOB_DIR:-"./data/blobs"} # Directory for local attachments storage
@@ -0,0 +1,78 @@
+package query_test
+
+import (
+	"api-server/domain/query"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("Empty query", func(t *testing.T) {
+		node, err := query.Parse("   ")
+		assert.NoError(t, err)
+		assert.Nil(t, node)
+	})
+
+	t.Run("Terms joined by AND", func(t *testing.T) {
+		node, err := query.Parse(`status:"In progress" due<2026-11-01 label:urgent -label:blocked "release notes"`)
+		assert.NoError(t, err)
+		assert.Equal(t, query.And{Nodes: []query.Node{
+			query.Term{Field: "status", Op: ":", Value: "In progress", Pos: 1},
+			query.Term{Field: "due", Op: "<", Value: "2026-11-01", Pos: 22},
+			query.Term{Field: "label", Op: ":", Value: "urgent", Pos: 37},
+			query.Not{Node: query.Term{Field: "label", Op: ":", Value: "blocked", Pos: 51}},
+			query.Term{Value: "release notes", Phrase: true, Pos: 65},
+		}}, node)
+	})
+
+	t.Run("Precedence of OR, NOT and parentheses", func(t *testing.T) {
+		node, err := query.Parse("deploy OR NOT (priority>=high AND is:overdue) fix-up")
+		assert.NoError(t, err)
+		assert.Equal(t, query.Or{Nodes: []query.Node{
+			query.Term{Value: "deploy", Pos: 1},
+			query.And{Nodes: []query.Node{
+				query.Not{Node: query.And{Nodes: []query.Node{
+					query.Term{Field: "priority", Op: ">=", Value: "high", Pos: 16},
+					query.Term{Field: "is", Op: ":", Value: "overdue", Pos: 35},
+				}}},
+				query.Term{Value: "fix-up", Pos: 47},
+			}},
+		}}, node)
+	})
+
+	t.Run("Field names are case insensitive", func(t *testing.T) {
+		node, err := query.Parse("Assignee:me")
+		assert.NoError(t, err)
+		assert.Equal(t, query.Term{Field: "assignee", Op: ":", Value: "me", Pos: 1}, node)
+	})
+
+	t.Run("Positioned errors", func(t *testing.T) {
+		cases := map[string]query.SyntaxError{
+			`status:"In progress`:   {Pos: 8, Msg: "unterminated quoted string"},
+			"owner:me":              {Pos: 1, Msg: `unknown field "owner"`},
+			"label<urgent":          {Pos: 6, Msg: `operator "<" is not supported by field "label"`},
+			"due: today":            {Pos: 5, Msg: `expected a value after "due:"`},
+			"due<someday":           {Pos: 5, Msg: `invalid day "someday"`},
+			"priority:whenever":     {Pos: 10, Msg: `invalid priority "whenever"`},
+			"project:abc":           {Pos: 9, Msg: `invalid project id "abc"`},
+			"has:cake":              {Pos: 5, Msg: `invalid value of "has" "cake"`},
+			"(label:a OR label:b":   {Pos: 1, Msg: `missing closing ")"`},
+			"label:a)":              {Pos: 8, Msg: `unexpected ")"`},
+			"label:a OR":            {Pos: 11, Msg: "expected a term at the end of the query"},
+			"deploy -":              {Pos: 8, Msg: "nothing to search for"},
+			"NOT":                   {Pos: 1, Msg: `expected a term after "NOT"`},
+			"status=done":           {Pos: 7, Msg: `unexpected "=", use ":" to compare`},
+			"AND deploy":            {Pos: 1, Msg: `expected a term before "AND"`},
+			`deploy "" release`:     {Pos: 8, Msg: "nothing to search for"},
+			"label:a OR (label:b))": {Pos: 21, Msg: `unexpected ")"`},
+		}
+		for input, expected := range cases {
+			_, err := query.Parse(input)
+			var syntaxErr *query.SyntaxError
+			if assert.ErrorAs(t, err, &syntaxErr, input) {
+				assert.Equal(t, expected, *syntaxErr, input)
+			}
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignees(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	workspacesRepo := repos.NewWorkspacesRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	assigneesService := services.NewAssigneesService(repos.NewAssigneesRepo(conn), workspacesRepo, test_utils.NewAuthorizer(conn, tasksRepo))
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(workspacesRepo, jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+	routes.RegisterAssigneesRoutes(r, jwtAuth, workspaceResolver, assigneesService)
+
+	ownerCred := models.UserRegister{Email: "owner@test.com", Password: "whatever"}
+	ownerData, tasks := test_utils.CreateUserWithTasks(
+		ownerCred,
+		[]models.TaskData{{Name: "Assigned task", Status: "To do"}, {Name: "Unassigned task", Status: "To do"}},
+		userRepo,
+		tasksRepo,
+	)
+	memberCred := models.UserRegister{Email: "member@test.com", Password: "whatever"}
+	memberData, _ := test_utils.CreateUserWithTasks(memberCred, []models.TaskData{}, userRepo, tasksRepo)
+	strangerCred := models.UserRegister{Email: "stranger@test.com", Password: "whatever"}
+	strangerData, _ := test_utils.CreateUserWithTasks(strangerCred, []models.TaskData{}, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"tasks", "users"})
+
+	// member joins owner's personal workspace
+	workspace := test_utils.GetPersonalWorkspace(conn, ownerData.Id)
+	invitation, err := workspacesRepo.CreateInvitation(context.Background(), workspace.Id, memberCred.Email, models.WorkspaceRoleMember, ownerData.Id)
+	assert.Nil(t, err)
+	err = workspacesRepo.AcceptInvitation(context.Background(), invitation, memberData.Id)
+	assert.Nil(t, err)
+
+	doRequest := func(userCred models.UserRegister, method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		req.Header.Set(workspaceResolver.WorkspaceHeader, fmt.Sprint(workspace.Id))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	listTasks := func(userCred models.UserRegister, query string) []models.TaskListItem {
+		resp := doRequest(userCred, "GET", "/tasks/"+query, "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.CursorPage[models.TaskListItem]
+		err := json.Unmarshal(resp.Body.Bytes(), &page)
+		assert.Nil(t, err, resp.Body.String())
+		return page.Items
+	}
+	taskPath := fmt.Sprintf("/tasks/%d", tasks[0].Id)
+	assigneesPath := taskPath + "/assignees/"
+
+	t.Run("Bad request on invalid assignee filter", func(t *testing.T) {
+		resp := doRequest(ownerCred, "GET", "/tasks/?assignee=someone", "")
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Not found when assigning non-member", func(t *testing.T) {
+		resp := doRequest(ownerCred, "PUT", fmt.Sprintf("%s%d", assigneesPath, strangerData.Id), "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Unassigned member has no access", func(t *testing.T) {
+		assert.Equal(t, 0, len(listTasks(memberCred, "")))
+		resp := doRequest(memberCred, "PATCH", taskPath, `{"status": "Done"}`)
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Assign and unassign member", func(t *testing.T) {
+		assignPath := fmt.Sprintf("%s%d", assigneesPath, memberData.Id)
+		resp := doRequest(ownerCred, "PUT", assignPath, "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		// assigning twice is a no-op
+		resp = doRequest(ownerCred, "PUT", assignPath, "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		resp = doRequest(ownerCred, "GET", assigneesPath, "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var assignees []models.AssigneeData
+		err := json.Unmarshal(resp.Body.Bytes(), &assignees)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(assignees))
+		assert.Equal(t, memberData.Id, assignees[0].UserId)
+		assert.Equal(t, memberCred.Email, assignees[0].Email)
+		assert.Equal(t, ownerData.Id, assignees[0].AssignedBy)
+
+		// owner's listing reports assignees, assignee filter narrows it down
+		ownerTasks := listTasks(ownerCred, "")
+		assert.Equal(t, 2, len(ownerTasks))
+		assignedTasks := listTasks(ownerCred, fmt.Sprintf("?assignee=%d", memberData.Id))
+		assert.Equal(t, 1, len(assignedTasks))
+		assert.Equal(t, []int{memberData.Id}, assignedTasks[0].AssigneeIds)
+		assert.Equal(t, 0, len(listTasks(ownerCred, "?assignee=me")))
+
+		// assignee sees the task in their assigned tasks view
+		myTasks := listTasks(memberCred, "?assignee=me")
+		assert.Equal(t, 1, len(myTasks))
+		assert.Equal(t, tasks[0].Id, myTasks[0].Id)
+
+		// assignee may change status but not delete the task or manage assignees
+		resp = doRequest(memberCred, "PATCH", taskPath, `{"status": "Done"}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		resp = doRequest(memberCred, "DELETE", taskPath, "")
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+		resp = doRequest(memberCred, "PUT", fmt.Sprintf("%s%d", assigneesPath, ownerData.Id), "")
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+
+		// assignee unassigns themselves and loses access
+		resp = doRequest(memberCred, "DELETE", assignPath, "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		resp = doRequest(ownerCred, "DELETE", assignPath, "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+		assert.Equal(t, 0, len(listTasks(memberCred, "?assignee=me")))
+		resp = doRequest(memberCred, "PATCH", taskPath, `{"status": "To do"}`)
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+	})
+}
@@ -0,0 +1,195 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	"api-server/domain/storage"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pngContent is a file starting with PNG signature
+var pngContent = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+
+func multipartFile(fileName string, content []byte) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", fileName)
+	part.Write(content)
+	writer.Close()
+	return body, writer.FormDataContentType()
+}
+
+func TestAttachments(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	auth := test_utils.NewAuthorizer(conn, tasksRepo)
+	blobStore := storage.NewLocalBlobStore(t.TempDir())
+	attachmentsRepo := repos.NewAttachmentsRepo(conn)
+	attachmentsService := services.NewAttachmentsService(attachmentsRepo, auth, blobStore)
+	attachmentsService.MaxSize = 1024
+	workflowsService := services.NewWorkflowsService(repos.NewWorkflowsRepo(conn), auth)
+	tasksService := services.NewTasksService(
+		tasksRepo,
+		auth,
+		attachmentsService,
+		workflowsService,
+		services.NewCustomFieldsService(repos.NewCustomFieldsRepo(conn), auth),
+		test_utils.NewCursorCodec(),
+		services.NewUndoService(repos.NewUndoRepo(conn), auth, workflowsService),
+	)
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+	routes.RegisterAttachmentsRoutes(r, jwtAuth, workspaceResolver, attachmentsService)
+
+	// create tester user
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	token, _ := tp.Provide(userCred.Email)
+	userAuthHeader := fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token)
+
+	userData, _ := test_utils.CreateUserWithTasks(userCred, []models.TaskData{}, userRepo, tasksRepo)
+	workspace := test_utils.GetPersonalWorkspace(conn, userData.Id)
+	otherUserCred := models.UserRegister{Email: "other@other.com", Password: "whatever"}
+	_, otherTasks := test_utils.CreateUserWithTasks(otherUserCred, []models.TaskData{{Name: "Other task", Status: "Done"}}, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"task_attachments", "tasks", "users"})
+
+	upload := func(taskId int, fileName string, content []byte) *httptest.ResponseRecorder {
+		body, contentType := multipartFile(fileName, content)
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/tasks/%d/attachments/", taskId), body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Unauthorized on empty header", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/tasks/%d/attachments/", otherTasks[0].Id), nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		assert.Equal(t, 401, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Forbidden on someone else's task", func(t *testing.T) {
+		resp := upload(otherTasks[0].Id, "image.png", pngContent)
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Rejects invalid uploads", func(t *testing.T) {
+		task, err := tasksRepo.Create(context.Background(), workspace.Id, models.TaskCreate{Name: "Task"}, "To do", userData.Id)
+		assert.Nil(t, err)
+
+		// no file
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/tasks/%d/attachments/", task.Id), nil)
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		// too large
+		resp = upload(task.Id, "large.png", append(pngContent, bytes.Repeat([]byte{0}, 2048)...))
+		assert.Equal(t, 413, resp.Code, resp.Body.String())
+
+		// type is sniffed from content, not from file name
+		resp = upload(task.Id, "image.png", []byte{0x00, 0x01, 0x02, 0xff, 0xfe})
+		assert.Equal(t, 415, resp.Code, resp.Body.String())
+
+		attachments, err := attachmentsRepo.ListByTaskId(context.Background(), task.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(attachments), attachments)
+	})
+
+	t.Run("Upload, download and delete", func(t *testing.T) {
+		task, err := tasksRepo.Create(context.Background(), workspace.Id, models.TaskCreate{Name: "Task"}, "To do", userData.Id)
+		assert.Nil(t, err)
+
+		resp := upload(task.Id, "../../screenshot.png", pngContent)
+		assert.Equal(t, 201, resp.Code, resp.Body.String())
+
+		var attachment models.AttachmentData
+		err = json.Unmarshal(resp.Body.Bytes(), &attachment)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, "screenshot.png", attachment.FileName)
+		assert.Equal(t, "image/png", attachment.ContentType)
+		assert.Equal(t, int64(len(pngContent)), attachment.Size)
+		assert.Equal(t, userData.Id, attachment.UserId)
+
+		attachmentPath := fmt.Sprintf("/tasks/%d/attachments/%d", task.Id, attachment.Id)
+		req, _ := http.NewRequest("GET", attachmentPath, nil)
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Equal(t, pngContent, resp.Body.Bytes())
+		assert.Equal(t, "image/png", resp.Header().Get("Content-Type"))
+		assert.Contains(t, resp.Header().Get("Content-Disposition"), "screenshot.png")
+
+		req, _ = http.NewRequest("DELETE", attachmentPath, nil)
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		attachmentDb, err := attachmentsRepo.ListByTaskId(context.Background(), task.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(attachmentDb))
+		_, err = blobStore.Get(context.Background(), attachment.BlobKey)
+		assert.Equal(t, storage.ErrBlobNotFound, err)
+	})
+
+	t.Run("Purging task removes attachments", func(t *testing.T) {
+		task, err := tasksRepo.Create(context.Background(), workspace.Id, models.TaskCreate{Name: "Task"}, "To do", userData.Id)
+		assert.Nil(t, err)
+
+		resp := upload(task.Id, "doc.txt", []byte("release notes"))
+		assert.Equal(t, 201, resp.Code, resp.Body.String())
+		attachments, err := attachmentsRepo.ListByTaskId(context.Background(), task.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(attachments))
+
+		req, _ := http.NewRequest("DELETE", fmt.Sprintf("/tasks/%d", task.Id), nil)
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		// trashed tasks can be restored, their attachments stay
+		blob, err := blobStore.Get(context.Background(), attachments[0].BlobKey)
+		assert.Nil(t, err)
+		blob.Close()
+
+		req, _ = http.NewRequest("DELETE", fmt.Sprintf("/tasks/trash/%d", task.Id), nil)
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		_, err = blobStore.Get(context.Background(), attachments[0].BlobKey)
+		assert.Equal(t, storage.ErrBlobNotFound, err)
+	})
+}
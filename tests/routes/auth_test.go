@@ -282,6 +282,13 @@ func TestWhoAmI(t *testing.T) {
 		r.ServeHTTP(resp, req)
 
 		assert.Equal(t, 401, resp.Code, resp.Body.String())
+
+		// prefix without token
+		req.Header.Set(jwtAuth.AuthHeader, jwtAuth.AuthHeaderPrefix)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		assert.Equal(t, 401, resp.Code, resp.Body.String())
 	})
 
 	t.Run("Unauthorized on correct header value, but non existing user", func(t *testing.T) {
@@ -0,0 +1,179 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkTasks(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	workspacesRepo := repos.NewWorkspacesRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(workspacesRepo, jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+
+	ownerCred := models.UserRegister{Email: "owner@test.com", Password: "whatever"}
+	ownerData, _ := test_utils.CreateUserWithTasks(ownerCred, nil, userRepo, tasksRepo)
+	memberCred := models.UserRegister{Email: "member@test.com", Password: "whatever"}
+	memberData, _ := test_utils.CreateUserWithTasks(memberCred, nil, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"tasks", "projects", "users"})
+
+	// member joins owner's personal workspace
+	workspace := test_utils.GetPersonalWorkspace(conn, ownerData.Id)
+	invitation, err := workspacesRepo.CreateInvitation(context.Background(), workspace.Id, memberCred.Email, models.WorkspaceRoleMember, ownerData.Id)
+	assert.Nil(t, err)
+	err = workspacesRepo.AcceptInvitation(context.Background(), invitation, memberData.Id)
+	assert.Nil(t, err)
+	project, err := repos.NewProjectsRepo(conn).Create(context.Background(), workspace.Id, "Cleanup", ownerData.Id)
+	assert.Nil(t, err)
+
+	doRequest := func(userCred models.UserRegister, method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		req.Header.Set(workspaceResolver.WorkspaceHeader, fmt.Sprint(workspace.Id))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	createTask := func(userCred models.UserRegister, name string) models.TaskData {
+		resp := doRequest(userCred, "POST", "/tasks/", fmt.Sprintf(`{"name": "%s"}`, name))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var task models.TaskData
+		json.Unmarshal(resp.Body.Bytes(), &task)
+		return task
+	}
+	bulk := func(body string) models.BulkTasksResult {
+		resp := doRequest(ownerCred, "POST", "/tasks/bulk", body)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var result models.BulkTasksResult
+		err := json.Unmarshal(resp.Body.Bytes(), &result)
+		assert.Nil(t, err, resp.Body.String())
+		return result
+	}
+	listTasks := func(query url.Values) []models.TaskListItem {
+		resp := doRequest(ownerCred, "GET", "/tasks/?"+query.Encode(), "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.CursorPage[models.TaskListItem]
+		json.Unmarshal(resp.Body.Bytes(), &page)
+		return page.Items
+	}
+	taskNames := func(tasks []models.TaskListItem) []string {
+		return test_utils.Map(tasks, func(t models.TaskListItem) string { return t.Name })
+	}
+
+	a, b, c := createTask(ownerCred, "a"), createTask(ownerCred, "b"), createTask(ownerCred, "c")
+	memberTask := createTask(memberCred, "member's")
+	missingId := memberTask.Id + 1000
+
+	t.Run("Bad request on invalid request", func(t *testing.T) {
+		for _, body := range []string{
+			`{"action": "delete"}`,
+			`{"ids": [1], "filter": {}, "action": "delete"}`,
+			`{"ids": [1, 1], "action": "delete"}`,
+			`{"ids": [1], "action": "archive"}`,
+			`{"ids": [1], "action": "set_status"}`,
+			`{"ids": [1], "action": "edit_labels"}`,
+			`{"filter": {"query": "owner:me"}, "action": "delete"}`,
+		} {
+			resp := doRequest(ownerCred, "POST", "/tasks/bulk", body)
+			assert.Equal(t, 400, resp.Code, body)
+		}
+	})
+
+	t.Run("Dry run changes nothing", func(t *testing.T) {
+		body := fmt.Sprintf(`{"ids": [%d, %d, %d], "action": "delete", "dry_run": true}`, a.Id, memberTask.Id, missingId)
+		result := bulk(body)
+		assert.True(t, result.DryRun)
+		assert.Equal(t, []models.BulkTaskResult{
+			{Id: a.Id, Result: models.BulkResultOk},
+			{Id: memberTask.Id, Result: models.BulkResultForbidden},
+			{Id: missingId, Result: models.BulkResultNotFound},
+		}, result.Results)
+		assert.Equal(t, []string{"a", "b", "c"}, taskNames(listTasks(url.Values{})))
+	})
+
+	t.Run("Set status of listed tasks", func(t *testing.T) {
+		result := bulk(fmt.Sprintf(`{"ids": [%d, %d], "action": "set_status", "status": "Nonexistent"}`, a.Id, b.Id))
+		assert.Equal(t, models.BulkResultInvalid, result.Results[0].Result)
+		assert.NotEmpty(t, result.Results[0].Error)
+
+		result = bulk(fmt.Sprintf(`{"ids": [%d, %d, %d], "action": "set_status", "status": "In progress"}`, a.Id, b.Id, memberTask.Id))
+		assert.Equal(t, []string{models.BulkResultOk, models.BulkResultOk, models.BulkResultForbidden},
+			test_utils.Map(result.Results, func(r models.BulkTaskResult) string { return r.Result }))
+		assert.Equal(t, []string{"a", "b"}, taskNames(listTasks(url.Values{"status": {"In progress"}})))
+	})
+
+	t.Run("Edit labels of filtered tasks", func(t *testing.T) {
+		bulk(fmt.Sprintf(`{"ids": [%d], "action": "edit_labels", "add_labels": ["stale", "keep"]}`, a.Id))
+
+		result := bulk(`{"filter": {"status": "In progress"}, "action": "edit_labels", "add_labels": ["cleanup"], "remove_labels": ["stale"]}`)
+		assert.Equal(t, []int{a.Id, b.Id}, test_utils.Map(result.Results, func(r models.BulkTaskResult) int { return r.Id }))
+
+		tasks := listTasks(url.Values{"status": {"In progress"}})
+		assert.Equal(t, []string{"keep", "cleanup"}, tasks[0].Labels)
+		assert.Equal(t, []string{"cleanup"}, tasks[1].Labels)
+	})
+
+	t.Run("Move tasks to project and back", func(t *testing.T) {
+		bulk(fmt.Sprintf(`{"ids": [%d, %d], "action": "move", "project_id": %d}`, a.Id, c.Id, project.Id))
+		assert.Equal(t, []string{"a", "c"}, taskNames(listTasks(url.Values{"project_id": {fmt.Sprint(project.Id)}})))
+
+		bulk(fmt.Sprintf(`{"ids": [%d], "action": "move", "project_id": null}`, c.Id))
+		assert.Equal(t, []string{"a"}, taskNames(listTasks(url.Values{"project_id": {fmt.Sprint(project.Id)}})))
+
+		resp := doRequest(ownerCred, "POST", "/tasks/bulk", fmt.Sprintf(`{"ids": [%d], "action": "move", "project_id": %d}`, a.Id, project.Id+1000))
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Changes of tasks changed since they were read are skipped", func(t *testing.T) {
+		current, err := tasksRepo.GetById(context.Background(), workspace.Id, c.Id)
+		assert.Nil(t, err)
+
+		status := "Done"
+		applied, skipped, err := tasksRepo.ApplyChanges(context.Background(), workspace.Id, ownerData.Id, []models.TaskChange{
+			{Id: c.Id, BaseVersion: current.Version - 1, Status: &status},
+			{Id: missingId, BaseVersion: 1, Delete: true},
+		})
+		assert.Nil(t, err)
+		assert.Empty(t, applied)
+		assert.Equal(t, map[int]error{c.Id: repos.ErrVersionMismatch, missingId: repos.ErrNotFound}, skipped)
+
+		unchanged, err := tasksRepo.GetById(context.Background(), workspace.Id, c.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, current, unchanged)
+	})
+
+	t.Run("Delete tasks", func(t *testing.T) {
+		result := bulk(fmt.Sprintf(`{"ids": [%d, %d], "action": "delete"}`, a.Id, b.Id))
+		assert.False(t, result.DryRun)
+		assert.Equal(t, []string{"c"}, taskNames(listTasks(url.Values{})))
+	})
+}
@@ -0,0 +1,161 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComments(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	commentsRepo := repos.NewCommentsRepo(conn)
+	commentsService := services.NewCommentsService(commentsRepo, test_utils.NewAuthorizer(conn, tasksRepo))
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+	routes.RegisterCommentsRoutes(r, jwtAuth, workspaceResolver, commentsService)
+
+	// create tester user
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	token, _ := tp.Provide(userCred.Email)
+	userAuthHeader := fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token)
+
+	userData, tasks := test_utils.CreateUserWithTasks(
+		userCred,
+		[]models.TaskData{{Name: "Discussed task", Status: "To do"}},
+		userRepo,
+		tasksRepo,
+	)
+	otherUserCred := models.UserRegister{Email: "other@other.com", Password: "whatever"}
+	otherUser, otherTasks := test_utils.CreateUserWithTasks(otherUserCred, []models.TaskData{{Name: "Other task", Status: "Done"}}, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"task_comments", "tasks", "users"})
+
+	doRequest := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	commentsPath := fmt.Sprintf("/tasks/%d/comments/", tasks[0].Id)
+
+	t.Run("Unauthorized on empty header", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", commentsPath, nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		assert.Equal(t, 401, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Bad request on invalid input", func(t *testing.T) {
+		resp := doRequest("POST", commentsPath, `{"body": ""}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		resp = doRequest("POST", commentsPath, `{"body": " \n\t "}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		resp = doRequest("PATCH", commentsPath+"1", `{"body": "   "}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		resp = doRequest("GET", commentsPath+"?per_page=1000", "")
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		resp = doRequest("PATCH", commentsPath+"abcd", `{"body": "text"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Forbidden on someone else's task", func(t *testing.T) {
+		resp := doRequest("POST", fmt.Sprintf("/tasks/%d/comments/", otherTasks[0].Id), `{"body": "hi"}`)
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Forbidden on someone else's comment", func(t *testing.T) {
+		defer utils.TruncateTables(conn, []string{"task_comments"})
+
+		comment, err := commentsRepo.Create(context.Background(), tasks[0].Id, otherUser.Id, "not yours")
+		assert.Nil(t, err)
+
+		resp := doRequest("PATCH", fmt.Sprintf("%s%d", commentsPath, comment.Id), `{"body": "edited"}`)
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+
+		resp = doRequest("DELETE", fmt.Sprintf("%s%d", commentsPath, comment.Id), "")
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		defer utils.TruncateTables(conn, []string{"task_comments"})
+
+		created := make([]models.CommentData, 0, 3)
+		for i := 0; i < 3; i++ {
+			resp := doRequest("POST", commentsPath, fmt.Sprintf(`{"body": "Comment %d"}`, i))
+			assert.Equal(t, 201, resp.Code, resp.Body.String())
+
+			var comment models.CommentData
+			err := json.Unmarshal(resp.Body.Bytes(), &comment)
+			assert.Nil(t, err, resp.Body.String())
+			assert.Equal(t, userData.Id, comment.AuthorId)
+			assert.Equal(t, userCred.Email, comment.AuthorEmail)
+			assert.Nil(t, comment.EditedAt)
+			created = append(created, comment)
+		}
+
+		// edit
+		resp := doRequest("PATCH", fmt.Sprintf("%s%d", commentsPath, created[0].Id), `{"body": "  Edited\n"}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var edited models.CommentData
+		err := json.Unmarshal(resp.Body.Bytes(), &edited)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, "Edited", edited.Body)
+		assert.NotNil(t, edited.EditedAt)
+
+		// delete
+		resp = doRequest("DELETE", fmt.Sprintf("%s%d", commentsPath, created[1].Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		resp = doRequest("DELETE", fmt.Sprintf("%s%d", commentsPath, created[1].Id), "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+
+		// paginated list skips deleted comment
+		resp = doRequest("GET", commentsPath+"?per_page=1&page=2", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.Page[models.CommentData]
+		err = json.Unmarshal(resp.Body.Bytes(), &page)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, 2, page.Total)
+		assert.Equal(t, 1, len(page.Items), page.Items)
+		assert.Equal(t, created[2].Id, page.Items[0].Id)
+
+		// comment count in task list
+		resp = doRequest("GET", "/tasks/", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var tasksPage models.CursorPage[models.TaskListItem]
+		err = json.Unmarshal(resp.Body.Bytes(), &tasksPage)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, 1, len(tasksPage.Items), tasksPage.Items)
+		assert.Equal(t, 2, tasksPage.Items[0].CommentsCount)
+	})
+}
@@ -0,0 +1,196 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomFields(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	auth := test_utils.NewAuthorizer(conn, tasksRepo)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	projectsService := services.NewProjectsService(repos.NewProjectsRepo(conn), auth)
+	fieldsService := services.NewCustomFieldsService(repos.NewCustomFieldsRepo(conn), auth)
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+	routes.RegisterProjectsRoutes(r, jwtAuth, workspaceResolver, projectsService)
+	routes.RegisterCustomFieldsRoutes(r, jwtAuth, workspaceResolver, fieldsService)
+
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	_, ownTasks := test_utils.CreateUserWithTasks(userCred, []models.TaskData{{Name: "No project", Status: "To do"}}, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"tasks", "projects", "users"})
+
+	doRequest := func(method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	listTasks := func(query url.Values) []models.TaskListItem {
+		resp := doRequest("GET", "/tasks/?"+query.Encode(), "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.CursorPage[models.TaskListItem]
+		err := json.Unmarshal(resp.Body.Bytes(), &page)
+		assert.Nil(t, err, resp.Body.String())
+		return page.Items
+	}
+	taskNames := func(tasks []models.TaskListItem) []string {
+		return test_utils.Map(tasks, func(t models.TaskListItem) string { return t.Name })
+	}
+
+	resp := doRequest("POST", "/projects/", `{"name": "Sprint"}`)
+	assert.Equal(t, 201, resp.Code, resp.Body.String())
+	var project models.ProjectData
+	json.Unmarshal(resp.Body.Bytes(), &project)
+	fieldsPath := fmt.Sprintf("/projects/%d/fields/", project.Id)
+
+	createField := func(body string) models.CustomFieldData {
+		resp := doRequest("POST", fieldsPath, body)
+		assert.Equal(t, 201, resp.Code, resp.Body.String())
+		var field models.CustomFieldData
+		json.Unmarshal(resp.Body.Bytes(), &field)
+		return field
+	}
+	points := createField(`{"name": "Story points", "type": "number"}`)
+	customer := createField(`{"name": "Customer", "type": "select", "options": ["Acme", "Globex"]}`)
+	labels := createField(`{"name": "Labels", "type": "multi_select", "options": ["backend", "frontend"]}`)
+	createField(`{"name": "Released", "type": "checkbox"}`)
+
+	var tasks []models.TaskData
+	for _, name := range []string{"Task 1", "Task 2", "Task 3"} {
+		resp := doRequest("POST", "/tasks/", fmt.Sprintf(`{"name": "%s", "project_id": %d}`, name, project.Id))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var task models.TaskData
+		json.Unmarshal(resp.Body.Bytes(), &task)
+		tasks = append(tasks, task)
+	}
+	setValue := func(task models.TaskData, field models.CustomFieldData, value string) *httptest.ResponseRecorder {
+		return doRequest("PUT", fmt.Sprintf("/tasks/%d/fields/%d", task.Id, field.Id), fmt.Sprintf(`{"value": %s}`, value))
+	}
+
+	t.Run("Bad request on invalid field definition", func(t *testing.T) {
+		resp := doRequest("POST", fieldsPath, `{"name": "Size", "type": "unknown"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		resp = doRequest("POST", fieldsPath, `{"name": "Size", "type": "select"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+		assert.Contains(t, resp.Body.String(), "Key: 'CustomFieldCreate.Options'")
+
+		resp = doRequest("POST", fieldsPath, `{"name": "Size", "type": "text", "options": ["S"]}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		resp = doRequest("POST", fieldsPath, `{"name": "Story points", "type": "text"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		resp = doRequest("GET", fieldsPath, "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var fields []models.CustomFieldData
+		json.Unmarshal(resp.Body.Bytes(), &fields)
+		assert.Equal(t, 4, len(fields))
+	})
+
+	t.Run("Bad request on invalid value", func(t *testing.T) {
+		resp := setValue(tasks[0], points, `"many"`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+		assert.Contains(t, resp.Body.String(), "failed on the 'number' tag")
+
+		resp = setValue(tasks[0], customer, `"Initech"`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+		assert.Contains(t, resp.Body.String(), "failed on the 'oneof' tag")
+
+		resp = setValue(tasks[0], labels, `["backend", "backend"]`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		resp = setValue(tasks[0], points, `null`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		// fields only apply to tasks of their project
+		resp = setValue(ownTasks[0], points, `1`)
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Values can be filtered and sorted", func(t *testing.T) {
+		for _, resp := range []*httptest.ResponseRecorder{
+			setValue(tasks[0], points, `5`),
+			setValue(tasks[0], customer, `"Acme"`),
+			setValue(tasks[0], labels, `["backend", "frontend"]`),
+			setValue(tasks[1], points, `3`),
+			setValue(tasks[1], labels, `["frontend"]`),
+		} {
+			assert.Equal(t, 200, resp.Code, resp.Body.String())
+		}
+
+		resp := doRequest("GET", fmt.Sprintf("/tasks/%d/fields/", tasks[0].Id), "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var values []models.FieldValueData
+		json.Unmarshal(resp.Body.Bytes(), &values)
+		assert.Equal(t, 3, len(values))
+		assert.Equal(t, float64(5), values[0].Value)
+
+		filter := func(field models.CustomFieldData, value string) url.Values {
+			return url.Values{"fields": {fmt.Sprintf(`{"%d": "%s"}`, field.Id, value)}}
+		}
+		assert.Equal(t, []string{"Task 1"}, taskNames(listTasks(filter(customer, "Acme"))))
+		assert.Equal(t, []string{"Task 1"}, taskNames(listTasks(filter(points, "5.0"))))
+		assert.ElementsMatch(t, []string{"Task 1", "Task 2"}, taskNames(listTasks(filter(labels, "frontend"))))
+
+		sorted := listTasks(url.Values{"sort": {fmt.Sprintf("fields.%d", points.Id)}, "project_id": {fmt.Sprint(project.Id)}})
+		assert.Equal(t, []string{"Task 2", "Task 1", "Task 3"}, taskNames(sorted))
+		assert.Equal(t, float64(3), sorted[0].Fields[fmt.Sprint(points.Id)])
+		sorted = listTasks(url.Values{"sort": {fmt.Sprintf("-fields.%d", points.Id)}, "project_id": {fmt.Sprint(project.Id)}})
+		assert.Equal(t, []string{"Task 1", "Task 2", "Task 3"}, taskNames(sorted))
+
+		for _, query := range []url.Values{
+			filter(points, "many"),
+			filter(customer, "Initech"),
+			{"fields": {`{"999999": "x"}`}},
+			{"fields": {`{"abc": "x"}`}},
+			{"sort": {"fields.999999"}},
+			{"sort": {"unknown"}},
+		} {
+			resp := doRequest("GET", "/tasks/?"+query.Encode(), "")
+			assert.Equal(t, 400, resp.Code, query.Encode())
+		}
+	})
+
+	t.Run("Delete value and field", func(t *testing.T) {
+		valuePath := fmt.Sprintf("/tasks/%d/fields/%d", tasks[1].Id, points.Id)
+		resp := doRequest("DELETE", valuePath, "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		resp = doRequest("DELETE", valuePath, "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+
+		resp = doRequest("DELETE", fmt.Sprintf("%s%d", fieldsPath, customer.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		resp = setValue(tasks[0], customer, `"Acme"`)
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+}
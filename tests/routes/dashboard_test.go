@@ -9,6 +9,7 @@ import (
 	"api-server/domain/services"
 	test_utils "api-server/tests/utils"
 	"api-server/utils"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -30,12 +31,18 @@ func TestDashboard(t *testing.T) {
 	userRepo := repos.NewUsersRepo(conn)
 
 	tasksRepo := repos.NewTasksRepo(conn)
-	tasksService := services.NewTasksService(tasksRepo)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	taskEvents := services.NewTaskEventsHub(repos.NewTaskEventsRepo(conn))
+	ctx, stopEvents := context.WithCancel(context.Background())
+	defer stopEvents()
+	go taskEvents.Run(ctx)
 
 	jwtCookieAuth := middlewares.NewJwtCookieAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtCookieAuth.AuthCtxKey)
 
 	utils.RegisterValidators()
-	routes.RegisterDashboardRoute(r, jwtCookieAuth, tasksService)
+	connectionTracker := middlewares.NewConnectionTracker(jwtCookieAuth.AuthCtxKey)
+	routes.RegisterDashboardRoute(r, jwtCookieAuth, workspaceResolver, connectionTracker, tasksService, taskEvents)
 
 	// Start a test server
 	server := httptest.NewServer(r)
@@ -53,7 +60,7 @@ func TestDashboard(t *testing.T) {
 	header := http.Header{"Cookie": {fmt.Sprintf("%s=%s", jwtCookieAuth.AuthCookieKey, token)}}
 
 	timeNow := time.Now().UTC()
-	_, tasks := test_utils.CreateUserWithTasks(
+	userData, tasks := test_utils.CreateUserWithTasks(
 		userCred,
 		[]models.TaskData{
 			{Name: "Task 1", DueDate: &timeNow, Status: "To do"},
@@ -75,8 +82,9 @@ func TestDashboard(t *testing.T) {
 
 	t.Run("Successful connection with auth cookie", func(t *testing.T) {
 		header := http.Header{"Cookie": {fmt.Sprintf("%s=%s", jwtCookieAuth.AuthCookieKey, token)}}
-		_, _, err := websocket.DefaultDialer.Dial(u.String(), header)
+		wsConn, _, err := websocket.DefaultDialer.Dial(u.String(), header)
 		assert.NoError(t, err)
+		wsConn.Close()
 	})
 
 	t.Run("Error on invalid filters", func(t *testing.T) {
@@ -125,11 +133,11 @@ func TestDashboard(t *testing.T) {
 		assert.NoError(t, err)
 		_, resp, err := wsConn.ReadMessage()
 		assert.NoError(t, err)
-		var tasksResp []models.TaskData
-		err = json.Unmarshal(resp, &tasksResp)
+		var page models.CursorPage[models.TaskData]
+		err = json.Unmarshal(resp, &page)
 		assert.NoError(t, err, string(resp))
-		assert.Equal(t, 4, len(tasksResp), tasksResp)
-		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks), test_utils.MapTasksToName(tasksResp))
+		assert.Equal(t, 4, len(page.Items), page.Items)
+		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks), test_utils.MapTasksToName(page.Items))
 
 		// query
 		query := "Task"
@@ -140,10 +148,10 @@ func TestDashboard(t *testing.T) {
 		assert.NoError(t, err)
 		_, resp, err = wsConn.ReadMessage()
 		assert.NoError(t, err)
-		err = json.Unmarshal(resp, &tasksResp)
+		err = json.Unmarshal(resp, &page)
 		assert.NoError(t, err)
-		assert.Equal(t, 3, len(tasksResp), tasksResp)
-		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:3]), test_utils.MapTasksToName(tasksResp))
+		assert.Equal(t, 3, len(page.Items), page.Items)
+		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:3]), test_utils.MapTasksToName(page.Items))
 
 		// query + due date
 		dueDate := timeNow.Format(utils.DayDateFmt)
@@ -154,10 +162,19 @@ func TestDashboard(t *testing.T) {
 		assert.NoError(t, err)
 		_, resp, err = wsConn.ReadMessage()
 		assert.NoError(t, err)
-		err = json.Unmarshal(resp, &tasksResp)
+		err = json.Unmarshal(resp, &page)
 		assert.NoError(t, err)
-		assert.Equal(t, 2, len(tasksResp), tasksResp)
-		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:2]), test_utils.MapTasksToName(tasksResp))
+		assert.Equal(t, 2, len(page.Items), page.Items)
+		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:2]), test_utils.MapTasksToName(page.Items))
+
+		// legacy clients send the due date as dues_date
+		err = wsConn.WriteMessage(websocket.BinaryMessage, []byte(fmt.Sprintf(`{"q": "Task", "dues_date": "%s"}`, dueDate)))
+		assert.NoError(t, err)
+		_, resp, err = wsConn.ReadMessage()
+		assert.NoError(t, err)
+		err = json.Unmarshal(resp, &page)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:2]), test_utils.MapTasksToName(page.Items))
 
 		// query + due date + status
 		status := "To do"
@@ -168,9 +185,334 @@ func TestDashboard(t *testing.T) {
 		assert.NoError(t, err)
 		_, resp, err = wsConn.ReadMessage()
 		assert.NoError(t, err)
-		err = json.Unmarshal(resp, &tasksResp)
+		err = json.Unmarshal(resp, &page)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(page.Items), page.Items)
+		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:1]), test_utils.MapTasksToName(page.Items))
+	})
+	t.Run("Push changes of matching tasks", func(t *testing.T) {
+		<-taskEvents.Ready()
+		wsConn, _, err := websocket.DefaultDialer.Dial(u.String(), header)
+		assert.NoError(t, err)
+		defer wsConn.Close()
+
+		status := "To do"
+		reqBytes, _ := json.Marshal(models.TasksFilter{Status: &status})
+		err = wsConn.WriteMessage(websocket.BinaryMessage, reqBytes)
+		assert.NoError(t, err)
+		_, resp, err := wsConn.ReadMessage()
+		assert.NoError(t, err)
+		var page models.CursorPage[models.TaskData]
+		err = json.Unmarshal(resp, &page)
+		assert.NoError(t, err, string(resp))
+		assert.Equal(t, 2, len(page.Items), page.Items)
+
+		readEvent := func() models.TaskEventMessage {
+			wsConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, resp, err := wsConn.ReadMessage()
+			assert.NoError(t, err)
+			var message models.TaskEventMessage
+			err = json.Unmarshal(resp, &message)
+			assert.NoError(t, err, string(resp))
+			return message
+		}
+
+		// changes made outside of the connection, as through any other instance
+		workspace := test_utils.GetPersonalWorkspace(conn, userData.Id)
+		created, err := tasksRepo.Create(context.Background(), workspace.Id, models.TaskCreate{Name: "Pushed"}, "To do", userData.Id)
+		assert.NoError(t, err)
+
+		message := readEvent()
+		assert.Equal(t, models.TaskEventCreated, message.Event)
+		assert.Equal(t, created.Id, message.TaskId)
+		assert.Equal(t, "Pushed", message.Task.Name)
+
+		// tasks of other statuses are left out, until they start matching
+		_, err = tasksRepo.UpdateStatus(context.Background(), workspace.Id, tasks[1].Id, tasks[1].Status, "Done", nil, userData.Id)
+		assert.NoError(t, err)
+		_, err = tasksRepo.UpdateStatus(context.Background(), workspace.Id, created.Id, created.Status, "In progress", nil, userData.Id)
+		assert.NoError(t, err)
+
+		message = readEvent()
+		assert.Equal(t, models.TaskEventDeleted, message.Event)
+		assert.Equal(t, created.Id, message.TaskId)
+		assert.Nil(t, message.Task)
+
+		_, err = tasksRepo.Trash(context.Background(), workspace.Id, tasks[0].Id, nil, userData.Id)
+		assert.NoError(t, err)
+		message = readEvent()
+		assert.Equal(t, models.TaskEventDeleted, message.Event)
+		assert.Equal(t, tasks[0].Id, message.TaskId)
+	})
+	t.Run("Legacy errors are JSON", func(t *testing.T) {
+		wsConn, _, err := websocket.DefaultDialer.Dial(u.String(), header)
+		assert.NoError(t, err)
+		defer wsConn.Close()
+		assert.Equal(t, "", wsConn.Subprotocol())
+
+		expr := "status=done"
+		reqBytes, _ := json.Marshal(models.TasksFilter{Expr: &expr})
+		err = wsConn.WriteMessage(websocket.TextMessage, reqBytes)
+		assert.NoError(t, err)
+		_, message, err := wsConn.ReadMessage()
+		assert.NoError(t, err)
+		var errorMessage map[string]string
+		err = json.Unmarshal(message, &errorMessage)
+		assert.NoError(t, err, string(message))
+		assert.Contains(t, errorMessage["error"], `"="`)
+	})
+
+	t.Run("Versioned protocol", func(t *testing.T) {
+		dialer := websocket.Dialer{Subprotocols: []string{models.DashboardProtocolV1}}
+		wsConn, _, err := dialer.Dial(u.String(), header)
+		assert.NoError(t, err)
+		defer wsConn.Close()
+		assert.Equal(t, models.DashboardProtocolV1, wsConn.Subprotocol())
+
+		request := func(message string) models.DashboardMessage {
+			err := wsConn.WriteMessage(websocket.TextMessage, []byte(message))
+			assert.NoError(t, err)
+			mt, resp, err := wsConn.ReadMessage()
+			assert.NoError(t, err)
+			assert.Equal(t, websocket.TextMessage, mt)
+			var envelope models.DashboardMessage
+			err = json.Unmarshal(resp, &envelope)
+			assert.NoError(t, err, string(resp))
+			assert.Equal(t, models.DashboardVersion, envelope.Version)
+			return envelope
+		}
+		errorOf := func(envelope models.DashboardMessage) models.DashboardErrorData {
+			assert.Equal(t, models.DashboardError, envelope.Type)
+			var errorData models.DashboardErrorData
+			err := json.Unmarshal(envelope.Data, &errorData)
+			assert.NoError(t, err)
+			return errorData
+		}
+
+		envelope := request(`{"v": 1, "type": "subscribe", "id": "1", "data": {"status": "Done"}}`)
+		assert.Equal(t, models.DashboardTasks, envelope.Type)
+		assert.Equal(t, "1", envelope.Id)
+		var page models.CursorPage[models.TaskData]
+		err = json.Unmarshal(envelope.Data, &page)
+		assert.NoError(t, err)
+		// Task 2 was moved to Done by the push test
+		assert.ElementsMatch(t, []string{"Task 2", "Another 3"}, test_utils.MapTasksToName(page.Items))
+
+		envelope = request(`{"v": 1, "type": "subscribe", "id": "2", "data": {"query": "status=done"}}`)
+		assert.Equal(t, "2", envelope.Id)
+		errorData := errorOf(envelope)
+		assert.Equal(t, models.DashboardErrInvalidFilter, errorData.Code)
+		assert.Contains(t, errorData.Message, `"="`)
+
+		envelope = request(`{"v": 1, "type": "unsubscribe", "id": "3"}`)
+		assert.Equal(t, "3", envelope.Id)
+		assert.Equal(t, models.DashboardErrUnsupported, errorOf(envelope).Code)
+
+		envelope = request(`{"v": 2, "type": "subscribe", "id": "4"}`)
+		assert.Equal(t, "4", envelope.Id)
+		assert.Equal(t, models.DashboardErrBadMessage, errorOf(envelope).Code)
+
+		envelope = request(`not json`)
+		assert.Equal(t, "", envelope.Id)
+		assert.Equal(t, models.DashboardErrBadMessage, errorOf(envelope).Code)
+	})
+	t.Run("Task operations", func(t *testing.T) {
+		<-taskEvents.Ready()
+		dialer := websocket.Dialer{Subprotocols: []string{models.DashboardProtocolV1}}
+		// another connection of the same user watches the changes
+		watcher, _, err := dialer.Dial(u.String(), header)
+		assert.NoError(t, err)
+		defer watcher.Close()
+		wsConn, _, err := dialer.Dial(u.String(), header)
+		assert.NoError(t, err)
+		defer wsConn.Close()
+
+		read := func(conn *websocket.Conn) models.DashboardMessage {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, resp, err := conn.ReadMessage()
+			assert.NoError(t, err)
+			var envelope models.DashboardMessage
+			err = json.Unmarshal(resp, &envelope)
+			assert.NoError(t, err, string(resp))
+			return envelope
+		}
+		request := func(message string) models.DashboardMessage {
+			err := wsConn.WriteMessage(websocket.TextMessage, []byte(message))
+			assert.NoError(t, err)
+			return read(wsConn)
+		}
+		ackOf := func(envelope models.DashboardMessage) models.DashboardTaskResult {
+			assert.Equal(t, models.DashboardAck, envelope.Type, string(envelope.Data))
+			var result models.DashboardTaskResult
+			err := json.Unmarshal(envelope.Data, &result)
+			assert.NoError(t, err)
+			return result
+		}
+		nackOf := func(envelope models.DashboardMessage) models.DashboardErrorData {
+			assert.Equal(t, models.DashboardNack, envelope.Type, string(envelope.Data))
+			var errorData models.DashboardErrorData
+			err := json.Unmarshal(envelope.Data, &errorData)
+			assert.NoError(t, err)
+			return errorData
+		}
+		readEvent := func() models.TaskEventMessage {
+			envelope := read(watcher)
+			assert.Equal(t, models.DashboardEvent, envelope.Type)
+			var message models.TaskEventMessage
+			err := json.Unmarshal(envelope.Data, &message)
+			assert.NoError(t, err)
+			return message
+		}
+
+		err = watcher.WriteMessage(websocket.TextMessage, []byte(`{"v": 1, "type": "subscribe", "data": {"status": "To do"}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, models.DashboardTasks, read(watcher).Type)
+
+		// the same validators as in REST apply
+		envelope := request(`{"v": 1, "type": "create_task", "id": "1", "data": {"description": "no name"}}`)
+		assert.Equal(t, "1", envelope.Id)
+		assert.Equal(t, models.DashboardErrInvalid, nackOf(envelope).Code)
+
+		envelope = request(`{"v": 1, "type": "create_task", "id": "2", "data": {"name": "Operated"}}`)
+		assert.Equal(t, "2", envelope.Id)
+		created := ackOf(envelope)
+		assert.Equal(t, "Operated", created.Task.Name)
+
+		message := readEvent()
+		assert.Equal(t, models.TaskEventCreated, message.Event)
+		assert.Equal(t, created.TaskId, message.TaskId)
+
+		envelope = request(fmt.Sprintf(`{"v": 1, "type": "edit_task", "id": "3", "data": {"id": %d, "name": "Edited", "priority": "whenever"}}`, created.TaskId))
+		assert.Equal(t, models.DashboardErrInvalid, nackOf(envelope).Code)
+
+		envelope = request(fmt.Sprintf(`{"v": 1, "type": "edit_task", "id": "4", "data": {"id": %d, "name": "Edited", "priority": "high"}}`, created.TaskId))
+		edited := ackOf(envelope)
+		assert.Equal(t, "Edited", edited.Task.Name)
+		assert.Equal(t, "high", *edited.Task.Priority)
+
+		message = readEvent()
+		assert.Equal(t, models.TaskEventUpdated, message.Event)
+		assert.Equal(t, created.TaskId, message.TaskId)
+
+		envelope = request(fmt.Sprintf(`{"v": 1, "type": "update_status", "id": "5", "data": {"id": %d, "status": "In progress", "version": %d}}`, created.TaskId, created.Task.Version))
+		assert.Equal(t, models.DashboardErrVersionMismatch, nackOf(envelope).Code)
+
+		envelope = request(fmt.Sprintf(`{"v": 1, "type": "update_status", "id": "6", "data": {"id": %d, "status": "random"}}`, created.TaskId))
+		assert.Equal(t, models.DashboardErrInvalid, nackOf(envelope).Code)
+
+		envelope = request(fmt.Sprintf(`{"v": 1, "type": "update_status", "id": "7", "data": {"id": %d, "status": "In progress", "version": %d}}`, created.TaskId, edited.Task.Version))
+		updated := ackOf(envelope)
+		assert.Equal(t, "In progress", updated.Task.Status)
+		assert.NotEmpty(t, updated.UndoToken)
+
+		// the task stopped matching the filter of the watcher
+		message = readEvent()
+		assert.Equal(t, models.TaskEventDeleted, message.Event)
+		assert.Equal(t, created.TaskId, message.TaskId)
+
+		envelope = request(`{"v": 1, "type": "delete_task", "id": "8", "data": {"id": 999999}}`)
+		assert.Equal(t, models.DashboardErrNotFound, nackOf(envelope).Code)
+
+		envelope = request(fmt.Sprintf(`{"v": 1, "type": "delete_task", "id": "9", "data": {"id": %d}}`, created.TaskId))
+		deleted := ackOf(envelope)
+		assert.Equal(t, created.TaskId, deleted.TaskId)
+		assert.Nil(t, deleted.Task)
+		assert.NotEmpty(t, deleted.UndoToken)
+	})
+}
+
+func TestDashboardConnections(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	taskEvents := services.NewTaskEventsHub(repos.NewTaskEventsRepo(conn))
+
+	jwtCookieAuth := middlewares.NewJwtCookieAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtCookieAuth.AuthCtxKey)
+	connectionTracker := middlewares.NewConnectionTracker(jwtCookieAuth.AuthCtxKey)
+	connectionTracker.MaxPerUser = 2
+
+	utils.RegisterValidators()
+	routes.RegisterDashboardRoute(r, jwtCookieAuth, workspaceResolver, connectionTracker, tasksService, taskEvents)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	u := &url.URL{
+		Scheme: "ws",
+		Host:   server.URL[7:],
+		Path:   "/dashboard/",
+	}
+
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	token, _ := tp.Provide(userCred.Email)
+	header := http.Header{"Cookie": {fmt.Sprintf("%s=%s", jwtCookieAuth.AuthCookieKey, token)}}
+	test_utils.CreateUserWithTasks(userCred, []models.TaskData{}, userRepo, tasksRepo)
+
+	defer utils.TruncateTables(conn, []string{"tasks", "users"})
+
+	t.Run("Too big messages close the connection", func(t *testing.T) {
+		wsConn, _, err := websocket.DefaultDialer.Dial(u.String(), header)
 		assert.NoError(t, err)
-		assert.Equal(t, 1, len(tasksResp), tasksResp)
-		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:1]), test_utils.MapTasksToName(tasksResp))
+		defer wsConn.Close()
+
+		// the server may close the connection before the whole message is sent
+		wsConn.WriteMessage(websocket.TextMessage, make([]byte, 1<<17))
+		wsConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, _, err = wsConn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), err)
+	})
+
+	t.Run("Connections per user are limited", func(t *testing.T) {
+		// the connection of the previous test may still be closing
+		assert.Eventually(t, func() bool {
+			first, _, err := websocket.DefaultDialer.Dial(u.String(), header)
+			if err != nil {
+				return false
+			}
+			defer first.Close()
+			second, _, err := websocket.DefaultDialer.Dial(u.String(), header)
+			if err != nil {
+				return false
+			}
+			defer second.Close()
+
+			_, httpResp, err := websocket.DefaultDialer.Dial(u.String(), header)
+			assert.EqualError(t, err, websocket.ErrBadHandshake.Error())
+			assert.Equal(t, http.StatusTooManyRequests, httpResp.StatusCode)
+			return true
+		}, 5*time.Second, 100*time.Millisecond)
+	})
+
+	t.Run("Connections are closed on shutdown", func(t *testing.T) {
+		var wsConn *websocket.Conn
+		assert.Eventually(t, func() bool {
+			var err error
+			wsConn, _, err = websocket.DefaultDialer.Dial(u.String(), header)
+			return err == nil
+		}, 5*time.Second, 100*time.Millisecond)
+		defer wsConn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr := make(chan error)
+		go func() {
+			shutdownErr <- connectionTracker.Shutdown(ctx)
+		}()
+
+		wsConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, _, err := wsConn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+		assert.NoError(t, <-shutdownErr)
+
+		_, httpResp, err := websocket.DefaultDialer.Dial(u.String(), header)
+		assert.EqualError(t, err, websocket.ErrBadHandshake.Error())
+		assert.Equal(t, http.StatusServiceUnavailable, httpResp.StatusCode)
 	})
 }
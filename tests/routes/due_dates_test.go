@@ -0,0 +1,137 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksDueDateFilters(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterAuthRoutes(r, jwtAuth, services.NewUsersService(userRepo, tp))
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	test_utils.CreateUserWithTasks(userCred, nil, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"tasks", "users"})
+
+	doRequest := func(method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	listNames := func(query url.Values) []string {
+		resp := doRequest("GET", "/tasks/?"+query.Encode(), "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.CursorPage[models.TaskListItem]
+		json.Unmarshal(resp.Body.Bytes(), &page)
+		return test_utils.Map(page.Items, func(t models.TaskListItem) string { return t.Name })
+	}
+
+	t.Run("Bad request on invalid time zone", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"time_zone": "Mars/Olympus"}`} {
+			resp := doRequest("PUT", "/auth/timezone", body)
+			assert.Equal(t, 400, resp.Code, body)
+		}
+	})
+
+	// far from UTC so that days of the user and UTC days differ most of the time
+	timeZone := "Pacific/Kiritimati"
+	resp := doRequest("PUT", "/auth/timezone", fmt.Sprintf(`{"time_zone": "%s"}`, timeZone))
+	assert.Equal(t, 200, resp.Code, resp.Body.String())
+	resp = doRequest("GET", "/auth/whoami", "")
+	var user models.UserData
+	json.Unmarshal(resp.Body.Bytes(), &user)
+	assert.Equal(t, timeZone, user.TimeZone)
+
+	loc, _ := time.LoadLocation(timeZone)
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	at := func(t time.Time) *time.Time { return &t }
+
+	for _, task := range []struct {
+		name    string
+		dueDate *time.Time
+		status  string
+	}{
+		{"today", at(today.Add(time.Minute)), "To do"},
+		{"tomorrow", at(today.AddDate(0, 0, 1).Add(time.Minute)), "To do"},
+		{"soon", at(today.AddDate(0, 0, 5)), "To do"},
+		{"past", at(today.AddDate(0, 0, -2)), "In progress"},
+		{"past done", at(today.AddDate(0, 0, -2)), "Done"},
+		{"undated", nil, "To do"},
+	} {
+		taskCreate, _ := json.Marshal(models.TaskCreate{Name: task.name, DueDate: task.dueDate})
+		resp := doRequest("POST", "/tasks/", string(taskCreate))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var created models.TaskData
+		json.Unmarshal(resp.Body.Bytes(), &created)
+
+		resp = doRequest("PATCH", fmt.Sprintf("/tasks/%d", created.Id), fmt.Sprintf(`{"status": "%s"}`, task.status))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+	}
+
+	t.Run("Bad request on invalid days", func(t *testing.T) {
+		for _, param := range []string{"due_date", "due_before", "due_after"} {
+			for _, day := range []string{"someday", "2024-13-01", "TODAY"} {
+				resp := doRequest("GET", fmt.Sprintf("/tasks/?%s=%s", param, day), "")
+				assert.Equal(t, 400, resp.Code, param, day)
+			}
+		}
+	})
+
+	t.Run("Filter by day in time zone of the user", func(t *testing.T) {
+		cases := []struct {
+			query    url.Values
+			expected []string
+		}{
+			{url.Values{"due_date": {"today"}}, []string{"today"}},
+			{url.Values{"due_date": {today.Format(utils.DayDateFmt)}}, []string{"today"}},
+			{url.Values{"due_date": {"tomorrow"}}, []string{"tomorrow"}},
+			{url.Values{"due_date": {"next_7_days"}}, []string{"today", "tomorrow", "soon"}},
+			{url.Values{"due_before": {"today"}}, []string{"past", "past done"}},
+			{url.Values{"due_after": {"tomorrow"}}, []string{"soon"}},
+			{url.Values{"due_after": {"yesterday"}, "due_before": {"tomorrow"}}, []string{"today"}},
+			{url.Values{"has_due_date": {"false"}}, []string{"undated"}},
+			{url.Values{"has_due_date": {"true"}}, []string{"today", "tomorrow", "soon", "past", "past done"}},
+			{url.Values{"overdue": {"true"}}, []string{"past"}},
+			{url.Values{"overdue": {"false"}}, []string{"today", "tomorrow", "soon", "past done", "undated"}},
+		}
+		for _, c := range cases {
+			assert.ElementsMatch(t, c.expected, listNames(c.query), c.query.Encode())
+		}
+		assert.Contains(t, listNames(url.Values{"due_date": {"this_week"}}), "today")
+		assert.NotContains(t, listNames(url.Values{"due_date": {"next_week"}}), "today")
+	})
+}
@@ -0,0 +1,156 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskHistory(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	workspacesRepo := repos.NewWorkspacesRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	historyService := services.NewHistoryService(repos.NewHistoryRepo(conn), tasksService.Auth)
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(workspacesRepo, jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+	routes.RegisterCustomFieldsRoutes(r, jwtAuth, workspaceResolver, tasksService.Fields)
+	routes.RegisterHistoryRoutes(r, jwtAuth, workspaceResolver, historyService)
+
+	ownerCred := models.UserRegister{Email: "owner@test.com", Password: "whatever"}
+	ownerData, _ := test_utils.CreateUserWithTasks(ownerCred, nil, userRepo, tasksRepo)
+	memberCred := models.UserRegister{Email: "member@test.com", Password: "whatever"}
+	memberData, _ := test_utils.CreateUserWithTasks(memberCred, nil, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"task_history", "tasks", "projects", "users"})
+
+	// member joins owner's personal workspace
+	workspace := test_utils.GetPersonalWorkspace(conn, ownerData.Id)
+	invitation, err := workspacesRepo.CreateInvitation(context.Background(), workspace.Id, memberCred.Email, models.WorkspaceRoleMember, ownerData.Id)
+	assert.Nil(t, err)
+	err = workspacesRepo.AcceptInvitation(context.Background(), invitation, memberData.Id)
+	assert.Nil(t, err)
+	project, err := repos.NewProjectsRepo(conn).Create(context.Background(), workspace.Id, "Audited", ownerData.Id)
+	assert.Nil(t, err)
+
+	doRequest := func(userCred models.UserRegister, method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		req.Header.Set(workspaceResolver.WorkspaceHeader, fmt.Sprint(workspace.Id))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	listEntries := func(userCred models.UserRegister, path string) models.Page[models.HistoryEntry] {
+		resp := doRequest(userCred, "GET", path, "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.Page[models.HistoryEntry]
+		err := json.Unmarshal(resp.Body.Bytes(), &page)
+		assert.Nil(t, err, resp.Body.String())
+		return page
+	}
+	fieldOf := func(entry models.HistoryEntry) string {
+		if entry.Field == nil {
+			return entry.Action
+		}
+		return entry.Action + " " + *entry.Field
+	}
+
+	resp := doRequest(ownerCred, "POST", "/tasks/", fmt.Sprintf(`{"name": "audited", "project_id": %d}`, project.Id))
+	assert.Equal(t, 200, resp.Code, resp.Body.String())
+	var task models.TaskData
+	json.Unmarshal(resp.Body.Bytes(), &task)
+	historyPath := fmt.Sprintf("/tasks/%d/history", task.Id)
+
+	resp = doRequest(ownerCred, "POST", fmt.Sprintf("/projects/%d/fields/", project.Id), `{"name": "Story points", "type": "number"}`)
+	assert.Equal(t, 201, resp.Code, resp.Body.String())
+	var field models.CustomFieldData
+	json.Unmarshal(resp.Body.Bytes(), &field)
+
+	for _, change := range []struct{ method, path, body string }{
+		{"PATCH", fmt.Sprintf("/tasks/%d", task.Id), `{"status": "In progress"}`},
+		{"POST", "/tasks/bulk", fmt.Sprintf(`{"ids": [%d], "action": "edit_labels", "add_labels": ["audit"]}`, task.Id)},
+		{"PUT", fmt.Sprintf("/tasks/%d/fields/%d", task.Id, field.Id), `{"value": 3}`},
+		{"PUT", fmt.Sprintf("/tasks/%d/fields/%d", task.Id, field.Id), `{"value": 5}`},
+		{"POST", "/tasks/bulk", fmt.Sprintf(`{"ids": [%d], "action": "set_status", "status": "Done"}`, task.Id)},
+	} {
+		resp := doRequest(ownerCred, change.method, change.path, change.body)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+	}
+
+	t.Run("History records every change in order", func(t *testing.T) {
+		page := listEntries(ownerCred, historyPath)
+		assert.Equal(t, 6, page.Total)
+		assert.Equal(t, []string{
+			"created",
+			"updated status",
+			"updated labels",
+			fmt.Sprintf("updated fields.%d", field.Id),
+			fmt.Sprintf("updated fields.%d", field.Id),
+			"updated status",
+		}, test_utils.Map(page.Items, fieldOf))
+
+		for _, entry := range page.Items {
+			assert.Equal(t, ownerData.Id, *entry.UserId)
+		}
+		assert.Equal(t, "To do", page.Items[1].OldValue)
+		assert.Equal(t, "In progress", page.Items[1].NewValue)
+		assert.Equal(t, []any{"audit"}, page.Items[2].NewValue)
+		assert.Nil(t, page.Items[3].OldValue)
+		assert.Equal(t, 3.0, page.Items[4].OldValue)
+		assert.Equal(t, 5.0, page.Items[4].NewValue)
+
+		page = listEntries(ownerCred, historyPath+"?page=2&per_page=4")
+		assert.Equal(t, 2, len(page.Items))
+	})
+
+	t.Run("History of inaccessible task", func(t *testing.T) {
+		resp := doRequest(memberCred, "GET", historyPath, "")
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+		resp = doRequest(ownerCred, "GET", fmt.Sprintf("/tasks/%d/history", task.Id+1000), "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Deleted tasks stay in activity feed", func(t *testing.T) {
+		resp := doRequest(ownerCred, "DELETE", fmt.Sprintf("/tasks/%d", task.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		page := listEntries(ownerCred, "/activity")
+		assert.Equal(t, 7, page.Total)
+		assert.Equal(t, models.HistoryDeleted, page.Items[0].Action)
+		assert.Equal(t, models.HistoryCreated, page.Items[len(page.Items)-1].Action)
+
+		// the member can't see the task, nor what the owner did to it
+		page = listEntries(memberCred, fmt.Sprintf("/activity?user_id=%d", ownerData.Id))
+		assert.Equal(t, 0, page.Total)
+		page = listEntries(memberCred, "/activity")
+		assert.Equal(t, 0, page.Total)
+
+		resp = doRequest(ownerCred, "GET", "/activity?user_id=abc", "")
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+	})
+}
@@ -0,0 +1,159 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	workspacesRepo := repos.NewWorkspacesRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	idempotencyService := services.NewIdempotencyService(repos.NewIdempotencyRepo(conn))
+	idempotencyCollector := services.NewIdempotencyCollector(idempotencyService)
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(workspacesRepo, jwtAuth.AuthCtxKey)
+	idempotencyGuard := middlewares.NewIdempotencyGuard(idempotencyService, jwtAuth, workspaceResolver)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r.Group("", idempotencyGuard.Handler), jwtAuth, workspaceResolver, tasksService)
+
+	ownerCred := models.UserRegister{Email: "owner@test.com", Password: "whatever"}
+	test_utils.CreateUserWithTasks(ownerCred, nil, userRepo, tasksRepo)
+	otherCred := models.UserRegister{Email: "other@test.com", Password: "whatever"}
+	test_utils.CreateUserWithTasks(otherCred, nil, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"idempotency_keys", "tasks", "users"})
+
+	doRequest := func(userCred models.UserRegister, method string, path string, body string, key string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		if key != "" {
+			req.Header.Set(idempotencyGuard.KeyHeader, key)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	listTaskNames := func(userCred models.UserRegister) []string {
+		resp := doRequest(userCred, "GET", "/tasks/", "", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.CursorPage[models.TaskListItem]
+		json.Unmarshal(resp.Body.Bytes(), &page)
+		return test_utils.Map(page.Items, func(t models.TaskListItem) string { return t.Name })
+	}
+
+	t.Run("Retried creation is replayed", func(t *testing.T) {
+		first := doRequest(ownerCred, "POST", "/tasks/", `{"name": "once"}`, "create-once")
+		assert.Equal(t, 200, first.Code, first.Body.String())
+		assert.Empty(t, first.Header().Get(idempotencyGuard.ReplayedHeader))
+
+		retry := doRequest(ownerCred, "POST", "/tasks/", `{"name": "once"}`, "create-once")
+		assert.Equal(t, 200, retry.Code, retry.Body.String())
+		assert.Equal(t, "true", retry.Header().Get(idempotencyGuard.ReplayedHeader))
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, first.Header().Get("ETag"), retry.Header().Get("ETag"))
+		assert.Equal(t, first.Header().Get("Content-Type"), retry.Header().Get("Content-Type"))
+
+		assert.Equal(t, []string{"once"}, listTaskNames(ownerCred))
+	})
+
+	t.Run("Conflict on key reused with different request", func(t *testing.T) {
+		resp := doRequest(ownerCred, "POST", "/tasks/", `{"name": "twice"}`, "create-once")
+		assert.Equal(t, 409, resp.Code, resp.Body.String())
+		assert.Equal(t, []string{"once"}, listTaskNames(ownerCred))
+	})
+
+	t.Run("Keys are scoped per user", func(t *testing.T) {
+		resp := doRequest(otherCred, "POST", "/tasks/", `{"name": "once"}`, "create-once")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Empty(t, resp.Header().Get(idempotencyGuard.ReplayedHeader))
+		assert.Equal(t, []string{"once"}, listTaskNames(otherCred))
+	})
+
+	t.Run("Failed requests are replayed too", func(t *testing.T) {
+		resp := doRequest(ownerCred, "DELETE", "/tasks/1000000", "", "delete-missing")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+		resp = doRequest(ownerCred, "DELETE", "/tasks/1000000", "", "delete-missing")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+		assert.Equal(t, "true", resp.Header().Get(idempotencyGuard.ReplayedHeader))
+	})
+
+	t.Run("Requests without key or unauthenticated pass through", func(t *testing.T) {
+		for range 2 {
+			resp := doRequest(ownerCred, "POST", "/tasks/", `{"name": "keyless"}`, "")
+			assert.Equal(t, 200, resp.Code, resp.Body.String())
+		}
+		assert.Equal(t, []string{"once", "keyless", "keyless"}, listTaskNames(ownerCred))
+
+		req, _ := http.NewRequest("POST", "/tasks/", strings.NewReader(`{"name": "anonymous"}`))
+		req.Header.Set(idempotencyGuard.KeyHeader, "anonymous")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		assert.Equal(t, 401, resp.Code, resp.Body.String())
+
+		resp = doRequest(ownerCred, "POST", "/tasks/", `{"name": "long"}`, strings.Repeat("k", 256))
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		resp = doRequest(ownerCred, "POST", "/tasks/", fmt.Sprintf(`{"name": "%s"}`, strings.Repeat("n", 1<<20)), "too-large")
+		assert.Equal(t, 413, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Bad request on multipart request with key", func(t *testing.T) {
+		token, _ := tp.Provide(ownerCred.Email)
+		req, _ := http.NewRequest("POST", "/tasks/", strings.NewReader("--x--\r\n"))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+		req.Header.Set(idempotencyGuard.KeyHeader, "multipart")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Requests matching no route don't take up keys", func(t *testing.T) {
+		resp := doRequest(ownerCred, "POST", "/nowhere", `{"name": "lost"}`, "unrouted")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+
+		resp = doRequest(ownerCred, "POST", "/tasks/", `{"name": "routed"}`, "unrouted")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Empty(t, resp.Header().Get(idempotencyGuard.ReplayedHeader))
+	})
+
+	t.Run("Expired keys are purged", func(t *testing.T) {
+		purged, err := idempotencyCollector.RunOnce(context.Background(), time.Now().UTC())
+		assert.Nil(t, err)
+		assert.Equal(t, 0, purged)
+
+		purged, err = idempotencyCollector.RunOnce(context.Background(), time.Now().UTC().Add(idempotencyService.TTL+time.Hour))
+		assert.Nil(t, err)
+		assert.Equal(t, 4, purged)
+
+		resp := doRequest(ownerCred, "POST", "/tasks/", `{"name": "twice"}`, "create-once")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Contains(t, listTaskNames(ownerCred), "twice")
+	})
+}
@@ -0,0 +1,141 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksPagination(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+
+	tasks := make([]models.TaskData, 7)
+	for i := range tasks {
+		tasks[i] = models.TaskData{Name: fmt.Sprintf("Task %d", i), Status: "To do"}
+	}
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	_, tasks = test_utils.CreateUserWithTasks(userCred, tasks, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"tasks", "users"})
+
+	doRequest := func(method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	listTasks := func(query url.Values) models.CursorPage[models.TaskListItem] {
+		resp := doRequest("GET", "/tasks/?"+query.Encode(), "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.CursorPage[models.TaskListItem]
+		err := json.Unmarshal(resp.Body.Bytes(), &page)
+		assert.Nil(t, err, resp.Body.String())
+		return page
+	}
+	taskNames := func(tasks []models.TaskListItem) []string {
+		return test_utils.Map(tasks, func(t models.TaskListItem) string { return t.Name })
+	}
+
+	t.Run("Bad request on invalid limit", func(t *testing.T) {
+		for _, limit := range []string{"0", "101", "abc"} {
+			resp := doRequest("GET", "/tasks/?limit="+limit, "")
+			assert.Equal(t, 400, resp.Code, limit)
+		}
+	})
+
+	t.Run("Single page without cursors", func(t *testing.T) {
+		page := listTasks(url.Values{})
+		assert.Equal(t, 7, len(page.Items))
+		assert.Nil(t, page.Next)
+		assert.Nil(t, page.Prev)
+	})
+
+	t.Run("Next and previous pages", func(t *testing.T) {
+		first := listTasks(url.Values{"limit": {"3"}})
+		assert.Equal(t, test_utils.MapTasksToName(tasks[:3]), taskNames(first.Items))
+		assert.NotNil(t, first.Next)
+		assert.Nil(t, first.Prev)
+
+		resp := doRequest("GET", "/tasks/?limit=3", "")
+		assert.Contains(t, resp.Header().Get("Link"), `rel="next"`)
+
+		second := listTasks(url.Values{"limit": {"3"}, "cursor": {*first.Next}})
+		assert.Equal(t, test_utils.MapTasksToName(tasks[3:6]), taskNames(second.Items))
+		assert.NotNil(t, second.Next)
+		assert.NotNil(t, second.Prev)
+
+		third := listTasks(url.Values{"limit": {"3"}, "cursor": {*second.Next}})
+		assert.Equal(t, test_utils.MapTasksToName(tasks[6:]), taskNames(third.Items))
+		assert.Nil(t, third.Next)
+		assert.NotNil(t, third.Prev)
+
+		back := listTasks(url.Values{"limit": {"3"}, "cursor": {*third.Prev}})
+		assert.Equal(t, taskNames(second.Items), taskNames(back.Items))
+
+		back = listTasks(url.Values{"limit": {"3"}, "cursor": {*back.Prev}})
+		assert.Equal(t, taskNames(first.Items), taskNames(back.Items))
+		assert.Nil(t, back.Prev)
+	})
+
+	t.Run("Inserted tasks don't shift pages", func(t *testing.T) {
+		first := listTasks(url.Values{"limit": {"3"}})
+
+		resp := doRequest("POST", "/tasks/", `{"name": "Late task"}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		defer func() {
+			var created models.TaskData
+			json.Unmarshal(resp.Body.Bytes(), &created)
+			doRequest("DELETE", fmt.Sprintf("/tasks/%d", created.Id), "")
+		}()
+
+		second := listTasks(url.Values{"limit": {"3"}, "cursor": {*first.Next}})
+		assert.Equal(t, test_utils.MapTasksToName(tasks[3:6]), taskNames(second.Items))
+	})
+
+	t.Run("Bad request on tampered cursor", func(t *testing.T) {
+		first := listTasks(url.Values{"limit": {"3"}})
+		payload, _, _ := strings.Cut(*first.Next, ".")
+
+		for _, cursor := range []string{"garbage", payload + ".forged", payload} {
+			resp := doRequest("GET", "/tasks/?limit=3&cursor="+url.QueryEscape(cursor), "")
+			assert.Equal(t, 400, resp.Code, cursor)
+		}
+	})
+
+	t.Run("Bad request on cursor of other sorting", func(t *testing.T) {
+		first := listTasks(url.Values{"limit": {"3"}})
+
+		query := url.Values{"limit": {"3"}, "cursor": {*first.Next}, "sort": {"-fields.1"}}
+		resp := doRequest("GET", "/tasks/?"+query.Encode(), "")
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+	})
+}
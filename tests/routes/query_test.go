@@ -0,0 +1,100 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksQuery(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	test_utils.CreateUserWithTasks(userCred, nil, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"tasks", "users"})
+
+	doRequest := func(method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	queryNames := func(q string) []string {
+		resp := doRequest("GET", "/tasks/?"+url.Values{"query": {q}}.Encode(), "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.CursorPage[models.TaskListItem]
+		json.Unmarshal(resp.Body.Bytes(), &page)
+		return test_utils.Map(page.Items, func(t models.TaskListItem) string { return t.Name })
+	}
+
+	var tasks []models.TaskData
+	for _, task := range []struct{ body, status string }{
+		{`{"name": "Deploy the release", "priority": "high"}`, "In progress"},
+		{`{"name": "Write release notes", "priority": "low"}`, "To do"},
+		{`{"name": "Fix login page", "priority": "urgent"}`, "To do"},
+	} {
+		resp := doRequest("POST", "/tasks/", task.body)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var created models.TaskData
+		json.Unmarshal(resp.Body.Bytes(), &created)
+		tasks = append(tasks, created)
+
+		resp = doRequest("PATCH", fmt.Sprintf("/tasks/%d", created.Id), fmt.Sprintf(`{"status": "%s"}`, task.status))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+	}
+	for _, labels := range []string{
+		fmt.Sprintf(`{"ids": [%d, %d], "action": "edit_labels", "add_labels": ["urgent"]}`, tasks[0].Id, tasks[1].Id),
+		fmt.Sprintf(`{"ids": [%d], "action": "edit_labels", "add_labels": ["blocked"]}`, tasks[1].Id),
+	} {
+		resp := doRequest("POST", "/tasks/bulk", labels)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+	}
+
+	t.Run("Filter by fields", func(t *testing.T) {
+		assert.Equal(t, []string{"Deploy the release"}, queryNames(`status:"In progress"`))
+		assert.Equal(t, []string{"Deploy the release"}, queryNames("label:urgent -label:blocked"))
+		assert.Equal(t, []string{"Deploy the release", "Fix login page"}, queryNames("priority>=high"))
+		assert.Equal(t, []string{"Fix login page"}, queryNames("-has:labels"))
+	})
+
+	t.Run("Combine with OR and text", func(t *testing.T) {
+		assert.Equal(t, []string{"Write release notes", "Fix login page"}, queryNames("label:blocked OR login"))
+		assert.Equal(t, []string{"Deploy the release"}, queryNames(`release (priority:high OR status:done)`))
+	})
+
+	t.Run("Bad request with position of syntax error", func(t *testing.T) {
+		resp := doRequest("GET", "/tasks/?"+url.Values{"query": {"label:urgent priority:whenever"}}.Encode(), "")
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+		assert.Contains(t, resp.Body.String(), "position 23")
+	})
+}
@@ -0,0 +1,313 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingNotifier struct {
+	mu        sync.Mutex
+	reminders []models.DueReminder
+	keys      []string
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, idempotencyKey string, reminder models.DueReminder) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.reminders = append(n.reminders, reminder)
+	n.keys = append(n.keys, idempotencyKey)
+	return nil
+}
+
+// failingNotifier fails to deliver reminders of the failing ids.
+type failingNotifier struct {
+	recordingNotifier
+	failing map[int]bool
+}
+
+func (n *failingNotifier) Notify(ctx context.Context, idempotencyKey string, reminder models.DueReminder) error {
+	n.recordingNotifier.Notify(ctx, idempotencyKey, reminder)
+	if n.failing[reminder.Id] {
+		return errors.New("delivery failed")
+	}
+	return nil
+}
+
+// notifierFunc delivers reminders by calling itself.
+type notifierFunc func(ctx context.Context, idempotencyKey string, reminder models.DueReminder) error
+
+func (f notifierFunc) Notify(ctx context.Context, idempotencyKey string, reminder models.DueReminder) error {
+	return f(ctx, idempotencyKey, reminder)
+}
+
+func TestReminders(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	remindersRepo := repos.NewRemindersRepo(conn)
+	remindersService := services.NewRemindersService(remindersRepo, test_utils.NewAuthorizer(conn, tasksRepo))
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterRemindersRoutes(r, jwtAuth, workspaceResolver, remindersService)
+
+	// create tester user
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	token, _ := tp.Provide(userCred.Email)
+	userAuthHeader := fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token)
+
+	dueDate := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Second)
+	_, tasks := test_utils.CreateUserWithTasks(
+		userCred,
+		[]models.TaskData{
+			{Name: "With due date", DueDate: &dueDate, Status: "To do"},
+			{Name: "Without due date", Status: "To do"},
+		},
+		userRepo,
+		tasksRepo,
+	)
+	defer utils.TruncateTables(conn, []string{"task_reminders", "tasks", "users"})
+
+	createReminder := func(taskId int, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/tasks/%d/reminders/", taskId), strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Unauthorized on empty header", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/tasks/%d/reminders/", tasks[0].Id), nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		assert.Equal(t, 401, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Bad request on invalid body", func(t *testing.T) {
+		defer utils.TruncateTables(conn, []string{"task_reminders"})
+
+		// neither remind_at nor offset
+		resp := createReminder(tasks[0].Id, "{}")
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		// both remind_at and offset
+		body := fmt.Sprintf(`{"remind_at": "%s", "offset_minutes": 10}`, dueDate.Format(time.RFC3339))
+		resp = createReminder(tasks[0].Id, body)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		// negative offset
+		resp = createReminder(tasks[0].Id, `{"offset_minutes": -5}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		// offset on task without due date
+		resp = createReminder(tasks[1].Id, `{"offset_minutes": 5}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Not found on non existing task", func(t *testing.T) {
+		resp := createReminder(tasks[1].Id+1000, `{"offset_minutes": 5}`)
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Forbidden on someone else's task", func(t *testing.T) {
+		otherUserCred := models.UserRegister{Email: "other@other.com", Password: "whatever"}
+		_, otherTasks := test_utils.CreateUserWithTasks(otherUserCred, []models.TaskData{{Name: "Other task", Status: "Done"}}, userRepo, tasksRepo)
+
+		resp := createReminder(otherTasks[0].Id, `{"offset_minutes": 5}`)
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Create, list and delete", func(t *testing.T) {
+		defer utils.TruncateTables(conn, []string{"task_reminders"})
+
+		resp := createReminder(tasks[0].Id, `{"offset_minutes": 60}`)
+		assert.Equal(t, 201, resp.Code, resp.Body.String())
+
+		var offsetReminder models.ReminderData
+		err := json.Unmarshal(resp.Body.Bytes(), &offsetReminder)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, dueDate.Add(-time.Hour), offsetReminder.RemindAt)
+		assert.Equal(t, 60, *offsetReminder.OffsetMinutes)
+		assert.Nil(t, offsetReminder.SentAt)
+
+		remindAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+		body := fmt.Sprintf(`{"remind_at": "%s"}`, remindAt.Format(time.RFC3339))
+		resp = createReminder(tasks[1].Id, body)
+		assert.Equal(t, 201, resp.Code, resp.Body.String())
+
+		var absoluteReminder models.ReminderData
+		err = json.Unmarshal(resp.Body.Bytes(), &absoluteReminder)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, remindAt, absoluteReminder.RemindAt)
+		assert.Nil(t, absoluteReminder.OffsetMinutes)
+
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/tasks/%d/reminders/", tasks[0].Id), nil)
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+
+		var reminders []models.ReminderData
+		err = json.Unmarshal(resp.Body.Bytes(), &reminders)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, 1, len(reminders), reminders)
+		assert.Equal(t, offsetReminder.Id, reminders[0].Id)
+
+		// reminder of another task can't be deleted through this task
+		req, _ = http.NewRequest("DELETE", fmt.Sprintf("/tasks/%d/reminders/%d", tasks[0].Id, absoluteReminder.Id), nil)
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+
+		req, _ = http.NewRequest("DELETE", fmt.Sprintf("/tasks/%d/reminders/%d", tasks[0].Id, offsetReminder.Id), nil)
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		reminders, err = remindersRepo.ListByTaskId(context.Background(), tasks[0].Id)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(reminders), reminders)
+	})
+
+	t.Run("Scheduler fires each due reminder once", func(t *testing.T) {
+		defer utils.TruncateTables(conn, []string{"task_reminders"})
+
+		now := time.Now().UTC()
+		past, err := remindersRepo.Create(context.Background(), tasks[0].Id, now.Add(-time.Minute), nil)
+		assert.Nil(t, err)
+		_, err = remindersRepo.Create(context.Background(), tasks[0].Id, now.Add(time.Hour), nil)
+		assert.Nil(t, err)
+
+		notifier := &recordingNotifier{}
+		// several concurrent schedulers imitate multiple server instances
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				scheduler := services.NewRemindersScheduler(remindersRepo, notifier)
+				_, err := scheduler.RunOnce(context.Background(), now)
+				assert.Nil(t, err)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, len(notifier.reminders), notifier.reminders)
+		assert.Equal(t, past.Id, notifier.reminders[0].Id)
+		assert.Equal(t, tasks[0].Name, notifier.reminders[0].TaskName)
+
+		// already sent reminder is not fired again
+		scheduler := services.NewRemindersScheduler(remindersRepo, notifier)
+		sent, err := scheduler.RunOnce(context.Background(), now)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, sent)
+
+		reminders, err := remindersRepo.ListByTaskId(context.Background(), tasks[0].Id)
+		assert.Nil(t, err)
+		assert.NotNil(t, reminders[0].SentAt)
+		assert.Nil(t, reminders[1].SentAt)
+	})
+	t.Run("Scheduler marks each reminder once delivered", func(t *testing.T) {
+		defer utils.TruncateTables(conn, []string{"task_reminders"})
+
+		now := time.Now().UTC()
+		first, err := remindersRepo.Create(context.Background(), tasks[0].Id, now.Add(-2*time.Minute), nil)
+		assert.Nil(t, err)
+		_, err = remindersRepo.Create(context.Background(), tasks[0].Id, now.Add(-time.Minute), nil)
+		assert.Nil(t, err)
+
+		// the first reminder is sent before the rest of the batch is delivered
+		var sentBefore []int
+		notifier := notifierFunc(func(ctx context.Context, idempotencyKey string, reminder models.DueReminder) error {
+			if reminder.Id == first.Id {
+				return nil
+			}
+			reminders, err := remindersRepo.ListByTaskId(ctx, tasks[0].Id)
+			assert.Nil(t, err)
+			for _, r := range reminders {
+				if r.SentAt != nil {
+					sentBefore = append(sentBefore, r.Id)
+				}
+			}
+			return nil
+		})
+		sent, err := services.NewRemindersScheduler(remindersRepo, notifier).RunOnce(context.Background(), now)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, sent)
+		assert.Equal(t, []int{first.Id}, sentBefore)
+	})
+	t.Run("Scheduler backs off failing reminders", func(t *testing.T) {
+		defer utils.TruncateTables(conn, []string{"task_reminders"})
+
+		now := time.Now().UTC()
+		failing, err := remindersRepo.Create(context.Background(), tasks[0].Id, now.Add(-2*time.Minute), nil)
+		assert.Nil(t, err)
+		later, err := remindersRepo.Create(context.Background(), tasks[0].Id, now.Add(-time.Minute), nil)
+		assert.Nil(t, err)
+
+		notifier := &failingNotifier{failing: map[int]bool{failing.Id: true}}
+		scheduler := services.NewRemindersScheduler(remindersRepo, notifier)
+		scheduler.BatchSize = 1
+		scheduler.MaxAttempts = 2
+
+		_, err = scheduler.RunOnce(context.Background(), now)
+		assert.NotNil(t, err)
+
+		// the failing reminder is backed off instead of holding up the later one
+		sent, err := scheduler.RunOnce(context.Background(), now)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, sent)
+
+		// retried once the backoff passes, then given up
+		_, err = scheduler.RunOnce(context.Background(), now.Add(scheduler.RetryBackoff))
+		assert.NotNil(t, err)
+		sent, err = scheduler.RunOnce(context.Background(), now.Add(time.Hour))
+		assert.Nil(t, err)
+		assert.Equal(t, 0, sent)
+
+		notified := make([]int, 0, len(notifier.reminders))
+		for _, reminder := range notifier.reminders {
+			notified = append(notified, reminder.Id)
+		}
+		assert.Equal(t, []int{failing.Id, later.Id, failing.Id}, notified)
+		assert.Equal(t, 2, notifier.reminders[2].Attempts)
+		// retries are delivered under the same idempotency key
+		assert.Equal(t, notifier.keys[0], notifier.keys[2])
+		assert.NotEqual(t, notifier.keys[0], notifier.keys[1])
+
+		reminders, err := remindersRepo.ListByTaskId(context.Background(), tasks[0].Id)
+		assert.Nil(t, err)
+		assert.Nil(t, reminders[0].SentAt)
+		assert.NotNil(t, reminders[0].FailedAt)
+		assert.NotNil(t, reminders[1].SentAt)
+		assert.Nil(t, reminders[1].FailedAt)
+	})
+}
@@ -0,0 +1,139 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksSearch(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	commentsService := services.NewCommentsService(repos.NewCommentsRepo(conn), test_utils.NewAuthorizer(conn, tasksRepo))
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+	routes.RegisterCommentsRoutes(r, jwtAuth, workspaceResolver, commentsService)
+
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	test_utils.CreateUserWithTasks(userCred, nil, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"task_comments", "tasks", "users"})
+
+	doRequest := func(method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	search := func(query url.Values) []models.TaskListItem {
+		var tasks []models.TaskListItem
+		for {
+			resp := doRequest("GET", "/tasks/?"+query.Encode(), "")
+			assert.Equal(t, 200, resp.Code, resp.Body.String())
+			var page models.CursorPage[models.TaskListItem]
+			json.Unmarshal(resp.Body.Bytes(), &page)
+			tasks = append(tasks, page.Items...)
+			if page.Next == nil {
+				return tasks
+			}
+			query.Set("cursor", *page.Next)
+		}
+	}
+	searchNames := func(q string) []string {
+		tasks := search(url.Values{"q": {q}})
+		return test_utils.Map(tasks, func(t models.TaskListItem) string { return t.Name })
+	}
+
+	for _, task := range []struct{ name, description, comment string }{
+		{"Deploy the release", "Roll out version two to production servers", ""},
+		{"Write release notes", "", "Mention the database migration"},
+		{"Fix login page", "Users can't sign in after the release", ""},
+		{"Plan offsite", "Book a venue for the team", "Production budget is approved"},
+	} {
+		taskCreate, _ := json.Marshal(models.TaskCreate{Name: task.name, Description: &task.description})
+		resp := doRequest("POST", "/tasks/", string(taskCreate))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var created models.TaskData
+		json.Unmarshal(resp.Body.Bytes(), &created)
+
+		if task.comment != "" {
+			commentCreate, _ := json.Marshal(map[string]string{"body": task.comment})
+			resp = doRequest("POST", fmt.Sprintf("/tasks/%d/comments/", created.Id), string(commentCreate))
+			assert.Equal(t, 201, resp.Code, resp.Body.String())
+		}
+	}
+
+	t.Run("Search names, descriptions and comments", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"Deploy the release", "Plan offsite"}, searchNames("production"))
+		assert.ElementsMatch(t, []string{"Write release notes"}, searchNames("migrations"))
+		assert.ElementsMatch(t, []string{"Fix login page"}, searchNames("LOGIN"))
+	})
+
+	t.Run("Rank name matches first", func(t *testing.T) {
+		names := searchNames("release")
+		assert.Equal(t, 3, len(names), names)
+		assert.Equal(t, "Fix login page", names[2])
+	})
+
+	t.Run("Prefix, phrase and exclusion", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"Deploy the release"}, searchNames("depl*"))
+		assert.ElementsMatch(t, []string{"Deploy the release"}, searchNames(`"version two"`))
+		assert.Empty(t, searchNames(`"two version"`))
+		assert.ElementsMatch(t, []string{"Deploy the release", "Write release notes"}, searchNames("release -login"))
+		assert.ElementsMatch(t, []string{"Fix login page", "Plan offsite"}, searchNames("-deploy -notes"))
+	})
+
+	t.Run("Fuzzy matches of names", func(t *testing.T) {
+		assert.Contains(t, searchNames("ofsite"), "Plan offsite")
+	})
+
+	t.Run("Highlighted snippets", func(t *testing.T) {
+		tasks := search(url.Values{"q": {"budget"}})
+		assert.Equal(t, 1, len(tasks))
+		assert.NotNil(t, tasks[0].Snippet)
+		assert.Contains(t, *tasks[0].Snippet, "<b>budget</b>")
+
+		tasks = search(url.Values{})
+		for _, task := range tasks {
+			assert.Nil(t, task.Snippet)
+		}
+	})
+
+	t.Run("Paginate by relevance", func(t *testing.T) {
+		all := search(url.Values{"q": {"release"}})
+		paged := search(url.Values{"q": {"release"}, "limit": {"1"}})
+		assert.Equal(t, all, paged)
+
+		first := doRequest("GET", "/tasks/?q=release&limit=1", "")
+		var page models.CursorPage[models.TaskListItem]
+		json.Unmarshal(first.Body.Bytes(), &page)
+		resp := doRequest("GET", "/tasks/?q=deploy&limit=1&cursor="+url.QueryEscape(*page.Next), "")
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+	})
+}
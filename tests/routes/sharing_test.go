@@ -0,0 +1,265 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharing(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	projectsRepo := repos.NewProjectsRepo(conn)
+	aclRepo := repos.NewACLRepo(conn)
+	workspacesRepo := repos.NewWorkspacesRepo(conn)
+	auth := services.NewAuthorizer(tasksRepo, projectsRepo, aclRepo, repos.NewAssigneesRepo(conn))
+	attachmentsService := services.NewAttachmentsService(repos.NewAttachmentsRepo(conn), auth, nil)
+	workflowsService := services.NewWorkflowsService(repos.NewWorkflowsRepo(conn), auth)
+	tasksService := services.NewTasksService(
+		tasksRepo,
+		auth,
+		attachmentsService,
+		workflowsService,
+		services.NewCustomFieldsService(repos.NewCustomFieldsRepo(conn), auth),
+		test_utils.NewCursorCodec(),
+		services.NewUndoService(repos.NewUndoRepo(conn), auth, workflowsService),
+	)
+	projectsService := services.NewProjectsService(projectsRepo, auth)
+	sharingService := services.NewSharingService(aclRepo, workspacesRepo, auth)
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+	routes.RegisterProjectsRoutes(r, jwtAuth, workspaceResolver, projectsService)
+	routes.RegisterSharingRoutes(r, jwtAuth, workspaceResolver, sharingService)
+
+	ownerCred := models.UserRegister{Email: "owner@test.com", Password: "whatever"}
+	ownerData, ownerTasks := test_utils.CreateUserWithTasks(ownerCred, []models.TaskData{{Name: "Shared task", Status: "To do"}}, userRepo, tasksRepo)
+	memberCred := models.UserRegister{Email: "member@test.com", Password: "whatever"}
+	memberData, _ := test_utils.CreateUserWithTasks(memberCred, []models.TaskData{}, userRepo, tasksRepo)
+
+	// shares are only possible within a workspace, member joins the owner's one
+	workspace := test_utils.GetPersonalWorkspace(conn, ownerData.Id)
+	workspaceInvitation, err := workspacesRepo.CreateInvitation(context.Background(), workspace.Id, memberCred.Email, models.WorkspaceRoleMember, ownerData.Id)
+	assert.Nil(t, err)
+	err = workspacesRepo.AcceptInvitation(context.Background(), workspaceInvitation, memberData.Id)
+	assert.Nil(t, err)
+	defer utils.TruncateTables(conn, []string{"acl_entries", "share_invitations", "tasks", "projects", "users"})
+
+	doRequest := func(userCred models.UserRegister, method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		req.Header.Set(workspaceResolver.WorkspaceHeader, fmt.Sprint(workspace.Id))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	invite := func(path string, role string) models.InvitationData {
+		resp := doRequest(ownerCred, "POST", path, fmt.Sprintf(`{"email": "%s", "role": "%s"}`, memberCred.Email, role))
+		assert.Equal(t, 201, resp.Code, resp.Body.String())
+		var invitation models.InvitationData
+		err := json.Unmarshal(resp.Body.Bytes(), &invitation)
+		assert.Nil(t, err, resp.Body.String())
+		return invitation
+	}
+	listTasks := func(userCred models.UserRegister) []models.TaskListItem {
+		resp := doRequest(userCred, "GET", "/tasks/", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.CursorPage[models.TaskListItem]
+		err := json.Unmarshal(resp.Body.Bytes(), &page)
+		assert.Nil(t, err, resp.Body.String())
+		return page.Items
+	}
+	taskPath := fmt.Sprintf("/tasks/%d", ownerTasks[0].Id)
+	sharesPath := taskPath + "/shares/"
+
+	t.Run("Bad request on invalid input", func(t *testing.T) {
+		resp := doRequest(ownerCred, "POST", sharesPath, `{"email": "member@test.com", "role": "admin"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		resp = doRequest(ownerCred, "POST", sharesPath, `{"email": "owner@test.com", "role": "viewer"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		// not a member of the workspace
+		resp = doRequest(ownerCred, "POST", sharesPath, `{"email": "stranger@test.com", "role": "viewer"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Forbidden to manage shares of someone else's task", func(t *testing.T) {
+		resp := doRequest(memberCred, "POST", sharesPath, `{"email": "owner@test.com", "role": "viewer"}`)
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+
+		resp = doRequest(memberCred, "GET", sharesPath, "")
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Task share with viewer and editor roles", func(t *testing.T) {
+		defer utils.TruncateTables(conn, []string{"acl_entries", "share_invitations"})
+
+		// not visible before the invitation is accepted
+		assert.Equal(t, 0, len(listTasks(memberCred)))
+
+		invitation := invite(sharesPath, models.RoleViewer)
+		resp := doRequest(memberCred, "GET", "/invitations/", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var invitations []models.InvitationData
+		err := json.Unmarshal(resp.Body.Bytes(), &invitations)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, 1, len(invitations), invitations)
+
+		// only the invited user can accept
+		resp = doRequest(ownerCred, "POST", fmt.Sprintf("/invitations/%d/accept", invitation.Id), "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+		resp = doRequest(memberCred, "POST", fmt.Sprintf("/invitations/%d/accept", invitation.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		tasks := listTasks(memberCred)
+		assert.Equal(t, 1, len(tasks), tasks)
+		assert.Equal(t, ownerTasks[0].Id, tasks[0].Id)
+
+		// viewer can't modify
+		resp = doRequest(memberCred, "PATCH", taskPath, `{"status": "Done"}`)
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+
+		// re-inviting upgrades the role
+		invitation = invite(sharesPath, models.RoleEditor)
+		resp = doRequest(memberCred, "POST", fmt.Sprintf("/invitations/%d/accept", invitation.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		resp = doRequest(memberCred, "PATCH", taskPath, `{"status": "Done"}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		resp = doRequest(memberCred, "DELETE", taskPath, "")
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+
+		resp = doRequest(ownerCred, "GET", sharesPath, "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var shares models.SharesList
+		err = json.Unmarshal(resp.Body.Bytes(), &shares)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, 1, len(shares.Members), shares)
+		assert.Equal(t, models.RoleEditor, shares.Members[0].Role)
+		assert.Equal(t, 0, len(shares.Invitations), shares)
+
+		// revoke
+		resp = doRequest(ownerCred, "DELETE", fmt.Sprintf("%s%d", sharesPath, memberData.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		resp = doRequest(ownerCred, "DELETE", fmt.Sprintf("%s%d", sharesPath, memberData.Id), "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+		assert.Equal(t, 0, len(listTasks(memberCred)))
+	})
+
+	t.Run("Invitations ignore email case and are not duplicated", func(t *testing.T) {
+		defer utils.TruncateTables(conn, []string{"acl_entries", "share_invitations"})
+
+		resp := doRequest(ownerCred, "POST", sharesPath, `{"email": "OWNER@test.com", "role": "viewer"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		resp = doRequest(ownerCred, "POST", sharesPath, `{"email": "Member@Test.com", "role": "viewer"}`)
+		assert.Equal(t, 201, resp.Code, resp.Body.String())
+		var first models.InvitationData
+		err := json.Unmarshal(resp.Body.Bytes(), &first)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, memberCred.Email, first.Email)
+
+		// inviting again updates the pending invitation
+		second := invite(sharesPath, models.RoleEditor)
+		assert.Equal(t, first.Id, second.Id)
+		assert.Equal(t, models.RoleEditor, second.Role)
+
+		resp = doRequest(ownerCred, "GET", sharesPath, "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var shares models.SharesList
+		err = json.Unmarshal(resp.Body.Bytes(), &shares)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, 1, len(shares.Invitations), shares)
+
+		resp = doRequest(memberCred, "POST", fmt.Sprintf("/invitations/%d/accept", second.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		assert.Equal(t, 1, len(listTasks(memberCred)))
+	})
+
+	t.Run("Declined invitation grants nothing", func(t *testing.T) {
+		defer utils.TruncateTables(conn, []string{"acl_entries", "share_invitations"})
+
+		invitation := invite(sharesPath, models.RoleViewer)
+		resp := doRequest(memberCred, "DELETE", fmt.Sprintf("/invitations/%d", invitation.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		resp = doRequest(memberCred, "POST", fmt.Sprintf("/invitations/%d/accept", invitation.Id), "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+		assert.Equal(t, 0, len(listTasks(memberCred)))
+	})
+
+	t.Run("Project share grants access to its tasks", func(t *testing.T) {
+		defer utils.TruncateTables(conn, []string{"acl_entries", "share_invitations", "tasks", "projects"})
+
+		resp := doRequest(ownerCred, "POST", "/projects/", `{"name": "Project"}`)
+		assert.Equal(t, 201, resp.Code, resp.Body.String())
+		var project models.ProjectData
+		err := json.Unmarshal(resp.Body.Bytes(), &project)
+		assert.Nil(t, err, resp.Body.String())
+
+		projectPath := fmt.Sprintf("/projects/%d", project.Id)
+		resp = doRequest(ownerCred, "POST", "/tasks/", fmt.Sprintf(`{"name": "Project task", "project_id": %d}`, project.Id))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var projectTask models.TaskData
+		err = json.Unmarshal(resp.Body.Bytes(), &projectTask)
+		assert.Nil(t, err, resp.Body.String())
+
+		// member can't add tasks to a project not shared with them
+		resp = doRequest(memberCred, "POST", "/tasks/", fmt.Sprintf(`{"name": "Intruder", "project_id": %d}`, project.Id))
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+		resp = doRequest(memberCred, "GET", projectPath, "")
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+
+		invitation := invite(projectPath+"/shares/", models.RoleEditor)
+		resp = doRequest(memberCred, "POST", fmt.Sprintf("/invitations/%d/accept", invitation.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		resp = doRequest(memberCred, "GET", projectPath, "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		resp = doRequest(memberCred, "POST", "/tasks/", fmt.Sprintf(`{"name": "Member task", "project_id": %d}`, project.Id))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+
+		tasks := listTasks(memberCred)
+		assert.ElementsMatch(t, []string{"Project task", "Member task"}, test_utils.Map(tasks, func(t models.TaskListItem) string { return t.Name }))
+
+		role, err := auth.TaskRole(context.Background(), projectTask, memberData.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, models.RoleEditor, role)
+
+		// editors rename project tasks in place, but can't take them out
+		projectTaskPath := fmt.Sprintf("/tasks/%d", projectTask.Id)
+		resp = doRequest(memberCred, "PUT", projectTaskPath, `{"name": "Renamed"}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var renamed models.TaskData
+		err = json.Unmarshal(resp.Body.Bytes(), &renamed)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, project.Id, *renamed.ProjectId)
+
+		resp = doRequest(memberCred, "PUT", projectTaskPath, `{"name": "Renamed", "remove_project": true}`)
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+	})
+}
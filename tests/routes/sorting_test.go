@@ -0,0 +1,123 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksSorting(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	test_utils.CreateUserWithTasks(userCred, nil, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"tasks", "users"})
+
+	doRequest := func(method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	listNames := func(query url.Values) []string {
+		var names []string
+		for {
+			resp := doRequest("GET", "/tasks/?"+query.Encode(), "")
+			assert.Equal(t, 200, resp.Code, resp.Body.String())
+			var page models.CursorPage[models.TaskListItem]
+			json.Unmarshal(resp.Body.Bytes(), &page)
+			names = append(names, test_utils.Map(page.Items, func(t models.TaskListItem) string { return t.Name })...)
+			if page.Next == nil {
+				return names
+			}
+			query.Set("cursor", *page.Next)
+		}
+	}
+
+	for _, task := range []struct{ body, status string }{
+		{`{"name": "b", "priority": "high", "due_date": "2030-01-02T00:00:00Z"}`, "Done"},
+		{`{"name": "a", "priority": "low"}`, "In progress"},
+		{`{"name": "d", "due_date": "2030-01-01T00:00:00Z"}`, "To do"},
+		{`{"name": "c", "priority": "urgent", "due_date": "2030-01-03T00:00:00Z"}`, "In progress"},
+	} {
+		resp := doRequest("POST", "/tasks/", task.body)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var created models.TaskData
+		json.Unmarshal(resp.Body.Bytes(), &created)
+
+		resp = doRequest("PATCH", fmt.Sprintf("/tasks/%d", created.Id), fmt.Sprintf(`{"status": "%s"}`, task.status))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+	}
+
+	t.Run("Bad request on invalid priority", func(t *testing.T) {
+		resp := doRequest("POST", "/tasks/", `{"name": "e", "priority": "whenever"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Bad request on unknown sort key", func(t *testing.T) {
+		for _, sort := range []string{"owner", "name,", "-", "fields.abc"} {
+			resp := doRequest("GET", "/tasks/?sort="+url.QueryEscape(sort), "")
+			assert.Equal(t, 400, resp.Code, sort)
+		}
+	})
+
+	t.Run("Sort by single key", func(t *testing.T) {
+		cases := map[string][]string{
+			"name":        {"a", "b", "c", "d"},
+			"-name":       {"d", "c", "b", "a"},
+			"created_at":  {"b", "a", "d", "c"},
+			"-created_at": {"c", "d", "a", "b"},
+			"due_date":    {"d", "b", "c", "a"},
+			"-due_date":   {"c", "b", "d", "a"},
+			"priority":    {"a", "b", "c", "d"},
+			"-priority":   {"c", "b", "a", "d"},
+			"status":      {"d", "a", "c", "b"},
+		}
+		for sort, expected := range cases {
+			assert.Equal(t, expected, listNames(url.Values{"sort": {sort}}), sort)
+		}
+	})
+
+	t.Run("Sort by multiple keys across pages", func(t *testing.T) {
+		names := listNames(url.Values{"sort": {"status,-priority"}, "limit": {"1"}})
+		assert.Equal(t, []string{"d", "c", "a", "b"}, names)
+
+		names = listNames(url.Values{"sort": {"-status,name"}, "limit": {"3"}})
+		assert.Equal(t, []string{"b", "a", "c", "d"}, names)
+	})
+
+	t.Run("Sort together with filters", func(t *testing.T) {
+		names := listNames(url.Values{"sort": {"-name"}, "status": {"In progress"}, "limit": {"1"}})
+		assert.Equal(t, []string{"c", "a"}, names)
+	})
+}
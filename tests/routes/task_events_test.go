@@ -0,0 +1,278 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type serverSentEvent struct {
+	Id    string
+	Event string
+	Data  string
+}
+
+// readServerSentEvent reads the next event of the stream, skipping comments.
+func readServerSentEvent(t *testing.T, reader *bufio.Reader) serverSentEvent {
+	var event serverSentEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return event
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event.Event != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.Id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestTaskEvents(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	taskEvents := services.NewTaskEventsHub(repos.NewTaskEventsRepo(conn))
+	ctx, stopEvents := context.WithCancel(context.Background())
+	defer stopEvents()
+	go taskEvents.Run(ctx)
+
+	jwtHeaderAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	jwtCookieAuth := middlewares.NewJwtCookieAuthenticator(tp, userRepo)
+	jwtAuth := middlewares.NewJwtHeaderOrCookieAuthenticator(jwtHeaderAuth, jwtCookieAuth)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+	connectionTracker := middlewares.NewConnectionTracker(jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTaskEventsRoute(r, jwtAuth, workspaceResolver, connectionTracker, tasksService, taskEvents)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	token, _ := tp.Provide(userCred.Email)
+	userData, tasks := test_utils.CreateUserWithTasks(
+		userCred,
+		[]models.TaskData{
+			{Name: "Task 1", Status: "To do"},
+			{Name: "Task 2", Status: "In progress"},
+		},
+		userRepo,
+		tasksRepo,
+	)
+	workspace := test_utils.GetPersonalWorkspace(conn, userData.Id)
+
+	defer utils.TruncateTables(conn, []string{"tasks", "users"})
+
+	// openStream returns the response along with a reader of its events, the
+	// stream is closed by cancelling the returned func
+	openStream := func(query string, header http.Header) (*http.Response, *bufio.Reader, context.CancelFunc) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/tasks/events"+query, nil)
+		req.Header = header
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			cancel()
+			t.FailNow()
+		}
+		return resp, bufio.NewReader(resp.Body), func() {
+			cancel()
+			resp.Body.Close()
+		}
+	}
+	authHeader := func() http.Header {
+		return http.Header{"Authorization": {"Bearer " + token}}
+	}
+
+	t.Run("Unauthorized without token", func(t *testing.T) {
+		resp, _, closeStream := openStream("", http.Header{})
+		defer closeStream()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Invalid filter", func(t *testing.T) {
+		resp, _, closeStream := openStream("?query=status%3Ddone", authHeader())
+		defer closeStream()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	var lastEventId string
+	t.Run("Header auth streams the page and events", func(t *testing.T) {
+		<-taskEvents.Ready()
+		resp, reader, closeStream := openStream("?status=To%20do", authHeader())
+		defer closeStream()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		event := readServerSentEvent(t, reader)
+		assert.Equal(t, models.DashboardTasks, event.Event)
+		var page models.CursorPage[models.TaskData]
+		err := json.Unmarshal([]byte(event.Data), &page)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Task 1"}, test_utils.MapTasksToName(page.Items))
+
+		created, err := tasksRepo.Create(context.Background(), workspace.Id, models.TaskCreate{Name: "Streamed"}, "To do", userData.Id)
+		assert.NoError(t, err)
+
+		event = readServerSentEvent(t, reader)
+		assert.Equal(t, models.TaskEventCreated, event.Event)
+		assert.NotEmpty(t, event.Id)
+		var message models.TaskEventMessage
+		err = json.Unmarshal([]byte(event.Data), &message)
+		assert.NoError(t, err)
+		assert.Equal(t, created.Id, message.TaskId)
+		assert.Equal(t, "Streamed", message.Task.Name)
+		lastEventId = event.Id
+	})
+
+	t.Run("Cookie auth", func(t *testing.T) {
+		header := http.Header{"Cookie": {fmt.Sprintf("%s=%s", jwtCookieAuth.AuthCookieKey, token)}}
+		resp, reader, closeStream := openStream("", header)
+		defer closeStream()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, models.DashboardTasks, readServerSentEvent(t, reader).Event)
+	})
+
+	t.Run("Resume with Last-Event-ID", func(t *testing.T) {
+		// a task of someone else the client can't see
+		otherCred := models.UserRegister{Email: "other@test.com", Password: "whatever"}
+		otherData, _ := test_utils.CreateUserWithTasks(otherCred, nil, userRepo, tasksRepo)
+		_, err := tasksRepo.Create(context.Background(), workspace.Id, models.TaskCreate{Name: "Private"}, "To do", otherData.Id)
+		assert.NoError(t, err)
+
+		// changed while the client was away
+		_, err = tasksRepo.UpdateStatus(context.Background(), workspace.Id, tasks[0].Id, tasks[0].Status, "In progress", nil, userData.Id)
+		assert.NoError(t, err)
+
+		header := authHeader()
+		header.Set("Last-Event-ID", lastEventId)
+		resp, reader, closeStream := openStream("?status=To%20do", header)
+		defer closeStream()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// Task 1 stopped matching the filter, the private task isn't told of
+		event := readServerSentEvent(t, reader)
+		assert.Equal(t, models.TaskEventDeleted, event.Event)
+		var message models.TaskEventMessage
+		err = json.Unmarshal([]byte(event.Data), &message)
+		assert.NoError(t, err)
+		assert.Equal(t, tasks[0].Id, message.TaskId)
+	})
+
+	t.Run("Events are logged in commit order", func(t *testing.T) {
+		lastId, err := taskEvents.LastId(context.Background(), workspace.Id)
+		assert.NoError(t, err)
+		taskIds := func(events []models.TaskEvent) []int {
+			return test_utils.Map(events, func(e models.TaskEvent) int { return e.TaskId })
+		}
+
+		// the first transaction changes a task before the second one and
+		// commits after it
+		first, err := conn.Begin(context.Background())
+		assert.NoError(t, err)
+		defer first.Rollback(context.Background())
+		_, err = first.Exec(context.Background(), "UPDATE tasks SET name = 'First' WHERE id = $1", tasks[0].Id)
+		assert.NoError(t, err)
+
+		second, err := conn.Begin(context.Background())
+		assert.NoError(t, err)
+		defer second.Rollback(context.Background())
+		_, err = second.Exec(context.Background(), "UPDATE tasks SET name = 'Second' WHERE id = $1", tasks[1].Id)
+		assert.NoError(t, err)
+		assert.NoError(t, second.Commit(context.Background()))
+
+		// a client resuming in between only knows of the second change
+		events, err := taskEvents.Since(context.Background(), workspace.Id, lastId)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{tasks[1].Id}, taskIds(events))
+		if len(events) == 0 {
+			return
+		}
+		seenId := events[0].Id
+
+		assert.NoError(t, first.Commit(context.Background()))
+		events, err = taskEvents.Since(context.Background(), workspace.Id, seenId)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{tasks[0].Id}, taskIds(events))
+	})
+
+	t.Run("Start over when events are no longer kept", func(t *testing.T) {
+		_, err := taskEvents.PurgeLog(context.Background(), time.Now().UTC().Add(services.DefaultTaskEventRetention+time.Hour))
+		assert.NoError(t, err)
+		lastId, err := taskEvents.LastId(context.Background(), workspace.Id)
+		assert.NoError(t, err)
+
+		header := authHeader()
+		header.Set("Last-Event-ID", strconv.FormatInt(lastId-2, 10))
+		resp, reader, closeStream := openStream("", header)
+		defer closeStream()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		event := readServerSentEvent(t, reader)
+		assert.Equal(t, models.DashboardTasks, event.Event)
+		assert.Equal(t, strconv.FormatInt(lastId, 10), event.Id)
+	})
+
+	t.Run("Assignee changes are streamed", func(t *testing.T) {
+		assigneesRepo := repos.NewAssigneesRepo(conn)
+		resp, reader, closeStream := openStream("?assignee=me", authHeader())
+		defer closeStream()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, models.DashboardTasks, readServerSentEvent(t, reader).Event)
+
+		// the task starts matching the filter once assigned and stops once not
+		err := assigneesRepo.Assign(context.Background(), tasks[1].Id, userData.Id, userData.Id)
+		assert.NoError(t, err)
+		event := readServerSentEvent(t, reader)
+		assert.Equal(t, models.TaskEventUpdated, event.Event)
+		var message models.TaskEventMessage
+		err = json.Unmarshal([]byte(event.Data), &message)
+		assert.NoError(t, err)
+		assert.Equal(t, tasks[1].Id, message.TaskId)
+
+		err = assigneesRepo.Unassign(context.Background(), tasks[1].Id, userData.Id)
+		assert.NoError(t, err)
+		event = readServerSentEvent(t, reader)
+		assert.Equal(t, models.TaskEventDeleted, event.Event)
+		err = json.Unmarshal([]byte(event.Data), &message)
+		assert.NoError(t, err)
+		assert.Equal(t, tasks[1].Id, message.TaskId)
+	})
+
+	t.Run("Invalid Last-Event-ID", func(t *testing.T) {
+		header := authHeader()
+		header.Set("Last-Event-ID", "abc")
+		resp, _, closeStream := openStream("", header)
+		defer closeStream()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -23,10 +23,13 @@ import (
 	"pgregory.net/rapid"
 )
 
+// statuses of the default workflow, see scripts/database/schema.sql
+var defaultStatuses = []string{"Won't do", "To do", "In progress", "Done"}
+
 var (
 	taskNameGen    = rapid.StringMatching(`[^\x00]+`)
 	dueDateUnixGen = rapid.Int64Range(time.Now().UTC().Add(-72*time.Hour).Unix(), time.Now().UTC().Add(72*time.Hour).Unix())
-	statusGen      = rapid.StringMatching(fmt.Sprintf("^(%s)$", strings.Join(utils.ValidTaskStatuses, "|")))
+	statusGen      = rapid.SampledFrom(defaultStatuses)
 )
 
 func genTask(t *rapid.T, i int) models.TaskData {
@@ -51,12 +54,13 @@ func TestTasksList(t *testing.T) {
 	userRepo := repos.NewUsersRepo(conn)
 
 	tasksRepo := repos.NewTasksRepo(conn)
-	tasksService := services.NewTasksService(tasksRepo)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
 
 	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
 
 	utils.RegisterValidators()
-	routes.RegisterTasksRoutes(r, jwtAuth, tasksService)
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
 
 	t.Run("Unauthorized on empty header", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/tasks/", nil)
@@ -81,12 +85,12 @@ func TestTasksList(t *testing.T) {
 
 		assert.Equal(t, 200, resp.Code, resp.Body.String())
 
-		var tasksResp []models.TaskData
-		err := json.NewDecoder(resp.Body).Decode(&tasksResp)
+		var page models.CursorPage[models.TaskData]
+		err := json.NewDecoder(resp.Body).Decode(&page)
 		if err != nil {
 			panic(err)
 		}
-		assert.Equal(t, 0, len(tasksResp), tasksResp)
+		assert.Equal(t, 0, len(page.Items), page.Items)
 	})
 
 	t.Run("Bad request on invalid filters", func(t *testing.T) {
@@ -155,13 +159,13 @@ func TestTasksList(t *testing.T) {
 
 		assert.Equal(t, 200, resp.Code, resp.Body.String())
 
-		var tasksResp []models.TaskData
-		err := json.NewDecoder(resp.Body).Decode(&tasksResp)
+		var page models.CursorPage[models.TaskData]
+		err := json.NewDecoder(resp.Body).Decode(&page)
 		if err != nil {
 			panic(err)
 		}
-		assert.Equal(t, 3, len(tasksResp), tasksResp)
-		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:3]), test_utils.MapTasksToName(tasksResp))
+		assert.Equal(t, 3, len(page.Items), page.Items)
+		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:3]), test_utils.MapTasksToName(page.Items))
 
 		// task due date filter
 		query.Set("due_date", timeNow.Format(utils.DayDateFmt))
@@ -174,12 +178,12 @@ func TestTasksList(t *testing.T) {
 
 		assert.Equal(t, 200, resp.Code, resp.Body.String())
 
-		err = json.NewDecoder(resp.Body).Decode(&tasksResp)
+		err = json.NewDecoder(resp.Body).Decode(&page)
 		if err != nil {
 			panic(err)
 		}
-		assert.Equal(t, 2, len(tasksResp), tasksResp)
-		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:2]), test_utils.MapTasksToName(tasksResp))
+		assert.Equal(t, 2, len(page.Items), page.Items)
+		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:2]), test_utils.MapTasksToName(page.Items))
 
 		// task status filter
 		query.Set("status", "To do")
@@ -192,12 +196,12 @@ func TestTasksList(t *testing.T) {
 
 		assert.Equal(t, 200, resp.Code, resp.Body.String())
 
-		err = json.NewDecoder(resp.Body).Decode(&tasksResp)
+		err = json.NewDecoder(resp.Body).Decode(&page)
 		if err != nil {
 			panic(err)
 		}
-		assert.Equal(t, 1, len(tasksResp), tasksResp)
-		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:1]), test_utils.MapTasksToName(tasksResp))
+		assert.Equal(t, 1, len(page.Items), page.Items)
+		assert.ElementsMatch(t, test_utils.MapTasksToName(tasks[:1]), test_utils.MapTasksToName(page.Items))
 	})
 
 	t.Run("Same list as in DB", func(t *testing.T) {
@@ -237,15 +241,15 @@ func TestTasksList(t *testing.T) {
 
 			assert.Equal(t, 200, resp.Code, resp.Body.String())
 
-			var tasksResp []models.TaskData
-			err := json.NewDecoder(resp.Body).Decode(&tasksResp)
+			var page models.CursorPage[models.TaskData]
+			err := json.NewDecoder(resp.Body).Decode(&page)
 			if err != nil {
 				panic(err)
 			}
 
-			assert.Equal(t, tasksCount, len(tasksResp), tasksResp)
+			assert.Equal(t, tasksCount, len(page.Items), page.Items)
 
-			for i, task := range tasksResp {
+			for i, task := range page.Items {
 				assert.NotEmpty(t, task.Id)
 				assert.NotEmpty(t, task.CreatedAt)
 				assert.Equal(t, expectedTasks[i].Name, task.Name)
@@ -265,12 +269,13 @@ func TestTasksCreate(t *testing.T) {
 	userRepo := repos.NewUsersRepo(conn)
 
 	tasksRepo := repos.NewTasksRepo(conn)
-	tasksService := services.NewTasksService(tasksRepo)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
 
 	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
 
 	utils.RegisterValidators()
-	routes.RegisterTasksRoutes(r, jwtAuth, tasksService)
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
 
 	// create tester user
 	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
@@ -341,7 +346,7 @@ func TestTasksCreate(t *testing.T) {
 			assert.Equal(t, taskDataResp.DueDate, taskCreate.DueDate)
 			assert.Equal(t, taskDataResp.Status, "To do")
 
-			taskDataDb, err := tasksRepo.GetById(context.Background(), taskDataResp.Id)
+			taskDataDb, err := tasksRepo.GetById(context.Background(), taskDataResp.WorkspaceId, taskDataResp.Id)
 			assert.Nil(t, err)
 			assert.Equal(t, taskDataDb.Name, taskDataResp.Name)
 			assert.Equal(t, taskDataDb.DueDate, taskDataResp.DueDate)
@@ -360,12 +365,13 @@ func TestTasksDelete(t *testing.T) {
 	userRepo := repos.NewUsersRepo(conn)
 
 	tasksRepo := repos.NewTasksRepo(conn)
-	tasksService := services.NewTasksService(tasksRepo)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
 
 	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
 
 	utils.RegisterValidators()
-	routes.RegisterTasksRoutes(r, jwtAuth, tasksService)
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
 
 	// create tester user
 	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
@@ -373,6 +379,7 @@ func TestTasksDelete(t *testing.T) {
 	userAuthHeader := fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token)
 
 	userData, _ := test_utils.CreateUserWithTasks(userCred, []models.TaskData{}, userRepo, tasksRepo)
+	workspace := test_utils.GetPersonalWorkspace(conn, userData.Id)
 	defer utils.TruncateTables(conn, []string{"users"})
 
 	t.Run("Unauthorized on empty header", func(t *testing.T) {
@@ -422,7 +429,7 @@ func TestTasksDelete(t *testing.T) {
 			defer utils.TruncateTables(conn, []string{"tasks"})
 
 			taskData := genTask(t, 0)
-			createdTask, err := tasksRepo.CreateWithStatus(context.Background(), taskData.Name, taskData.DueDate, taskData.Status, userData.Id)
+			createdTask, err := tasksRepo.CreateWithStatus(context.Background(), workspace.Id, taskData.Name, taskData.DueDate, taskData.Status, userData.Id)
 			if err != nil {
 				panic(err)
 			}
@@ -435,7 +442,7 @@ func TestTasksDelete(t *testing.T) {
 
 			assert.Equal(t, 204, resp.Code, resp.Body.String())
 
-			_, err = tasksRepo.GetById(context.Background(), createdTask.Id)
+			_, err = tasksRepo.GetById(context.Background(), workspace.Id, createdTask.Id)
 			assert.Equal(t, repos.ErrNotFound, err, resp.Code)
 		})
 	})
@@ -450,12 +457,13 @@ func TestTasksUpdate(t *testing.T) {
 	userRepo := repos.NewUsersRepo(conn)
 
 	tasksRepo := repos.NewTasksRepo(conn)
-	tasksService := services.NewTasksService(tasksRepo)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
 
 	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
 
 	utils.RegisterValidators()
-	routes.RegisterTasksRoutes(r, jwtAuth, tasksService)
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
 
 	// create tester user
 	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
@@ -463,6 +471,7 @@ func TestTasksUpdate(t *testing.T) {
 	userAuthHeader := fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token)
 
 	userData, _ := test_utils.CreateUserWithTasks(userCred, []models.TaskData{}, userRepo, tasksRepo)
+	workspace := test_utils.GetPersonalWorkspace(conn, userData.Id)
 	defer utils.TruncateTables(conn, []string{"users"})
 
 	t.Run("Unauthorized on empty header", func(t *testing.T) {
@@ -516,7 +525,7 @@ func TestTasksUpdate(t *testing.T) {
 			defer utils.TruncateTables(conn, []string{"tasks"})
 
 			taskData := genTask(t, 0)
-			createdTask, err := tasksRepo.CreateWithStatus(context.Background(), taskData.Name, taskData.DueDate, taskData.Status, userData.Id)
+			createdTask, err := tasksRepo.CreateWithStatus(context.Background(), workspace.Id, taskData.Name, taskData.DueDate, taskData.Status, userData.Id)
 			if err != nil {
 				panic(err)
 			}
@@ -539,7 +548,7 @@ func TestTasksUpdate(t *testing.T) {
 			// status changed
 			assert.Equal(t, updatedTaskData.Status, statusUpdate.Status)
 
-			taskDataDb, err := tasksRepo.GetById(context.Background(), createdTask.Id)
+			taskDataDb, err := tasksRepo.GetById(context.Background(), workspace.Id, createdTask.Id)
 			assert.Nil(t, err)
 			assert.Equal(t, taskDataDb.Name, createdTask.Name)
 			assert.Equal(t, taskDataDb.DueDate, createdTask.DueDate)
@@ -548,3 +557,224 @@ func TestTasksUpdate(t *testing.T) {
 		})
 	})
 }
+
+func TestTasksEdit(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+
+	// create tester user
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	token, _ := tp.Provide(userCred.Email)
+	userAuthHeader := fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token)
+
+	userData, _ := test_utils.CreateUserWithTasks(userCred, []models.TaskData{}, userRepo, tasksRepo)
+	workspace := test_utils.GetPersonalWorkspace(conn, userData.Id)
+	defer utils.TruncateTables(conn, []string{"tasks", "projects", "users"})
+
+	doRequest := func(method string, path string, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	edit := func(path string, body string, headers map[string]string) models.TaskData {
+		resp := doRequest("PUT", path, body, headers)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var task models.TaskData
+		err := json.Unmarshal(resp.Body.Bytes(), &task)
+		assert.Nil(t, err, resp.Body.String())
+		return task
+	}
+
+	task, err := tasksRepo.CreateWithStatus(context.Background(), workspace.Id, "Draft", nil, "In progress", userData.Id)
+	assert.Nil(t, err)
+	taskPath := fmt.Sprintf("/tasks/%d", task.Id)
+	project, err := repos.NewProjectsRepo(conn).Create(context.Background(), workspace.Id, "Release", userData.Id)
+	assert.Nil(t, err)
+
+	t.Run("Bad request on invalid body", func(t *testing.T) {
+		bodies := []string{
+			`{}`,
+			`{"name": "  "}`,
+			`{"name": "Final", "priority": "whenever"}`,
+			`{"name": "Final", "due_date": "tomorrow"}`,
+			`{"name": "Final", "project_id": 1, "remove_project": true}`,
+		}
+		for _, body := range bodies {
+			resp := doRequest("PUT", taskPath, body, nil)
+			assert.Equal(t, 400, resp.Code, body)
+		}
+	})
+
+	t.Run("Not found on non existing task or project", func(t *testing.T) {
+		resp := doRequest("PUT", "/tasks/999999", `{"name": "Final"}`, nil)
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+
+		resp = doRequest("PUT", taskPath, fmt.Sprintf(`{"name": "Final", "project_id": %d}`, project.Id+1000), nil)
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		edited := edit(taskPath, `{"name": "Final", "description": "Ship it", "due_date": "2026-11-01T10:00:00+02:00", "priority": "high"}`, map[string]string{"If-Match": `"1"`})
+		assert.Equal(t, "Final", edited.Name)
+		assert.Equal(t, "Ship it", *edited.Description)
+		assert.True(t, time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC).Equal(*edited.DueDate), edited.DueDate)
+		assert.Equal(t, "high", *edited.Priority)
+		assert.Equal(t, "In progress", edited.Status)
+		assert.Equal(t, 2, edited.Version)
+
+		// the other client still holds the first version
+		resp := doRequest("PUT", taskPath, `{"name": "Stale"}`, map[string]string{"If-Match": `"1"`})
+		assert.Equal(t, 412, resp.Code, resp.Body.String())
+
+		// fields left out are cleared
+		moved := edit(taskPath, fmt.Sprintf(`{"name": "Final", "project_id": %d}`, project.Id), nil)
+		assert.Equal(t, project.Id, *moved.ProjectId)
+		assert.Nil(t, moved.Description)
+		assert.Nil(t, moved.DueDate)
+		assert.Nil(t, moved.Priority)
+		assert.Equal(t, "In progress", moved.Status)
+
+		// the task stays in its project unless it's taken out
+		moved = edit(taskPath, `{"name": "Final"}`, nil)
+		assert.Equal(t, project.Id, *moved.ProjectId)
+
+		moved = edit(taskPath, `{"name": "Final", "remove_project": true}`, nil)
+		assert.Nil(t, moved.ProjectId)
+		assert.Equal(t, "In progress", moved.Status)
+	})
+
+	t.Run("Reminders follow the due date", func(t *testing.T) {
+		remindersRepo := repos.NewRemindersRepo(conn)
+		edited := edit(taskPath, `{"name": "Final", "due_date": "2026-11-01T10:00:00Z"}`, nil)
+		offset := 60
+		relative, err := remindersRepo.Create(context.Background(), task.Id, edited.DueDate.Add(-time.Hour), &offset)
+		assert.Nil(t, err)
+		fixed, err := remindersRepo.Create(context.Background(), task.Id, edited.DueDate.Add(-2*time.Hour), nil)
+		assert.Nil(t, err)
+
+		edit(taskPath, `{"name": "Final", "due_date": "2026-11-02T10:00:00Z"}`, nil)
+		reminders, err := remindersRepo.ListByTaskId(context.Background(), task.Id)
+		assert.Nil(t, err)
+		remindAt := map[int]time.Time{}
+		for _, reminder := range reminders {
+			remindAt[reminder.Id] = reminder.RemindAt
+		}
+		assert.True(t, time.Date(2026, 11, 2, 9, 0, 0, 0, time.UTC).Equal(remindAt[relative.Id]), remindAt)
+		assert.True(t, fixed.RemindAt.Equal(remindAt[fixed.Id]), remindAt)
+
+		// without a due date there's nothing to be reminded relative to
+		edit(taskPath, `{"name": "Final"}`, nil)
+		reminders, err = remindersRepo.ListByTaskId(context.Background(), task.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, []int{fixed.Id}, test_utils.Map(reminders, func(r models.ReminderData) int { return r.Id }))
+	})
+}
+
+func TestTasksConditionalRequests(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+
+	// create tester user
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	token, _ := tp.Provide(userCred.Email)
+	userAuthHeader := fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token)
+
+	userData, _ := test_utils.CreateUserWithTasks(userCred, []models.TaskData{}, userRepo, tasksRepo)
+	workspace := test_utils.GetPersonalWorkspace(conn, userData.Id)
+	defer utils.TruncateTables(conn, []string{"tasks", "users"})
+
+	doRequest := func(method string, path string, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, userAuthHeader)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	task, err := tasksRepo.CreateWithStatus(context.Background(), workspace.Id, "Contended", nil, "To do", userData.Id)
+	assert.Nil(t, err)
+	taskPath := fmt.Sprintf("/tasks/%d", task.Id)
+
+	t.Run("Get task with ETag", func(t *testing.T) {
+		resp := doRequest("GET", taskPath, "", nil)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Equal(t, `"1"`, resp.Header().Get("ETag"))
+
+		resp = doRequest("GET", taskPath, "", map[string]string{"If-None-Match": `"7", W/"1"`})
+		assert.Equal(t, 304, resp.Code, resp.Body.String())
+		assert.Empty(t, resp.Body.String())
+
+		resp = doRequest("GET", taskPath, "", map[string]string{"If-None-Match": `"2"`})
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Stale update fails", func(t *testing.T) {
+		resp := doRequest("PATCH", taskPath, `{"status": "In progress"}`, map[string]string{"If-Match": `"1"`})
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Equal(t, `"2"`, resp.Header().Get("ETag"))
+
+		// the other client still holds the first version
+		resp = doRequest("PATCH", taskPath, `{"status": "Done"}`, map[string]string{"If-Match": `"1"`})
+		assert.Equal(t, 412, resp.Code, resp.Body.String())
+		resp = doRequest("PATCH", taskPath, `{"status": "In progress"}`, map[string]string{"If-Match": `"1"`})
+		assert.Equal(t, 412, resp.Code, resp.Body.String())
+		resp = doRequest("PATCH", taskPath, `{"status": "Done"}`, map[string]string{"If-Match": `W/"2"`})
+		assert.Equal(t, 412, resp.Code, resp.Body.String())
+
+		taskDb, err := tasksRepo.GetById(context.Background(), workspace.Id, task.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, "In progress", taskDb.Status)
+
+		// transitions checked from a status the task has left since aren't made
+		_, err = tasksRepo.UpdateStatus(context.Background(), workspace.Id, task.Id, "To do", "Done", nil, userData.Id)
+		assert.Equal(t, repos.ErrVersionMismatch, err)
+
+		resp = doRequest("PATCH", taskPath, `{"status": "Done"}`, map[string]string{"If-Match": "*"})
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Equal(t, `"3"`, resp.Header().Get("ETag"))
+	})
+
+	t.Run("Stale delete fails", func(t *testing.T) {
+		resp := doRequest("DELETE", taskPath, "", map[string]string{"If-Match": `"2"`})
+		assert.Equal(t, 412, resp.Code, resp.Body.String())
+
+		resp = doRequest("DELETE", taskPath, "", map[string]string{"If-Match": `"3"`})
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		resp = doRequest("GET", taskPath, "", nil)
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+}
@@ -0,0 +1,148 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrash(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	workspacesRepo := repos.NewWorkspacesRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	trashCollector := services.NewTrashCollector(tasksService)
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(workspacesRepo, jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+
+	ownerCred := models.UserRegister{Email: "owner@test.com", Password: "whatever"}
+	ownerData, _ := test_utils.CreateUserWithTasks(ownerCred, nil, userRepo, tasksRepo)
+	memberCred := models.UserRegister{Email: "member@test.com", Password: "whatever"}
+	memberData, _ := test_utils.CreateUserWithTasks(memberCred, nil, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"tasks", "users"})
+
+	// member joins owner's personal workspace
+	workspace := test_utils.GetPersonalWorkspace(conn, ownerData.Id)
+	invitation, err := workspacesRepo.CreateInvitation(context.Background(), workspace.Id, memberCred.Email, models.WorkspaceRoleMember, ownerData.Id)
+	assert.Nil(t, err)
+	err = workspacesRepo.AcceptInvitation(context.Background(), invitation, memberData.Id)
+	assert.Nil(t, err)
+
+	doRequest := func(userCred models.UserRegister, method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		req.Header.Set(workspaceResolver.WorkspaceHeader, fmt.Sprint(workspace.Id))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	createTask := func(name string) models.TaskData {
+		resp := doRequest(ownerCred, "POST", "/tasks/", fmt.Sprintf(`{"name": "%s"}`, name))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var task models.TaskData
+		json.Unmarshal(resp.Body.Bytes(), &task)
+		return task
+	}
+	deleteTask := func(task models.TaskData) {
+		resp := doRequest(ownerCred, "DELETE", fmt.Sprintf("/tasks/%d", task.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+	}
+	listTrash := func(userCred models.UserRegister) []models.TrashedTask {
+		resp := doRequest(userCred, "GET", "/tasks/trash/", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var tasks []models.TrashedTask
+		err := json.Unmarshal(resp.Body.Bytes(), &tasks)
+		assert.Nil(t, err, resp.Body.String())
+		return tasks
+	}
+	listTaskNames := func() []string {
+		resp := doRequest(ownerCred, "GET", "/tasks/", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.CursorPage[models.TaskListItem]
+		json.Unmarshal(resp.Body.Bytes(), &page)
+		return test_utils.Map(page.Items, func(t models.TaskListItem) string { return t.Name })
+	}
+
+	t.Run("Deleted task moves to trash and back", func(t *testing.T) {
+		task := createTask("restored")
+		deleteTask(task)
+		assert.NotContains(t, listTaskNames(), "restored")
+
+		resp := doRequest(ownerCred, "PATCH", fmt.Sprintf("/tasks/%d", task.Id), `{"status": "Done"}`)
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+
+		trash := listTrash(ownerCred)
+		assert.Equal(t, 1, len(trash))
+		assert.Equal(t, task.Id, trash[0].Id)
+		assert.Equal(t, ownerData.Id, *trash[0].DeletedBy)
+		assert.Equal(t, trash[0].DeletedAt.Add(services.DefaultTrashRetentionDays*24*time.Hour), trash[0].PurgeAt)
+		assert.Equal(t, 0, len(listTrash(memberCred)))
+
+		resp = doRequest(memberCred, "POST", fmt.Sprintf("/tasks/trash/%d/restore", task.Id), "")
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+
+		resp = doRequest(ownerCred, "POST", fmt.Sprintf("/tasks/trash/%d/restore", task.Id), "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Contains(t, listTaskNames(), "restored")
+		assert.Equal(t, 0, len(listTrash(ownerCred)))
+
+		resp = doRequest(ownerCred, "POST", fmt.Sprintf("/tasks/trash/%d/restore", task.Id), "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Purge task from trash", func(t *testing.T) {
+		task := createTask("purged")
+		resp := doRequest(ownerCred, "DELETE", fmt.Sprintf("/tasks/trash/%d", task.Id), "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+
+		deleteTask(task)
+		resp = doRequest(memberCred, "DELETE", fmt.Sprintf("/tasks/trash/%d", task.Id), "")
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+		resp = doRequest(ownerCred, "DELETE", fmt.Sprintf("/tasks/trash/%d", task.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		assert.Equal(t, 0, len(listTrash(ownerCred)))
+		resp = doRequest(ownerCred, "POST", fmt.Sprintf("/tasks/trash/%d/restore", task.Id), "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Collector purges expired tasks", func(t *testing.T) {
+		task := createTask("expired")
+		deleteTask(task)
+
+		purged, err := trashCollector.RunOnce(context.Background(), time.Now().UTC())
+		assert.Nil(t, err)
+		assert.Equal(t, 0, purged)
+
+		purged, err = trashCollector.RunOnce(context.Background(), time.Now().UTC().Add(tasksService.TrashRetention+time.Hour))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, purged)
+		assert.Equal(t, 0, len(listTrash(ownerCred)))
+	})
+}
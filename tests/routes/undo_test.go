@@ -0,0 +1,200 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndo(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	workspacesRepo := repos.NewWorkspacesRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(workspacesRepo, jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+	routes.RegisterUndoRoutes(r, jwtAuth, workspaceResolver, tasksService.Undo)
+
+	ownerCred := models.UserRegister{Email: "owner@test.com", Password: "whatever"}
+	ownerData, _ := test_utils.CreateUserWithTasks(ownerCred, nil, userRepo, tasksRepo)
+	memberCred := models.UserRegister{Email: "member@test.com", Password: "whatever"}
+	memberData, _ := test_utils.CreateUserWithTasks(memberCred, nil, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"undo_entries", "task_history", "tasks", "projects", "users"})
+
+	// member joins owner's personal workspace
+	workspace := test_utils.GetPersonalWorkspace(conn, ownerData.Id)
+	invitation, err := workspacesRepo.CreateInvitation(context.Background(), workspace.Id, memberCred.Email, models.WorkspaceRoleMember, ownerData.Id)
+	assert.Nil(t, err)
+	err = workspacesRepo.AcceptInvitation(context.Background(), invitation, memberData.Id)
+	assert.Nil(t, err)
+	project, err := repos.NewProjectsRepo(conn).Create(context.Background(), workspace.Id, "Undoable", ownerData.Id)
+	assert.Nil(t, err)
+
+	doRequest := func(userCred models.UserRegister, method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		req.Header.Set(workspaceResolver.WorkspaceHeader, fmt.Sprint(workspace.Id))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	createTask := func(name string) models.TaskData {
+		resp := doRequest(ownerCred, "POST", "/tasks/", fmt.Sprintf(`{"name": "%s"}`, name))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var task models.TaskData
+		json.Unmarshal(resp.Body.Bytes(), &task)
+		return task
+	}
+	getTask := func(id int) models.TaskData {
+		resp := doRequest(ownerCred, "GET", fmt.Sprintf("/tasks/%d", id), "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var task models.TaskData
+		json.Unmarshal(resp.Body.Bytes(), &task)
+		return task
+	}
+	undo := func(userCred models.UserRegister, token string) *httptest.ResponseRecorder {
+		return doRequest(userCred, "POST", "/undo/"+token, "")
+	}
+
+	t.Run("Undo delete", func(t *testing.T) {
+		task := createTask("deleted")
+		resp := doRequest(ownerCred, "DELETE", fmt.Sprintf("/tasks/%d", task.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		token := resp.Header().Get("Undo-Token")
+		assert.NotEmpty(t, token)
+
+		resp = undo(memberCred, token)
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+
+		resp = undo(ownerCred, token)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var tasks []models.TaskData
+		json.Unmarshal(resp.Body.Bytes(), &tasks)
+		assert.Equal(t, []int{task.Id}, test_utils.Map(tasks, func(t models.TaskData) int { return t.Id }))
+		assert.Equal(t, "deleted", getTask(task.Id).Name)
+
+		resp = undo(ownerCred, token)
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Undo delete falls back to the initial status of the workflow", func(t *testing.T) {
+		resp := doRequest(ownerCred, "POST", "/tasks/", fmt.Sprintf(`{"name": "stale status", "project_id": %d}`, project.Id))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var task models.TaskData
+		json.Unmarshal(resp.Body.Bytes(), &task)
+
+		resp = doRequest(ownerCred, "DELETE", fmt.Sprintf("/tasks/%d", task.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		token := resp.Header().Get("Undo-Token")
+
+		// the status of the task is removed while it's in the trash
+		_, err := repos.NewWorkflowsRepo(conn).Replace(context.Background(), project.Id, []models.StatusCreate{{Name: "Backlog", Category: "todo"}}, nil)
+		assert.Nil(t, err)
+		// the project goes back to the default workflow
+		defer conn.Exec(context.Background(), "DELETE FROM workflow_statuses WHERE project_id = $1", project.Id)
+
+		resp = undo(ownerCred, token)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Equal(t, "Backlog", getTask(task.Id).Status)
+	})
+
+	t.Run("Undo status change unless changed again", func(t *testing.T) {
+		task := createTask("moving")
+		taskPath := fmt.Sprintf("/tasks/%d", task.Id)
+
+		resp := doRequest(ownerCred, "PATCH", taskPath, `{"status": "In progress"}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		token := resp.Header().Get("Undo-Token")
+		resp = undo(ownerCred, token)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Equal(t, "To do", getTask(task.Id).Status)
+
+		resp = doRequest(ownerCred, "PATCH", taskPath, `{"status": "In progress"}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		token = resp.Header().Get("Undo-Token")
+		resp = doRequest(ownerCred, "PUT", taskPath, `{"name": "moved"}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+
+		resp = undo(ownerCred, token)
+		assert.Equal(t, 409, resp.Code, resp.Body.String())
+		assert.Equal(t, "In progress", getTask(task.Id).Status)
+
+		// setting the same status again changes nothing to undo
+		resp = doRequest(ownerCred, "PATCH", taskPath, `{"status": "In progress"}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Empty(t, resp.Header().Get("Undo-Token"))
+	})
+
+	t.Run("Undo bulk edit as a whole", func(t *testing.T) {
+		a, b := createTask("a"), createTask("b")
+		resp := doRequest(ownerCred, "POST", "/tasks/bulk", fmt.Sprintf(`{"ids": [%d], "action": "edit_labels", "add_labels": ["keep"]}`, a.Id))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+
+		resp = doRequest(ownerCred, "POST", "/tasks/bulk", fmt.Sprintf(`{"ids": [%d, %d], "action": "edit_labels", "add_labels": ["bulk"]}`, a.Id, b.Id))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var result models.BulkTasksResult
+		json.Unmarshal(resp.Body.Bytes(), &result)
+		assert.NotEmpty(t, result.UndoToken)
+		assert.Equal(t, result.UndoToken, resp.Header().Get("Undo-Token"))
+
+		resp = undo(ownerCred, result.UndoToken)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Equal(t, []string{"keep"}, getTask(a.Id).Labels)
+		assert.Equal(t, []string{}, getTask(b.Id).Labels)
+
+		resp = doRequest(ownerCred, "POST", "/tasks/bulk", fmt.Sprintf(`{"ids": [%d, %d], "action": "move", "project_id": %d}`, a.Id, b.Id, project.Id))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		json.Unmarshal(resp.Body.Bytes(), &result)
+		resp = doRequest(ownerCred, "DELETE", fmt.Sprintf("/tasks/%d", b.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		resp = undo(ownerCred, result.UndoToken)
+		assert.Equal(t, 409, resp.Code, resp.Body.String())
+		assert.Equal(t, project.Id, *getTask(a.Id).ProjectId)
+	})
+
+	t.Run("Undo stack and window", func(t *testing.T) {
+		resp := doRequest(ownerCred, "GET", "/undo/", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var entries []models.UndoEntry
+		json.Unmarshal(resp.Body.Bytes(), &entries)
+		assert.Equal(t, models.BulkDelete, entries[0].Action)
+		assert.Equal(t, models.BulkMove, entries[1].Action)
+		assert.Equal(t, 2, len(entries[1].TaskIds))
+
+		tasksService.Undo.Window = -time.Second
+		defer func() { tasksService.Undo.Window = services.DefaultUndoWindow }()
+		task := createTask("expired")
+		resp = doRequest(ownerCred, "DELETE", fmt.Sprintf("/tasks/%d", task.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		resp = undo(ownerCred, resp.Header().Get("Undo-Token"))
+		assert.Equal(t, 410, resp.Code, resp.Body.String())
+	})
+}
@@ -0,0 +1,176 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViews(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	workspacesRepo := repos.NewWorkspacesRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	viewsService := services.NewViewsService(repos.NewViewsRepo(conn), tasksService)
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(workspacesRepo, jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+	routes.RegisterViewsRoutes(r, jwtAuth, workspaceResolver, viewsService)
+
+	ownerCred := models.UserRegister{Email: "owner@test.com", Password: "whatever"}
+	ownerData, _ := test_utils.CreateUserWithTasks(ownerCred, nil, userRepo, tasksRepo)
+	memberCred := models.UserRegister{Email: "member@test.com", Password: "whatever"}
+	memberData, _ := test_utils.CreateUserWithTasks(memberCred, nil, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"saved_views", "tasks", "users"})
+
+	// member joins owner's personal workspace
+	workspace := test_utils.GetPersonalWorkspace(conn, ownerData.Id)
+	invitation, err := workspacesRepo.CreateInvitation(context.Background(), workspace.Id, memberCred.Email, models.WorkspaceRoleMember, ownerData.Id)
+	assert.Nil(t, err)
+	err = workspacesRepo.AcceptInvitation(context.Background(), invitation, memberData.Id)
+	assert.Nil(t, err)
+
+	doRequest := func(userCred models.UserRegister, method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		req.Header.Set(workspaceResolver.WorkspaceHeader, fmt.Sprint(workspace.Id))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	createView := func(body string) models.ViewData {
+		resp := doRequest(ownerCred, "POST", "/views/", body)
+		assert.Equal(t, 201, resp.Code, resp.Body.String())
+		var view models.ViewData
+		json.Unmarshal(resp.Body.Bytes(), &view)
+		return view
+	}
+	listGroups := func(userCred models.UserRegister, path string) models.CursorPage[models.TaskGroup] {
+		resp := doRequest(userCred, "GET", path, "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.CursorPage[models.TaskGroup]
+		err := json.Unmarshal(resp.Body.Bytes(), &page)
+		assert.Nil(t, err, resp.Body.String())
+		return page
+	}
+	groupNames := func(groups []models.TaskGroup) map[any][]string {
+		names := make(map[any][]string)
+		for _, group := range groups {
+			names[group.Key] = test_utils.Map(group.Tasks, func(t models.TaskListItem) string { return t.Name })
+		}
+		return names
+	}
+
+	for _, body := range []string{
+		`{"name": "a", "priority": "high"}`,
+		`{"name": "b", "priority": "low", "due_date": "2030-01-01T00:00:00Z"}`,
+		`{"name": "c", "priority": "high", "due_date": "2030-01-02T00:00:00Z"}`,
+		`{"name": "d"}`,
+	} {
+		resp := doRequest(ownerCred, "POST", "/tasks/", body)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+	}
+
+	t.Run("Bad request on invalid definition", func(t *testing.T) {
+		for _, body := range []string{
+			`{"filter": {}}`,
+			`{"name": "v", "group_by": "owner"}`,
+			`{"name": "v", "group_by": "fields.999"}`,
+			`{"name": "v", "filter": {"due_date": "someday"}}`,
+			`{"name": "v", "filter": {"status": "Nonexistent"}}`,
+			`{"name": "v", "filter": {"query": "owner:me"}}`,
+			`{"name": "v", "filter": {"sort": "-nothing"}}`,
+			`{"name": "v", "filter": {"limit": 1000}}`,
+		} {
+			resp := doRequest(ownerCred, "POST", "/views/", body)
+			assert.Equal(t, 400, resp.Code, body)
+		}
+	})
+
+	t.Run("Create, update and delete view", func(t *testing.T) {
+		view := createView(`{"name": "Due soon", "filter": {"has_due_date": true, "sort": "due_date"}}`)
+		assert.Equal(t, "Due soon", view.Name)
+		assert.Equal(t, ownerData.Id, view.UserId)
+		assert.False(t, view.Shared)
+
+		page := listGroups(ownerCred, fmt.Sprintf("/views/%d/tasks", view.Id))
+		assert.Equal(t, 1, len(page.Items))
+		assert.Nil(t, page.Items[0].Key)
+		assert.Equal(t, map[any][]string{nil: {"b", "c"}}, groupNames(page.Items))
+
+		viewPath := fmt.Sprintf("/views/%d", view.Id)
+		resp := doRequest(ownerCred, "PUT", viewPath, `{"name": "Due later", "filter": {"has_due_date": true, "sort": "-due_date"}}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		page = listGroups(ownerCred, viewPath+"/tasks")
+		assert.Equal(t, map[any][]string{nil: {"c", "b"}}, groupNames(page.Items))
+
+		resp = doRequest(ownerCred, "DELETE", viewPath, "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		resp = doRequest(ownerCred, "GET", viewPath, "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Group tasks across pages", func(t *testing.T) {
+		view := createView(`{"name": "By priority", "group_by": "priority", "filter": {"sort": "name"}}`)
+		viewPath := fmt.Sprintf("/views/%d/tasks", view.Id)
+
+		first := listGroups(ownerCred, viewPath+"?limit=2")
+		assert.Equal(t, map[any][]string{"low": {"b"}, "high": {"a"}}, groupNames(first.Items))
+		assert.Equal(t, "low", first.Items[0].Key)
+		assert.NotNil(t, first.Next)
+
+		second := listGroups(ownerCred, viewPath+"?limit=2&cursor="+*first.Next)
+		assert.Equal(t, map[any][]string{"high": {"c"}, nil: {"d"}}, groupNames(second.Items))
+		assert.Nil(t, second.Next)
+
+		resp := doRequest(ownerCred, "GET", viewPath+"?limit=2&cursor=garbage", "")
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Share view with workspace", func(t *testing.T) {
+		view := createView(`{"name": "Mine", "filter": {"assignee": "me"}}`)
+		viewPath := fmt.Sprintf("/views/%d", view.Id)
+
+		resp := doRequest(memberCred, "GET", viewPath, "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+		resp = doRequest(memberCred, "GET", "/views/", "")
+		assert.NotContains(t, resp.Body.String(), `"Mine"`)
+
+		resp = doRequest(ownerCred, "PUT", viewPath, `{"name": "Mine", "filter": {"assignee": "me"}, "shared": true}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+
+		resp = doRequest(memberCred, "GET", "/views/", "")
+		assert.Contains(t, resp.Body.String(), `"Mine"`)
+		page := listGroups(memberCred, viewPath+"/tasks")
+		assert.Equal(t, 0, len(page.Items))
+
+		resp = doRequest(memberCred, "PUT", viewPath, `{"name": "Theirs"}`)
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+		resp = doRequest(memberCred, "DELETE", viewPath, "")
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+	})
+}
@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflows(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	auth := test_utils.NewAuthorizer(conn, tasksRepo)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	projectsService := services.NewProjectsService(repos.NewProjectsRepo(conn), auth)
+	workflowsService := services.NewWorkflowsService(repos.NewWorkflowsRepo(conn), auth)
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(repos.NewWorkspacesRepo(conn), jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+	routes.RegisterProjectsRoutes(r, jwtAuth, workspaceResolver, projectsService)
+	routes.RegisterWorkflowsRoutes(r, jwtAuth, workspaceResolver, workflowsService)
+
+	userCred := models.UserRegister{Email: "tester@test.com", Password: "whatever"}
+	test_utils.CreateUserWithTasks(userCred, []models.TaskData{}, userRepo, tasksRepo)
+	otherUserCred := models.UserRegister{Email: "other@other.com", Password: "whatever"}
+	test_utils.CreateUserWithTasks(otherUserCred, []models.TaskData{}, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"tasks", "projects", "users"})
+
+	doRequest := func(userCred models.UserRegister, method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	decodeWorkflow := func(resp *httptest.ResponseRecorder) models.WorkflowData {
+		var workflow models.WorkflowData
+		err := json.Unmarshal(resp.Body.Bytes(), &workflow)
+		assert.Nil(t, err, resp.Body.String())
+		return workflow
+	}
+	statusNames := func(workflow models.WorkflowData) []string {
+		return test_utils.Map(workflow.Statuses, func(s models.StatusData) string { return s.Name })
+	}
+
+	resp := doRequest(userCred, "POST", "/projects/", `{"name": "Release"}`)
+	assert.Equal(t, 201, resp.Code, resp.Body.String())
+	var project models.ProjectData
+	json.Unmarshal(resp.Body.Bytes(), &project)
+	workflowPath := fmt.Sprintf("/projects/%d/workflow", project.Id)
+
+	t.Run("Project uses default workflow", func(t *testing.T) {
+		resp := doRequest(userCred, "GET", workflowPath, "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		workflow := decodeWorkflow(resp)
+		assert.Nil(t, workflow.ProjectId)
+		assert.Equal(t, []string{"To do", "In progress", "Done", "Won't do"}, statusNames(workflow))
+		assert.Equal(t, 12, len(workflow.Transitions))
+
+		resp = doRequest(otherUserCred, "GET", workflowPath, "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Bad request on invalid workflow", func(t *testing.T) {
+		for _, body := range []string{
+			`{"statuses": []}`,
+			`{"statuses": [{"name": "Backlog", "category": "unknown"}]}`,
+			`{"statuses": [{"name": "Backlog", "category": "todo"}, {"name": "Backlog", "category": "done"}]}`,
+			`{"statuses": [{"name": "Backlog", "category": "todo"}], "transitions": [{"from": "Backlog", "to": "Shipped"}]}`,
+		} {
+			resp := doRequest(userCred, "PUT", workflowPath, body)
+			assert.Equal(t, 400, resp.Code, body)
+		}
+	})
+
+	t.Run("Custom workflow restricts transitions", func(t *testing.T) {
+		resp := doRequest(userCred, "PUT", workflowPath, `{
+			"statuses": [
+				{"name": "Backlog", "category": "todo"},
+				{"name": "In review", "category": "active"},
+				{"name": "Shipped", "category": "done"}
+			],
+			"transitions": [
+				{"from": "Backlog", "to": "In review"},
+				{"from": "In review", "to": "Backlog"},
+				{"from": "In review", "to": "Shipped"}
+			]
+		}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		workflow := decodeWorkflow(resp)
+		assert.Equal(t, &project.Id, workflow.ProjectId)
+		assert.Equal(t, []string{"Backlog", "In review", "Shipped"}, statusNames(workflow))
+		assert.Equal(t, models.StatusCategoryActive, workflow.Statuses[1].Category)
+
+		// new tasks start in the first status
+		resp = doRequest(userCred, "POST", "/tasks/", fmt.Sprintf(`{"name": "Feature", "project_id": %d}`, project.Id))
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var task models.TaskData
+		json.Unmarshal(resp.Body.Bytes(), &task)
+		assert.Equal(t, "Backlog", task.Status)
+		taskPath := fmt.Sprintf("/tasks/%d", task.Id)
+
+		resp = doRequest(userCred, "PATCH", taskPath, `{"status": "Done"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+		resp = doRequest(userCred, "PATCH", taskPath, `{"status": "Shipped"}`)
+		assert.Equal(t, 409, resp.Code, resp.Body.String())
+		resp = doRequest(userCred, "PATCH", taskPath, `{"status": "In review"}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		resp = doRequest(userCred, "PATCH", taskPath, `{"status": "Shipped"}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+
+		// custom statuses can be filtered on
+		resp = doRequest(userCred, "GET", "/tasks/?status=Shipped", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.CursorPage[models.TaskListItem]
+		json.Unmarshal(resp.Body.Bytes(), &page)
+		assert.Equal(t, 1, len(page.Items))
+
+		// tasks outside of the project keep the default workflow
+		resp = doRequest(userCred, "POST", "/tasks/", `{"name": "Chore"}`)
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		json.Unmarshal(resp.Body.Bytes(), &task)
+		assert.Equal(t, "To do", task.Status)
+		resp = doRequest(userCred, "PATCH", fmt.Sprintf("/tasks/%d", task.Id), `{"status": "Shipped"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		// statuses used by tasks can't be removed
+		resp = doRequest(userCred, "PUT", workflowPath, `{"statuses": [{"name": "Backlog", "category": "todo"}]}`)
+		assert.Equal(t, 409, resp.Code, resp.Body.String())
+		resp = doRequest(userCred, "GET", workflowPath, "")
+		assert.Equal(t, []string{"Backlog", "In review", "Shipped"}, statusNames(decodeWorkflow(resp)))
+	})
+}
@@ -0,0 +1,259 @@
+package routes
+
+import (
+	"api-server/app/middlewares"
+	"api-server/app/routes"
+	"api-server/db"
+	"api-server/domain/models"
+	"api-server/domain/repos"
+	"api-server/domain/services"
+	test_utils "api-server/tests/utils"
+	"api-server/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"pgregory.net/rapid"
+)
+
+func TestWorkspaces(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	workspacesRepo := repos.NewWorkspacesRepo(conn)
+	workspacesService := services.NewWorkspacesService(workspacesRepo)
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+
+	utils.RegisterValidators()
+	routes.RegisterWorkspacesRoutes(r, jwtAuth, workspacesService)
+
+	ownerCred := models.UserRegister{Email: "owner@test.com", Password: "whatever"}
+	ownerData, _ := test_utils.CreateUserWithTasks(ownerCred, []models.TaskData{}, userRepo, tasksRepo)
+	memberCred := models.UserRegister{Email: "member@test.com", Password: "whatever"}
+	memberData, _ := test_utils.CreateUserWithTasks(memberCred, []models.TaskData{}, userRepo, tasksRepo)
+	defer utils.TruncateTables(conn, []string{"users"})
+
+	doRequest := func(userCred models.UserRegister, method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Personal workspace is created with user", func(t *testing.T) {
+		resp := doRequest(ownerCred, "GET", "/workspaces/", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var workspaces []models.UserWorkspace
+		err := json.Unmarshal(resp.Body.Bytes(), &workspaces)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, 1, len(workspaces), workspaces)
+		assert.True(t, workspaces[0].Personal)
+		assert.Equal(t, models.WorkspaceRoleOwner, workspaces[0].Role)
+	})
+
+	t.Run("Bad request on invalid input", func(t *testing.T) {
+		resp := doRequest(ownerCred, "POST", "/workspaces/", `{"name": ""}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		workspace := test_utils.GetPersonalWorkspace(conn, ownerData.Id)
+		resp = doRequest(ownerCred, "POST", fmt.Sprintf("/workspaces/%d/invitations/", workspace.Id), `{"email": "member@test.com", "role": "owner"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Membership lifecycle", func(t *testing.T) {
+		resp := doRequest(ownerCred, "POST", "/workspaces/", `{"name": "Team"}`)
+		assert.Equal(t, 201, resp.Code, resp.Body.String())
+		var workspace models.UserWorkspace
+		err := json.Unmarshal(resp.Body.Bytes(), &workspace)
+		assert.Nil(t, err, resp.Body.String())
+		assert.False(t, workspace.Personal)
+
+		workspacePath := fmt.Sprintf("/workspaces/%d", workspace.Id)
+
+		// non-members don't see the workspace at all
+		resp = doRequest(memberCred, "GET", workspacePath+"/members/", "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+
+		resp = doRequest(ownerCred, "POST", workspacePath+"/invitations/", `{"email": "OWNER@test.com", "role": "member"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+		resp = doRequest(ownerCred, "POST", workspacePath+"/invitations/", `{"email": "member@test.com", "role": "admin"}`)
+		assert.Equal(t, 201, resp.Code, resp.Body.String())
+		var invitation models.WorkspaceInvitationData
+		err = json.Unmarshal(resp.Body.Bytes(), &invitation)
+		assert.Nil(t, err, resp.Body.String())
+
+		// inviting again, in any case, updates the pending invitation
+		resp = doRequest(ownerCred, "POST", workspacePath+"/invitations/", `{"email": "Member@Test.com", "role": "member"}`)
+		assert.Equal(t, 201, resp.Code, resp.Body.String())
+		var reinvitation models.WorkspaceInvitationData
+		err = json.Unmarshal(resp.Body.Bytes(), &reinvitation)
+		assert.Nil(t, err, resp.Body.String())
+		assert.Equal(t, invitation.Id, reinvitation.Id)
+		assert.Equal(t, memberCred.Email, reinvitation.Email)
+		assert.Equal(t, models.WorkspaceRoleMember, reinvitation.Role)
+
+		// only the invited user can accept
+		resp = doRequest(ownerCred, "POST", fmt.Sprintf("/workspaces/invitations/%d/accept", invitation.Id), "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+		resp = doRequest(memberCred, "GET", "/workspaces/invitations/", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		assert.Contains(t, resp.Body.String(), fmt.Sprintf(`"id":%d`, invitation.Id))
+		resp = doRequest(memberCred, "POST", fmt.Sprintf("/workspaces/invitations/%d/accept", invitation.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+
+		resp = doRequest(memberCred, "GET", workspacePath+"/members/", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var members []models.WorkspaceMemberData
+		err = json.Unmarshal(resp.Body.Bytes(), &members)
+		assert.Nil(t, err, resp.Body.String())
+		assert.ElementsMatch(t, []int{ownerData.Id, memberData.Id}, test_utils.Map(members, func(m models.WorkspaceMemberData) int { return m.UserId }))
+
+		// regular members can't manage the workspace
+		resp = doRequest(memberCred, "POST", workspacePath+"/invitations/", `{"email": "new@test.com", "role": "member"}`)
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+		resp = doRequest(memberCred, "DELETE", fmt.Sprintf("%s/members/%d", workspacePath, ownerData.Id), "")
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+		resp = doRequest(ownerCred, "DELETE", fmt.Sprintf("%s/members/%d", workspacePath, ownerData.Id), "")
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+		resp = doRequest(ownerCred, "POST", workspacePath+"/invitations/", `{"email": "member@test.com", "role": "admin"}`)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+
+		// member leaves
+		resp = doRequest(memberCred, "DELETE", fmt.Sprintf("%s/members/%d", workspacePath, memberData.Id), "")
+		assert.Equal(t, 204, resp.Code, resp.Body.String())
+		resp = doRequest(memberCred, "GET", workspacePath+"/members/", "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+}
+
+func TestWorkspacesIsolation(t *testing.T) {
+	r := routes.SetupDefaultRouter()
+
+	conn := db.ConnectDB()
+
+	tp := services.NewJwtTokenProvider()
+	userRepo := repos.NewUsersRepo(conn)
+
+	tasksRepo := repos.NewTasksRepo(conn)
+	workspacesRepo := repos.NewWorkspacesRepo(conn)
+	tasksService := test_utils.NewTasksService(conn, tasksRepo, t.TempDir())
+	commentsService := services.NewCommentsService(repos.NewCommentsRepo(conn), test_utils.NewAuthorizer(conn, tasksRepo))
+
+	jwtAuth := middlewares.NewJwtHeaderAuthenticator(tp, userRepo)
+	workspaceResolver := middlewares.NewWorkspaceResolver(workspacesRepo, jwtAuth.AuthCtxKey)
+
+	utils.RegisterValidators()
+	routes.RegisterTasksRoutes(r, jwtAuth, workspaceResolver, tasksService)
+	routes.RegisterCommentsRoutes(r, jwtAuth, workspaceResolver, commentsService)
+
+	userACred := models.UserRegister{Email: "a@test.com", Password: "whatever"}
+	userAData, _ := test_utils.CreateUserWithTasks(userACred, []models.TaskData{}, userRepo, tasksRepo)
+	userBCred := models.UserRegister{Email: "b@test.com", Password: "whatever"}
+	userBData, tasksB := test_utils.CreateUserWithTasks(userBCred, []models.TaskData{{Name: "Secret", Status: "To do"}}, userRepo, tasksRepo)
+	workspaceA := test_utils.GetPersonalWorkspace(conn, userAData.Id)
+	workspaceB := test_utils.GetPersonalWorkspace(conn, userBData.Id)
+	defer utils.TruncateTables(conn, []string{"acl_entries", "tasks", "users"})
+
+	doRequest := func(userCred models.UserRegister, workspaceId *int, method string, path string, body string) *httptest.ResponseRecorder {
+		token, _ := tp.Provide(userCred.Email)
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		if workspaceId != nil {
+			req.Header.Set(workspaceResolver.WorkspaceHeader, fmt.Sprint(*workspaceId))
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	listTasks := func(userCred models.UserRegister, workspaceId *int) []models.TaskListItem {
+		resp := doRequest(userCred, workspaceId, "GET", "/tasks/", "")
+		assert.Equal(t, 200, resp.Code, resp.Body.String())
+		var page models.CursorPage[models.TaskListItem]
+		err := json.Unmarshal(resp.Body.Bytes(), &page)
+		assert.Nil(t, err, resp.Body.String())
+		return page.Items
+	}
+
+	t.Run("Bad request on invalid workspace header", func(t *testing.T) {
+		token, _ := tp.Provide(userACred.Email)
+		req, _ := http.NewRequest("GET", "/tasks/", nil)
+		req.Header.Set(jwtAuth.AuthHeader, fmt.Sprintf("%s %s", jwtAuth.AuthHeaderPrefix, token))
+		req.Header.Set(workspaceResolver.WorkspaceHeader, "abc")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		assert.Equal(t, 400, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Forbidden to select foreign workspace", func(t *testing.T) {
+		resp := doRequest(userACred, &workspaceB.Id, "GET", "/tasks/", "")
+		assert.Equal(t, 403, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Shares don't leak across workspaces", func(t *testing.T) {
+		defer utils.TruncateTables(conn, []string{"acl_entries"})
+
+		// a stray share of B's task with A must not give A access from A's workspace
+		_, err := conn.Exec(
+			context.Background(),
+			"INSERT INTO acl_entries (user_id, task_id, role) VALUES ($1, $2, 'owner')",
+			userAData.Id, tasksB[0].Id,
+		)
+		assert.Nil(t, err)
+
+		assert.Equal(t, 0, len(listTasks(userACred, nil)))
+		assert.Equal(t, 0, len(listTasks(userACred, &workspaceA.Id)))
+
+		taskPath := fmt.Sprintf("/tasks/%d", tasksB[0].Id)
+		resp := doRequest(userACred, nil, "PATCH", taskPath, `{"status": "Done"}`)
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+		resp = doRequest(userACred, nil, "DELETE", taskPath, "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+		resp = doRequest(userACred, nil, "GET", taskPath+"/comments/", "")
+		assert.Equal(t, 404, resp.Code, resp.Body.String())
+	})
+
+	t.Run("Member of many workspaces only sees the selected one", func(t *testing.T) {
+		// A joins B's workspace
+		invitation, err := workspacesRepo.CreateInvitation(context.Background(), workspaceB.Id, userACred.Email, models.WorkspaceRoleMember, userBData.Id)
+		assert.Nil(t, err)
+		err = workspacesRepo.AcceptInvitation(context.Background(), invitation, userAData.Id)
+		assert.Nil(t, err)
+
+		rapid.Check(t, func(rt *rapid.T) {
+			defer conn.Exec(context.Background(), "DELETE FROM tasks WHERE user_id = $1", userAData.Id)
+
+			expected := map[int][]int{workspaceA.Id: {}, workspaceB.Id: {}}
+			n := rapid.IntRange(0, 10).Draw(rt, "n")
+			for i := 0; i < n; i++ {
+				task := genTask(rt, i)
+				workspaceId := rapid.SampledFrom([]int{workspaceA.Id, workspaceB.Id}).Draw(rt, fmt.Sprintf("workspace%d", i))
+				created, err := tasksRepo.CreateWithStatus(context.Background(), workspaceId, task.Name, task.DueDate, task.Status, userAData.Id)
+				if err != nil {
+					rt.Fatal(err)
+				}
+				expected[workspaceId] = append(expected[workspaceId], created.Id)
+			}
+
+			for workspaceId, taskIds := range expected {
+				tasks := listTasks(userACred, &workspaceId)
+				for _, task := range tasks {
+					assert.Equal(rt, workspaceId, task.WorkspaceId)
+				}
+				assert.ElementsMatch(rt, taskIds, test_utils.Map(tasks, func(t models.TaskListItem) int { return t.Id }))
+			}
+		})
+	})
+}
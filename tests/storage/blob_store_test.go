@@ -0,0 +1,89 @@
+package storage_test
+
+import (
+	"api-server/domain/storage"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is a minimal in-memory stand-in for an S3 compatible service
+// supporting path-style PUT, GET and DELETE of objects.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") ||
+		r.Header.Get("X-Amz-Date") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func testBlobStore(t *testing.T, store storage.BlobStore) {
+	ctx := context.Background()
+	content := "attachment content"
+
+	err := store.Put(ctx, "tasks/1/blob", strings.NewReader(content), int64(len(content)), "text/plain")
+	assert.NoError(t, err)
+
+	reader, err := store.Get(ctx, "tasks/1/blob")
+	assert.NoError(t, err)
+	stored, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, content, string(stored))
+
+	assert.NoError(t, store.Delete(ctx, "tasks/1/blob"))
+	_, err = store.Get(ctx, "tasks/1/blob")
+	assert.Equal(t, storage.ErrBlobNotFound, err)
+
+	// deleting missing blob is not an error
+	assert.NoError(t, store.Delete(ctx, "tasks/1/blob"))
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	store := storage.NewLocalBlobStore(t.TempDir())
+	testBlobStore(t, store)
+
+	err := store.Put(context.Background(), "../escape", strings.NewReader("x"), 1, "text/plain")
+	assert.Error(t, err)
+}
+
+func TestS3BlobStore(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := storage.NewS3BlobStore(server.URL, "attachments", "us-east-1", "access", "secret")
+	testBlobStore(t, store)
+
+	store.AccessKey = "wrong"
+	err := store.Put(context.Background(), "tasks/1/blob", strings.NewReader("x"), 1, "text/plain")
+	assert.Error(t, err)
+}
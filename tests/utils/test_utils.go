@@ -3,7 +3,12 @@ package test_utils
 import (
 	"api-server/domain/models"
 	"api-server/domain/repos"
+	"api-server/domain/services"
+	"api-server/domain/storage"
+	"api-server/utils"
 	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func Map[T, V any](ts []T, fn func(T) V) []V {
@@ -26,8 +31,9 @@ func CreateUserWithTasks(
 ) (models.UserData, []models.TaskData) {
 	createdTasks := make([]models.TaskData, 0, len(tasksData))
 	user, _ := userRepo.Create(context.Background(), userCred.Email, userCred.Password)
+	workspace := GetPersonalWorkspace(userRepo.Conn, user.Id)
 	for _, t := range tasksData {
-		createdTask, err := tasksRepo.CreateWithStatus(context.Background(), t.Name, t.DueDate, t.Status, user.Id)
+		createdTask, err := tasksRepo.CreateWithStatus(context.Background(), workspace.Id, t.Name, t.DueDate, t.Status, user.Id)
 		if err != nil {
 			panic(err)
 		}
@@ -35,3 +41,29 @@ func CreateUserWithTasks(
 	}
 	return user, createdTasks
 }
+
+func GetPersonalWorkspace(conn *pgxpool.Pool, userId int) models.UserWorkspace {
+	workspace, err := repos.NewWorkspacesRepo(conn).GetPersonal(context.Background(), userId)
+	if err != nil {
+		panic(err)
+	}
+	return workspace
+}
+
+func NewAuthorizer(conn *pgxpool.Pool, tasksRepo *repos.TasksRepo) *services.Authorizer {
+	return services.NewAuthorizer(tasksRepo, repos.NewProjectsRepo(conn), repos.NewACLRepo(conn), repos.NewAssigneesRepo(conn))
+}
+
+func NewTasksService(conn *pgxpool.Pool, tasksRepo *repos.TasksRepo, blobDir string) *services.TasksService {
+	auth := NewAuthorizer(conn, tasksRepo)
+	attachmentsRepo := repos.NewAttachmentsRepo(conn)
+	attachmentsService := services.NewAttachmentsService(attachmentsRepo, auth, storage.NewLocalBlobStore(blobDir))
+	workflowsService := services.NewWorkflowsService(repos.NewWorkflowsRepo(conn), auth)
+	customFieldsService := services.NewCustomFieldsService(repos.NewCustomFieldsRepo(conn), auth)
+	undoService := services.NewUndoService(repos.NewUndoRepo(conn), auth, workflowsService)
+	return services.NewTasksService(tasksRepo, auth, attachmentsService, workflowsService, customFieldsService, NewCursorCodec(), undoService)
+}
+
+func NewCursorCodec() *utils.CursorCodec {
+	return utils.NewCursorCodec("test-cursor-secret")
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("cursor is malformed or was tampered with")
+
+// CursorCodec turns pagination state into opaque cursors signed with HMAC
+// so clients can't forge positions they weren't handed.
+type CursorCodec struct {
+	Secret []byte
+}
+
+func NewCursorCodec(secret string) *CursorCodec {
+	return &CursorCodec{Secret: []byte(secret)}
+}
+
+func (cc *CursorCodec) sign(payload string) string {
+	mac := hmac.New(sha256.New, cc.Secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (cc *CursorCodec) Encode(state any) (string, error) {
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(encoded)
+	return payload + "." + cc.sign(payload), nil
+}
+
+func (cc *CursorCodec) Decode(cursor string, state any) error {
+	payload, signature, ok := strings.Cut(cursor, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(cc.sign(payload))) {
+		return ErrInvalidCursor
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(decoded, state); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"slices"
+	"time"
+	_ "time/tzdata" // time zones of users must resolve wherever the server runs
+)
+
+// Relative days accepted wherever a day is, resolved in the time zone of the user
+var RelativeDays = []string{
+	"today",
+	"tomorrow",
+	"yesterday",
+	"this_week",
+	"next_week",
+	"next_7_days",
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// DayRange resolves a day in DayDateFmt or one of RelativeDays into the
+// half-open interval [from, to) in the location of now. Weeks start on Monday.
+func DayRange(day string, now time.Time) (from time.Time, to time.Time, ok bool) {
+	today := startOfDay(now)
+	weekStart := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+
+	switch day {
+	case "today":
+		return today, today.AddDate(0, 0, 1), true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), today.AddDate(0, 0, 2), true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, true
+	case "this_week":
+		return weekStart, weekStart.AddDate(0, 0, 7), true
+	case "next_week":
+		return weekStart.AddDate(0, 0, 7), weekStart.AddDate(0, 0, 14), true
+	case "next_7_days":
+		return today, today.AddDate(0, 0, 7), true
+	}
+
+	date, err := time.ParseInLocation(DayDateFmt, day, now.Location())
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return date, date.AddDate(0, 0, 1), true
+}
+
+func isDay(day string) bool {
+	if slices.Contains(RelativeDays, day) {
+		return true
+	}
+	_, err := time.Parse(DayDateFmt, day)
+	return err == nil
+}
@@ -0,0 +1,9 @@
+package utils
+
+import "strings"
+
+// NormalizeEmail returns the form emails are compared and stored in by
+// invitations, which ignores case and surrounding whitespace.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"os"
+	"strconv"
 )
 
 func MustGetenv(key string) string {
@@ -12,3 +13,23 @@ func MustGetenv(key string) string {
 	}
 	return v
 }
+
+func GetenvOrDefault(key string, defaultValue string) string {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultValue
+	}
+	return v
+}
+
+func GetenvIntOrDefault(key string, defaultValue int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		panic(fmt.Sprintf("%s env variable must be an integer", key))
+	}
+	return n
+}
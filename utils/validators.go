@@ -1,8 +1,10 @@
 package utils
 
 import (
+	"fmt"
 	"regexp"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin/binding"
@@ -12,11 +14,63 @@ import (
 // YYYY-MM-dd
 var DayDateFmt = "2006-01-02"
 
-var ValidTaskStatuses = []string{
-	"Won't do",
-	"To do",
-	"In progress",
-	"Done",
+var ValidStatusCategories = []string{
+	"todo",
+	"active",
+	"done",
+	"cancelled",
+}
+
+// Ordered from the least to the most privileged
+var ValidShareRoles = []string{
+	"viewer",
+	"editor",
+	"owner",
+}
+
+// Ordered from the least to the most privileged
+var ValidWorkspaceRoles = []string{
+	"member",
+	"admin",
+	"owner",
+}
+
+var ValidCustomFieldTypes = []string{
+	"text",
+	"number",
+	"date",
+	"select",
+	"multi_select",
+	"checkbox",
+}
+
+// Ordered from the lowest to the highest priority
+var ValidTaskPriorities = []string{
+	"low",
+	"medium",
+	"high",
+	"urgent",
+}
+
+// Task attributes saved views group by, besides custom fields
+var ValidGroupBy = []string{
+	"status",
+	"priority",
+}
+
+var fieldGroupByRegex = regexp.MustCompile(`^fields\.[0-9]+$`)
+
+// ValidationError reports input which can only be validated past binding,
+// e.g. against data stored in the database. Its message has the same shape
+// as binding errors of the validator.
+type ValidationError struct {
+	Namespace string
+	Field     string
+	Tag       string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("Key: '%s' Error:Field validation for '%s' failed on the '%s' tag", e.Namespace, e.Field, e.Tag)
 }
 
 var strongPasswordValidator validator.Func = func(fl validator.FieldLevel) bool {
@@ -39,10 +93,42 @@ var strongPasswordValidator validator.Func = func(fl validator.FieldLevel) bool
 	return false
 }
 
-var taskStatusValidator validator.Func = func(fl validator.FieldLevel) bool {
-	status, ok := fl.Field().Interface().(string)
+var statusCategoryValidator validator.Func = func(fl validator.FieldLevel) bool {
+	category, ok := fl.Field().Interface().(string)
 	if ok {
-		return slices.Contains(ValidTaskStatuses, status)
+		return slices.Contains(ValidStatusCategories, category)
+	}
+	return false
+}
+
+var shareRoleValidator validator.Func = func(fl validator.FieldLevel) bool {
+	role, ok := fl.Field().Interface().(string)
+	if ok {
+		return slices.Contains(ValidShareRoles, role)
+	}
+	return false
+}
+
+var customFieldTypeValidator validator.Func = func(fl validator.FieldLevel) bool {
+	fieldType, ok := fl.Field().Interface().(string)
+	if ok {
+		return slices.Contains(ValidCustomFieldTypes, fieldType)
+	}
+	return false
+}
+
+var taskPriorityValidator validator.Func = func(fl validator.FieldLevel) bool {
+	priority, ok := fl.Field().Interface().(string)
+	if ok {
+		return slices.Contains(ValidTaskPriorities, priority)
+	}
+	return false
+}
+
+var groupByValidator validator.Func = func(fl validator.FieldLevel) bool {
+	groupBy, ok := fl.Field().Interface().(string)
+	if ok {
+		return slices.Contains(ValidGroupBy, groupBy) || fieldGroupByRegex.MatchString(groupBy)
 	}
 	return false
 }
@@ -56,10 +142,34 @@ var dayDateFormatValidator validator.Func = func(fl validator.FieldLevel) bool {
 	return false
 }
 
+// notBlankValidator rejects strings of whitespace only, which required
+// lets through.
+var notBlankValidator validator.Func = func(fl validator.FieldLevel) bool {
+	value, ok := fl.Field().Interface().(string)
+	if ok {
+		return strings.TrimSpace(value) != ""
+	}
+	return false
+}
+
+var dayValidator validator.Func = func(fl validator.FieldLevel) bool {
+	day, ok := fl.Field().Interface().(string)
+	if ok {
+		return isDay(day)
+	}
+	return false
+}
+
 func RegisterValidators() {
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("strongpass", strongPasswordValidator)
-		v.RegisterValidation("taskStatus", taskStatusValidator)
+		v.RegisterValidation("statusCategory", statusCategoryValidator)
 		v.RegisterValidation("dayFormat", dayDateFormatValidator)
+		v.RegisterValidation("day", dayValidator)
+		v.RegisterValidation("shareRole", shareRoleValidator)
+		v.RegisterValidation("customFieldType", customFieldTypeValidator)
+		v.RegisterValidation("taskPriority", taskPriorityValidator)
+		v.RegisterValidation("groupBy", groupByValidator)
+		v.RegisterValidation("notBlank", notBlankValidator)
 	}
 }